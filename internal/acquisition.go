@@ -2,55 +2,248 @@ package internal
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Source produces IMU samples for DataAcquisition.
+type Source interface {
+	// Start begins producing samples on the returned channel.
+	// The channel is closed once the source is exhausted or stopped.
+	Start() <-chan IMUData
+	// Stop halts the source and releases its resources.
+	Stop()
+}
+
+// Trajectory returns the sample an IMU reports at time t, for driving a SimulatedSource along a
+// known path.
+type Trajectory func(imuID int, t time.Time) IMUData
+
+// SimulatedSource emits samples for a fixed number of IMUs at a fixed period, following a
+// Trajectory, or at rest by default.
+type SimulatedSource struct {
+	imuCount   int
+	period     time.Duration
+	trajectory Trajectory // nil for zero motion
+	stopChan   chan struct{}
+	stopWg     sync.WaitGroup
+}
+
+// NewSimulatedSource creates a SimulatedSource emitting one zero-motion frame every period.
+func NewSimulatedSource(imuCount int, period time.Duration) *SimulatedSource {
+	return NewSimulatedSourceWithTrajectory(imuCount, period, nil)
+}
+
+// NewSimulatedSourceWithTrajectory creates a SimulatedSource emitting one frame every period,
+// with each sample taken from traj at the frame's time. A nil traj emits zero motion.
+func NewSimulatedSourceWithTrajectory(imuCount int, period time.Duration, traj Trajectory) *SimulatedSource {
+	return &SimulatedSource{
+		imuCount:   imuCount,
+		period:     period,
+		trajectory: traj,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start begins emitting frames, stamping every sample in a frame with the same device timestamp.
+// The IMU ID and device timestamp of trajectory samples are set by the source.
+func (s *SimulatedSource) Start() <-chan IMUData {
+	out := make(chan IMUData, s.imuCount)
+	s.stopWg.Add(1)
+	go func() {
+		defer s.stopWg.Done()
+		defer close(out)
+		ticker := time.NewTicker(s.period)
+		defer ticker.Stop()
+		for {
+			select {
+			case ts := <-ticker.C:
+				for imuID := 0; imuID < s.imuCount; imuID++ {
+					var data IMUData
+					if s.trajectory != nil {
+						data = s.trajectory(imuID, ts)
+					}
+					data.IMUID = imuID
+					data.DeviceTimestamp = ts
+					select {
+					case out <- data:
+					case <-s.stopChan:
+						return
+					}
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Stop halts the simulation.
+func (s *SimulatedSource) Stop() {
+	close(s.stopChan)
+	s.stopWg.Wait()
+}
+
+// BackpressurePolicy selects what DataAcquisition does with a sample when its buffer is full.
+type BackpressurePolicy int
+
+const (
+	BackpressureDrop  BackpressurePolicy = iota // discard the sample and count it, see Dropped
+	BackpressureBlock                           // stop reading the source until there is room
+)
+
 // DataAcquisition handles the collection of data from multiple IMUs.
 type DataAcquisition struct {
-	sync     *Synchronizer
-	imuCount int
-	stopChan chan struct{}
-	stopWg   sync.WaitGroup
+	sync        *Synchronizer
+	source      Source
+	imuCount    int
+	angularUnit AngularUnit // unit the source reports angular velocity in
+	clock       Clock       // stamps receive times
+	stopChan    chan struct{}
+	stopWg      sync.WaitGroup
 	sync.Mutex
+
+	capacity int                // bound on buffered samples, 0 for unbounded; see SetBackpressure
+	policy   BackpressurePolicy // what to do with samples beyond capacity
+	dropped  uint64             // samples discarded under BackpressureDrop, updated atomically
 }
 
-// NewDataAcquisition initializes a new DataAcquisition instance.
+// NewDataAcquisition initializes a new DataAcquisition instance backed by a 1000Hz SimulatedSource.
 func NewDataAcquisition(imuCount int, sync *Synchronizer) *DataAcquisition {
+	return NewDataAcquisitionFromSource(imuCount, NewSimulatedSource(imuCount, 1*time.Millisecond), sync)
+}
+
+// NewDataAcquisitionWithTrajectory initializes a new DataAcquisition backed by a 1000Hz
+// SimulatedSource following traj, so tests can drive the pipeline along a known path.
+func NewDataAcquisitionWithTrajectory(imuCount int, sync *Synchronizer, traj Trajectory) *DataAcquisition {
+	return NewDataAcquisitionFromSource(imuCount, NewSimulatedSourceWithTrajectory(imuCount, 1*time.Millisecond, traj), sync)
+}
+
+// NewDataAcquisitionFromSource initializes a new DataAcquisition reading from the given source.
+func NewDataAcquisitionFromSource(imuCount int, source Source, sync *Synchronizer) *DataAcquisition {
 	return &DataAcquisition{
 		sync:     sync,
+		source:   source,
 		imuCount: imuCount,
+		clock:    RealClock{},
 		stopChan: make(chan struct{}),
 	}
 }
 
-// Start simulates the collection of data from the IMUs and sends it to the Synchronizer.
+// SetClock sets the clock samples are stamped with on receipt. It must be called before Start.
+func (da *DataAcquisition) SetClock(clock Clock) {
+	da.clock = clock
+}
+
+// SetAngularUnit declares the unit the source reports angular velocity in.
+// Samples are converted to rad/s on ingest. It must be called before Start.
+func (da *DataAcquisition) SetAngularUnit(unit AngularUnit) {
+	da.angularUnit = unit
+}
+
+// SetBackpressure bounds the samples waiting to be fused. Samples pass through a queue of
+// capacity samples to the Synchronizer, which is fed only while it holds fewer than capacity
+// samples, so a stalled processing loop fills the queue instead of growing the Synchronizer
+// without bound. Once the queue is full, policy either drops new samples, counting them in
+// Dropped, or blocks reading from the source. The capacity is raised to at least one sample
+// per IMU, so that a whole frame fits. A capacity <= 0 feeds every sample to the Synchronizer
+// directly, the default. It must be called before Start.
+func (da *DataAcquisition) SetBackpressure(capacity int, policy BackpressurePolicy) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	if capacity > 0 && capacity < da.imuCount {
+		capacity = da.imuCount
+	}
+	da.capacity = capacity
+	da.policy = policy
+}
+
+// Dropped returns the number of samples discarded under BackpressureDrop.
+func (da *DataAcquisition) Dropped() uint64 {
+	return atomic.LoadUint64(&da.dropped)
+}
+
+// Start begins reading from the source, stamping each sample with its receive time and
+// converting its angular velocity to rad/s before sending it to the Synchronizer.
 func (da *DataAcquisition) Start() {
+	samples := da.source.Start()
+	if da.capacity > 0 {
+		da.startBounded(samples)
+		return
+	}
 	da.stopWg.Add(1)
 	go func() {
 		defer da.stopWg.Done()
-		ticker := time.NewTicker(1 * time.Millisecond) // Simulate 1000Hz frames.
-		defer ticker.Stop()
 		for {
 			select {
-			case ts := <-ticker.C:
-				for imuID := 0; imuID < da.imuCount; imuID++ {
-					data := IMUData{
-						IMUID:           imuID,
-						Timestamp:       ts,
-						Acceleration:    [3]float64{},
-						AngularVelocity: [3]float64{},
+			case data, ok := <-samples:
+				if !ok {
+					return
+				}
+				data.Timestamp = da.clock.Now()
+				data.AngularVelocity = da.angularUnit.ToRadians(data.AngularVelocity)
+				da.sync.AddData(data)
+			case <-da.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// startBounded reads samples into a queue of da.capacity samples and feeds them to the
+// Synchronizer while it holds fewer than da.capacity samples.
+func (da *DataAcquisition) startBounded(samples <-chan IMUData) {
+	queue := make(chan IMUData, da.capacity)
+	da.stopWg.Add(2)
+	go func() {
+		defer da.stopWg.Done()
+		defer close(queue)
+		for {
+			select {
+			case data, ok := <-samples:
+				if !ok {
+					return
+				}
+				data.Timestamp = da.clock.Now()
+				data.AngularVelocity = da.angularUnit.ToRadians(data.AngularVelocity)
+				if da.policy == BackpressureBlock {
+					select {
+					case queue <- data:
+					case <-da.stopChan:
+						return
 					}
-					da.sync.AddData(data)
+					continue
+				}
+				select {
+				case queue <- data:
+				default:
+					atomic.AddUint64(&da.dropped, 1)
 				}
 			case <-da.stopChan:
 				return
 			}
 		}
 	}()
+	go func() {
+		defer da.stopWg.Done()
+		for data := range queue {
+			for da.sync.Pending() >= da.capacity {
+				select {
+				case <-da.stopChan:
+					return
+				case <-time.After(1 * time.Millisecond):
+				}
+			}
+			da.sync.AddData(data)
+		}
+	}()
 }
 
 // Stop signals the data acquisition goroutines to stop.
 func (da *DataAcquisition) Stop() {
 	close(da.stopChan)
+	da.source.Stop()
 	da.stopWg.Wait()
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -28,3 +29,228 @@ func TestDataAcquisitionProducesAlignedFrames(t *testing.T) {
 		}
 	}
 }
+
+// chanSource is a Source backed by a caller-supplied channel.
+type chanSource struct {
+	samples chan IMUData
+}
+
+func (s *chanSource) Start() <-chan IMUData { return s.samples }
+func (s *chanSource) Stop()                 {}
+
+func TestDataAcquisitionStampsReceiveTime(t *testing.T) {
+	sync := NewSynchronizer()
+	src := &chanSource{samples: make(chan IMUData, 2)}
+	acq := NewDataAcquisitionFromSource(2, src, sync)
+
+	device := time.Unix(0, 42)
+	src.samples <- IMUData{IMUID: 0, DeviceTimestamp: device}
+	src.samples <- IMUData{IMUID: 1, DeviceTimestamp: device}
+	close(src.samples)
+
+	before := time.Now()
+	acq.Start()
+	defer acq.Stop()
+
+	deadline := time.After(100 * time.Millisecond)
+	for {
+		if frames := sync.GetAlignedData(2); len(frames) > 0 {
+			for _, data := range frames[0] {
+				if !data.DeviceTimestamp.Equal(device) {
+					t.Errorf("Expected device timestamp %v, got %v", device, data.DeviceTimestamp)
+				}
+				if data.Timestamp.Before(before) {
+					t.Errorf("Expected receive timestamp after %v, got %v", before, data.Timestamp)
+				}
+			}
+			return
+		}
+
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for aligned frame")
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+}
+
+// integrateHeading acquires samples from src and integrates the yaw rate of IMU 0 over
+// consecutive frames.
+func integrateHeading(t *testing.T, src *chanSource, unit AngularUnit, frames int) float64 {
+	sync := NewSynchronizer()
+	acq := NewDataAcquisitionFromSource(1, src, sync)
+	acq.SetAngularUnit(unit)
+	acq.Start()
+	defer acq.Stop()
+
+	var aligned [][]IMUData
+	deadline := time.After(time.Second)
+	for len(aligned) < frames {
+		aligned = append(aligned, sync.GetAlignedData(1)...)
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for frames, got %d of %d", len(aligned), frames)
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+
+	var heading float64
+	for i := 1; i < len(aligned); i++ {
+		dt := aligned[i][0].SampleTime().Sub(aligned[i-1][0].SampleTime()).Seconds()
+		heading += aligned[i][0].AngularVelocity[2] * dt
+	}
+	return heading
+}
+
+func TestDataAcquisitionConvertsDegreesToRadians(t *testing.T) {
+	const frames = 101
+	const period = 10 * time.Millisecond
+	const rateDeg = 90.0
+
+	feed := func(rate float64) *chanSource {
+		src := &chanSource{samples: make(chan IMUData, frames)}
+		for i := 0; i < frames; i++ {
+			src.samples <- IMUData{
+				DeviceTimestamp: time.Unix(0, int64(i)*int64(period)),
+				AngularVelocity: [3]float64{0, 0, rate},
+			}
+		}
+		close(src.samples)
+		return src
+	}
+
+	degrees := integrateHeading(t, feed(rateDeg), DegreesPerSecond, frames)
+	radians := integrateHeading(t, feed(rateDeg*math.Pi/180), RadiansPerSecond, frames)
+
+	if math.Abs(degrees-radians) > 1e-12 {
+		t.Errorf("Expected deg/s heading %f to match rad/s heading %f", degrees, radians)
+	}
+	if want := math.Pi / 2; math.Abs(radians-want) > 1e-9 {
+		t.Errorf("Expected heading %f after one second at 90 deg/s, got %f", want, radians)
+	}
+}
+
+func TestDataAcquisitionBackpressure(t *testing.T) {
+	const samples = 50
+	const capacity = 10
+	feed := func() *chanSource {
+		src := &chanSource{samples: make(chan IMUData, samples)}
+		for i := 0; i < samples; i++ {
+			src.samples <- IMUData{DeviceTimestamp: time.Unix(0, int64(i+1))}
+		}
+		close(src.samples)
+		return src
+	}
+	// settle waits for acquisition to stop making progress while nothing consumes frames.
+	settle := func(sync *Synchronizer) {
+		for last := -1; sync.Pending() != last; {
+			last = sync.Pending()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		sync := NewSynchronizer()
+		acq := NewDataAcquisitionFromSource(1, feed(), sync)
+		acq.SetBackpressure(capacity, BackpressureDrop)
+		acq.Start()
+		defer acq.Stop()
+		settle(sync)
+
+		// The stalled consumer holds capacity samples and the queue at most capacity more, so
+		// depending on how far the queue drained while the source was read, the rest are dropped.
+		if n := sync.Pending(); n != capacity {
+			t.Errorf("Expected %d samples in the synchronizer, got %d", capacity, n)
+		}
+		if d := acq.Dropped(); d < samples-2*capacity || d > samples-capacity {
+			t.Errorf("Expected between %d and %d dropped samples, got %d", samples-2*capacity, samples-capacity, d)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		sync := NewSynchronizer()
+		src := feed()
+		acq := NewDataAcquisitionFromSource(1, src, sync)
+		acq.SetBackpressure(capacity, BackpressureBlock)
+		acq.Start()
+		defer acq.Stop()
+		settle(sync)
+
+		if n := sync.Pending(); n != capacity {
+			t.Errorf("Expected %d samples in the synchronizer, got %d", capacity, n)
+		}
+		if len(src.samples) == 0 {
+			t.Error("Expected the blocked source to keep unread samples")
+		}
+
+		// Once the consumer catches up, every sample arrives and none are dropped.
+		var frames [][]IMUData
+		deadline := time.After(time.Second)
+		for len(frames) < samples {
+			frames = append(frames, sync.GetAlignedData(1)...)
+			select {
+			case <-deadline:
+				t.Fatalf("timed out waiting for frames, got %d of %d", len(frames), samples)
+			case <-time.After(1 * time.Millisecond):
+			}
+		}
+		if d := acq.Dropped(); d != 0 {
+			t.Errorf("Expected no dropped samples, got %d", d)
+		}
+	})
+}
+
+func TestDataAcquisitionWithTrajectory(t *testing.T) {
+	const imuCount = 2
+	const jerk = 10.0
+	const frames = 200
+	// Acceleration along X ramping linearly from origin, so the position grows cubically.
+	origin := time.Now()
+	traj := func(imuID int, ts time.Time) IMUData {
+		return IMUData{Acceleration: [3]float64{jerk * ts.Sub(origin).Seconds(), 0, 0}}
+	}
+	sync := NewSynchronizer()
+	acq := NewDataAcquisitionWithTrajectory(imuCount, sync, traj)
+	acq.Start()
+
+	var aligned [][]IMUData
+	deadline := time.After(5 * time.Second)
+	for len(aligned) < frames {
+		aligned = append(aligned, sync.GetAlignedData(imuCount)...)
+		select {
+		case <-deadline:
+			acq.Stop()
+			t.Fatalf("timed out waiting for frames, got %d of %d", len(aligned), frames)
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+	acq.Stop()
+
+	for _, frame := range aligned {
+		want := jerk * frame[0].DeviceTimestamp.Sub(origin).Seconds()
+		if got := frame[0].Acceleration[0]; got != want {
+			t.Fatalf("Expected acceleration %f from the trajectory, got %f", want, got)
+		}
+	}
+
+	sys, err := NewIMUFusionSystem(imuCount)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.SetStationarityDetection(0, 0, 0)
+	sys.SetRefinementRadius(0) // refinement towards past positions would lag the motion
+	trajectory := sys.FuseTrajectory(aligned)
+	if len(trajectory) != len(aligned) {
+		t.Fatalf("Expected %d positions, got %d", len(aligned), len(trajectory))
+	}
+	// Starting at rest with acceleration a0, x(t) = a0 t^2 / 2 + jerk t^3 / 6.
+	start := aligned[0][0].SampleTime()
+	a0 := aligned[0][0].Acceleration[0]
+	for _, i := range []int{len(aligned) / 2, len(aligned) - 1} {
+		elapsed := aligned[i][0].SampleTime().Sub(start).Seconds()
+		want := 0.5*a0*elapsed*elapsed + jerk*elapsed*elapsed*elapsed/6
+		if got := trajectory[i].X; math.Abs(got-want) > 0.02*want+1e-4 {
+			t.Errorf("after %f s: Expected position %f, got %f", elapsed, want, got)
+		}
+	}
+}
@@ -1,12 +1,30 @@
 package internal
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"math"
 )
 
+// CalibrationResult describes how well a calibration was taken.
+type CalibrationResult struct {
+	// Residual is the summed X and Y variance of the samples about their mean. Calibration
+	// assumes the device is at rest, so a high residual means it was moving or vibrating.
+	Residual float64
+	// NoiseLevel is the per-axis standard deviation implied by Residual, a starting point
+	// for the noise level used by uncertainty estimation.
+	NoiseLevel float64
+}
+
 // Calibrate performs calibration on the IMU using provided raw data.
-// It adjusts the offset and scale based on the average of the measurements.
-func (imu *IMU) Calibrate(rawData [][]float64) {
+// It adjusts the offset and scale based on the average of the measurements, and reports the
+// spread of the measurements so callers can reject a calibration taken while moving.
+// With no data the calibration is left unchanged.
+func (imu *IMU) Calibrate(rawData [][]float64) CalibrationResult {
+	if len(rawData) == 0 {
+		return CalibrationResult{}
+	}
 	var sumX, sumY float64
 	count := float64(len(rawData))
 
@@ -27,6 +45,20 @@ func (imu *IMU) Calibrate(rawData [][]float64) {
 	// Here we simply set scale factors to 1 for simplicity
 	imu.ScaleX = 1.0
 	imu.ScaleY = 1.0
+
+	// Variance of the centered samples
+	var residual float64
+	for _, data := range rawData {
+		dx := data[0] - avgX
+		dy := data[1] - avgY
+		residual += dx*dx + dy*dy
+	}
+	residual /= count
+
+	return CalibrationResult{
+		Residual:   residual,
+		NoiseLevel: math.Sqrt(residual / 2),
+	}
 }
 
 // ApplyCalibration applies the calibration parameters to raw IMU measurements.
@@ -36,9 +68,123 @@ func (imu *IMU) ApplyCalibration(rawX, rawY float64) (float64, float64) {
 	return calibratedX, calibratedY
 }
 
+// CalibrateGyroBias estimates the gyro bias as the mean of angular velocity samples in rad/s
+// recorded at rest, and reports their spread as for Calibrate. With no data the bias is left
+// unchanged.
+func (imu *IMU) CalibrateGyroBias(samples [][3]float64) CalibrationResult {
+	if len(samples) == 0 {
+		return CalibrationResult{}
+	}
+	count := float64(len(samples))
+	var mean [3]float64
+	for _, s := range samples {
+		for i := range mean {
+			mean[i] += s[i]
+		}
+	}
+	for i := range mean {
+		mean[i] /= count
+	}
+	imu.GyroBias = mean
+
+	var residual float64
+	for _, s := range samples {
+		for i := range mean {
+			d := s[i] - mean[i]
+			residual += d * d
+		}
+	}
+	residual /= count
+
+	return CalibrationResult{
+		Residual:   residual,
+		NoiseLevel: math.Sqrt(residual / 3),
+	}
+}
+
+// ApplyGyroCalibration removes the gyro bias from an angular velocity in rad/s.
+func (imu *IMU) ApplyGyroCalibration(rate [3]float64) [3]float64 {
+	for i := range rate {
+		rate[i] -= imu.GyroBias[i]
+	}
+	return rate
+}
+
 // CalculateError computes the calibration error based on expected and measured values.
 func CalculateError(expectedX, expectedY, measuredX, measuredY float64) float64 {
 	errorX := expectedX - measuredX
 	errorY := expectedY - measuredY
 	return math.Sqrt(errorX*errorX + errorY*errorY) // Euclidean distance
 }
+
+// calibrationFile is the JSON form written by SaveCalibration and read by LoadCalibration:
+//
+//	{"imus":[{"imu_id":0,"offset_x":0.02,"offset_y":-0.01,"scale_x":1,"scale_y":1,"gyro_bias":[0,0,0.001]}]}
+type calibrationFile struct {
+	IMUs []calibrationRecord `json:"imus"`
+}
+
+type calibrationRecord struct {
+	IMUID   int     `json:"imu_id"`
+	OffsetX float64 `json:"offset_x"`
+	OffsetY float64 `json:"offset_y"`
+	ScaleX  float64 `json:"scale_x"`
+	ScaleY  float64 `json:"scale_y"`
+
+	GyroBias [3]float64 `json:"gyro_bias"`
+}
+
+// SaveCalibration writes the offsets, scales, and gyro bias of every IMU to w as JSON.
+func (sys *IMUFusionSystem) SaveCalibration(w io.Writer) error {
+	sys.filterMu.Lock()
+	file := calibrationFile{IMUs: make([]calibrationRecord, len(sys.calib))}
+	for i, imu := range sys.calib {
+		file.IMUs[i] = calibrationRecord{IMUID: i, OffsetX: imu.OffsetX, OffsetY: imu.OffsetY, ScaleX: imu.ScaleX, ScaleY: imu.ScaleY, GyroBias: imu.GyroBias}
+	}
+	sys.filterMu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// LoadCalibration reads offsets, scales, and gyro biases written by SaveCalibration. The file must hold one
+// entry for each IMU of the system, with finite values and nonzero scales; otherwise an error is
+// returned and the calibration is left unchanged. It should be called before Start.
+func (sys *IMUFusionSystem) LoadCalibration(r io.Reader) error {
+	var file calibrationFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("decoding calibration: %w", err)
+	}
+	if len(file.IMUs) != sys.imuCount {
+		return fmt.Errorf("calibration has %d IMUs, system has %d", len(file.IMUs), sys.imuCount)
+	}
+	seen := make([]bool, sys.imuCount)
+	for _, rec := range file.IMUs {
+		if rec.IMUID < 0 || rec.IMUID >= sys.imuCount {
+			return fmt.Errorf("IMU ID %d out of range [0, %d)", rec.IMUID, sys.imuCount)
+		}
+		if seen[rec.IMUID] {
+			return fmt.Errorf("duplicate calibration for IMU %d", rec.IMUID)
+		}
+		seen[rec.IMUID] = true
+		for _, v := range []float64{rec.OffsetX, rec.OffsetY, rec.ScaleX, rec.ScaleY, rec.GyroBias[0], rec.GyroBias[1], rec.GyroBias[2]} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("IMU %d: calibration values must be finite", rec.IMUID)
+			}
+		}
+		if rec.ScaleX == 0 || rec.ScaleY == 0 {
+			return fmt.Errorf("IMU %d: calibration scales must be nonzero", rec.IMUID)
+		}
+	}
+
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	for _, rec := range file.IMUs {
+		imu := sys.calib[rec.IMUID]
+		imu.OffsetX, imu.OffsetY = rec.OffsetX, rec.OffsetY
+		imu.ScaleX, imu.ScaleY = rec.ScaleX, rec.ScaleY
+		imu.GyroBias = rec.GyroBias
+	}
+	return nil
+}
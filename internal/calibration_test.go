@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCalibrateResidual(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sample := func(n int, noise float64, moving bool) [][]float64 {
+		data := make([][]float64, n)
+		for i := range data {
+			x := 0.2 + noise*rng.NormFloat64()
+			y := -0.1 + noise*rng.NormFloat64()
+			if moving {
+				x += float64(i) / float64(n) // the device is pushed during calibration
+			}
+			data[i] = []float64{x, y}
+		}
+		return data
+	}
+
+	tests := []struct {
+		name         string
+		data         [][]float64
+		maxResidual  float64
+		minResidual  float64
+		wantNoiseStd float64
+	}{
+		{name: "Quiet", data: sample(2000, 0.01, false), maxResidual: 1e-3, wantNoiseStd: 0.01},
+		{name: "Noisy", data: sample(2000, 0.5, false), minResidual: 0.1, wantNoiseStd: 0.5},
+		{name: "Moving", data: sample(2000, 0.01, true), minResidual: 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imu := NewIMU()
+			r := imu.Calibrate(tt.data)
+			if tt.maxResidual > 0 && r.Residual > tt.maxResidual {
+				t.Errorf("Expected residual below %f, got %f", tt.maxResidual, r.Residual)
+			}
+			if r.Residual < tt.minResidual {
+				t.Errorf("Expected residual above %f, got %f", tt.minResidual, r.Residual)
+			}
+			if tt.wantNoiseStd > 0 && !floatsClose(r.NoiseLevel, tt.wantNoiseStd, 0.1*tt.wantNoiseStd) {
+				t.Errorf("Expected noise level near %f, got %f", tt.wantNoiseStd, r.NoiseLevel)
+			}
+		})
+	}
+}
+
+func TestCalibrateEmpty(t *testing.T) {
+	imu := NewIMU()
+	imu.OffsetX = 0.3
+	if r := imu.Calibrate(nil); r != (CalibrationResult{}) {
+		t.Errorf("Expected zero result for no data, got %+v", r)
+	}
+	if imu.OffsetX != 0.3 {
+		t.Errorf("Expected offset to be unchanged, got %f", imu.OffsetX)
+	}
+}
+
+func TestCalibrationSaveLoadRoundTrip(t *testing.T) {
+	original, err := NewIMUFusionSystem(3)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		samples := make([][3]float64, 100)
+		for k := range samples {
+			samples[k] = [3]float64{0.1 * float64(i+1), -0.05 * float64(i+1), 9.81}
+		}
+		if _, err := original.CalibrateIMU(i, samples); err != nil {
+			t.Fatalf("CalibrateIMU failed: %v", err)
+		}
+	}
+	original.calib[2].ScaleX = 1.25
+	original.calib[1].GyroBias = [3]float64{0.001, -0.002, 0.003}
+
+	var buf bytes.Buffer
+	if err := original.SaveCalibration(&buf); err != nil {
+		t.Fatalf("SaveCalibration failed: %v", err)
+	}
+	saved := buf.String()
+
+	restored, _ := NewIMUFusionSystem(3)
+	if err := restored.LoadCalibration(strings.NewReader(saved)); err != nil {
+		t.Fatalf("LoadCalibration failed: %v", err)
+	}
+	for i := range original.calib {
+		want, got := *original.calib[i], *restored.calib[i]
+		if got != want {
+			t.Errorf("IMU %d: Expected calibration %+v, got %+v", i, want, got)
+		}
+	}
+
+	mismatched, _ := NewIMUFusionSystem(2)
+	if err := mismatched.LoadCalibration(strings.NewReader(saved)); err == nil {
+		t.Error("Expected error loading a calibration for a different IMU count")
+	}
+	if *mismatched.calib[0] != *NewIMU() {
+		t.Errorf("Expected a rejected calibration to leave defaults, got %+v", *mismatched.calib[0])
+	}
+}
+
+func TestCalibrateGyroBias(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	bias := [3]float64{0.01, -0.02, 0.005}
+	samples := make([][3]float64, 5000)
+	for i := range samples {
+		for k := range bias {
+			samples[i][k] = bias[k] + 0.001*rng.NormFloat64()
+		}
+	}
+
+	imu := NewIMU()
+	r := imu.CalibrateGyroBias(samples)
+	for k := range bias {
+		if !floatsClose(imu.GyroBias[k], bias[k], 1e-4) {
+			t.Errorf("axis %d: Expected gyro bias %f, got %f", k, bias[k], imu.GyroBias[k])
+		}
+	}
+	if !floatsClose(r.NoiseLevel, 0.001, 1e-4) {
+		t.Errorf("Expected noise level 0.001, got %f", r.NoiseLevel)
+	}
+
+	corrected := imu.ApplyGyroCalibration([3]float64{bias[0], bias[1], bias[2] + 0.5})
+	want := [3]float64{0, 0, 0.5}
+	for k := range want {
+		if !floatsClose(corrected[k], want[k], 1e-4) {
+			t.Errorf("axis %d: Expected corrected rate %f, got %f", k, want[k], corrected[k])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+)
+
+// defaultNoiseLevel is the IMU noise level behind the filters and the default uncertainty model.
+const defaultNoiseLevel = 0.1
+
+// Logger receives the warnings of an IMUFusionSystem. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdoutLogger is the default Logger, writing to stdout.
+type stdoutLogger struct{}
+
+// Printf writes a formatted message to stdout.
+func (stdoutLogger) Printf(format string, v ...interface{}) {
+	fmt.Printf(format, v...)
+}
+
+// FusionConfig is the configuration an IMUFusionSystem is constructed with. Settings that may
+// change while running have setters on the system instead.
+type FusionConfig struct {
+	Noise    float64    // IMU noise level of the filters and the default uncertainty model
+	MinAlpha float64    // smallest expansion factor searched by the geometric fusion
+	MaxAlpha float64    // largest expansion factor searched by the geometric fusion
+	Mode     FusionMode // what is fused when the circles share no point up to MaxAlpha
+	Logger   Logger     // receives the system's warnings
+}
+
+// DefaultFusionConfig returns the configuration used when no options are given.
+func DefaultFusionConfig() FusionConfig {
+	return FusionConfig{
+		Noise:    defaultNoiseLevel,
+		MinAlpha: alphaLowerBound,
+		MaxAlpha: alphaUpperBound,
+		Mode:     FusionStrict,
+		Logger:   stdoutLogger{},
+	}
+}
+
+// Option modifies a FusionConfig; see NewIMUFusionSystem.
+type Option func(*FusionConfig)
+
+// WithNoise sets the IMU noise level of the filters and the default uncertainty model.
+func WithNoise(level float64) Option {
+	return func(c *FusionConfig) { c.Noise = level }
+}
+
+// WithAlphaBounds sets the range [lo, hi] of expansion factors searched by the geometric fusion.
+func WithAlphaBounds(lo, hi float64) Option {
+	return func(c *FusionConfig) { c.MinAlpha, c.MaxAlpha = lo, hi }
+}
+
+// WithFusionMode sets what is fused when the circles share no point; see FusionMode.
+func WithFusionMode(mode FusionMode) Option {
+	return func(c *FusionConfig) { c.Mode = mode }
+}
+
+// WithLogger sends the system's warnings to logger instead of stdout, including those of the
+// filters the system creates. A source passed to NewIMUFusionSystemWithSource keeps its own
+// logger; RetryingSource and FileReplaySource have a SetLogger for that. A nil logger restores
+// stdout.
+func WithLogger(logger Logger) Option {
+	return func(c *FusionConfig) {
+		if logger == nil {
+			logger = stdoutLogger{}
+		}
+		c.Logger = logger
+	}
+}
+
+// newFusionConfig applies opts to the defaults and validates the noise level. The alpha bounds
+// are validated by FusionTracker.SetAlphaBounds.
+func newFusionConfig(opts []Option) (FusionConfig, error) {
+	cfg := DefaultFusionConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Noise <= 0 || math.IsInf(cfg.Noise, 0) || math.IsNaN(cfg.Noise) {
+		return FusionConfig{}, fmt.Errorf("noise level must be positive and finite, got %f", cfg.Noise)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,82 @@
+package internal
+
+import "time"
+
+// frameAverager combines consecutive aligned frames into one frame per period, so fusion can
+// run slower than acquisition. Each IMU's accelerations and angular velocities are averaged over
+// the period and stamped with its latest sample, so integrating the averaged frame over the
+// period covers the same motion as integrating every frame.
+type frameAverager struct {
+	period      time.Duration
+	windowStart time.Time // sample time of the last emitted frame
+	started     bool
+	sums        map[int]*averagedSample // per-IMU sums of the current period
+	order       []int                   // IMU IDs in the order they first appeared in the period
+}
+
+// averagedSample accumulates one IMU's samples over a period.
+type averagedSample struct {
+	latest       IMUData
+	acceleration [3]float64
+	angular      [3]float64
+	count        float64
+}
+
+// newFrameAverager creates a frameAverager emitting at most one frame per period.
+func newFrameAverager(period time.Duration) *frameAverager {
+	return &frameAverager{period: period, sums: make(map[int]*averagedSample)}
+}
+
+// add accumulates frame and returns the averaged frame once its sample time is a full period
+// after the last emitted frame, or false while the period is still filling. The first frame
+// starts the first period.
+func (a *frameAverager) add(frame []IMUData) ([]IMUData, bool) {
+	if len(frame) == 0 {
+		return nil, false
+	}
+	for _, data := range frame {
+		s, ok := a.sums[data.IMUID]
+		if !ok {
+			s = &averagedSample{}
+			a.sums[data.IMUID] = s
+			a.order = append(a.order, data.IMUID)
+		}
+		s.latest = data
+		for i := 0; i < 3; i++ {
+			s.acceleration[i] += data.Acceleration[i]
+			s.angular[i] += data.AngularVelocity[i]
+		}
+		s.count++
+	}
+
+	now := frame[0].SampleTime()
+	if !a.started {
+		a.windowStart = now
+		a.started = true
+	}
+	if now.Sub(a.windowStart) < a.period {
+		return nil, false
+	}
+
+	averaged := make([]IMUData, 0, len(a.order))
+	for _, id := range a.order {
+		s := a.sums[id]
+		data := s.latest
+		for i := 0; i < 3; i++ {
+			data.Acceleration[i] = s.acceleration[i] / s.count
+			data.AngularVelocity[i] = s.angular[i] / s.count
+		}
+		averaged = append(averaged, data)
+	}
+	a.windowStart = now
+	a.sums = make(map[int]*averagedSample)
+	a.order = a.order[:0]
+	return averaged, true
+}
+
+// reset discards the current period; the next frame starts a new one.
+func (a *frameAverager) reset() {
+	a.started = false
+	a.sums = make(map[int]*averagedSample)
+	a.order = a.order[:0]
+}
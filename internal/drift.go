@@ -0,0 +1,89 @@
+package internal
+
+import "math"
+
+// BiasDriftMonitor watches the mean calibrated acceleration of each IMU while the body is
+// stationary. At rest the planar acceleration should be zero, so a persistent mean is residual
+// bias that calibration no longer removes.
+type BiasDriftMonitor struct {
+	threshold float64 // drift magnitude that triggers a report, 0 to disable
+	window    int     // stationary samples averaged per IMU
+
+	samples [][][2]float64 // per-IMU ring buffer of calibrated accelerations
+	sums    [][2]float64   // per-IMU running sum over the ring buffer
+	counts  []int          // per-IMU samples in the ring buffer
+	next    []int          // per-IMU ring buffer write index
+	flagged []bool         // per-IMU, whether drift has been reported since it last fell below threshold
+}
+
+// driftEvent is a threshold crossing reported by BiasDriftMonitor.
+type driftEvent struct {
+	imuID int
+	drift float64
+}
+
+// NewBiasDriftMonitor creates a monitor for imuCount IMUs averaging over window stationary samples.
+func NewBiasDriftMonitor(imuCount, window int, threshold float64) *BiasDriftMonitor {
+	m := &BiasDriftMonitor{
+		threshold: threshold,
+		window:    window,
+		samples:   make([][][2]float64, imuCount),
+		sums:      make([][2]float64, imuCount),
+		counts:    make([]int, imuCount),
+		next:      make([]int, imuCount),
+		flagged:   make([]bool, imuCount),
+	}
+	for i := range m.samples {
+		m.samples[i] = make([][2]float64, window)
+	}
+	return m
+}
+
+// SetThreshold changes the drift magnitude that triggers a report. 0 disables the monitor.
+func (m *BiasDriftMonitor) SetThreshold(threshold float64) {
+	m.threshold = threshold
+}
+
+// Add records a stationary calibrated acceleration for an IMU. It returns the mean drift
+// magnitude over the window, and whether this sample made it cross the threshold.
+// Each crossing is reported once; the monitor re-arms when the drift falls back below.
+func (m *BiasDriftMonitor) Add(imuID int, ax, ay float64) (float64, bool) {
+	if m.threshold <= 0 || m.window <= 0 || imuID < 0 || imuID >= len(m.samples) {
+		return 0, false
+	}
+	slot := &m.samples[imuID][m.next[imuID]]
+	if m.counts[imuID] == m.window {
+		m.sums[imuID][0] -= slot[0]
+		m.sums[imuID][1] -= slot[1]
+	} else {
+		m.counts[imuID]++
+	}
+	*slot = [2]float64{ax, ay}
+	m.sums[imuID][0] += ax
+	m.sums[imuID][1] += ay
+	m.next[imuID] = (m.next[imuID] + 1) % m.window
+
+	if m.counts[imuID] < m.window {
+		return 0, false
+	}
+	n := float64(m.window)
+	drift := math.Hypot(m.sums[imuID][0]/n, m.sums[imuID][1]/n)
+	if drift <= m.threshold {
+		m.flagged[imuID] = false
+		return drift, false
+	}
+	if m.flagged[imuID] {
+		return drift, false
+	}
+	m.flagged[imuID] = true
+	return drift, true
+}
+
+// Reset discards the windows, e.g. when the body starts moving. Reported crossings stay flagged.
+func (m *BiasDriftMonitor) Reset() {
+	for i := range m.samples {
+		m.sums[i] = [2]float64{}
+		m.counts[i] = 0
+		m.next[i] = 0
+	}
+}
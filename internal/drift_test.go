@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBiasDriftMonitorReportsOncePerCrossing(t *testing.T) {
+	m := NewBiasDriftMonitor(1, 4, 0.1)
+
+	for i := 0; i < 3; i++ {
+		if _, crossed := m.Add(0, 0.5, 0); crossed {
+			t.Fatal("Expected no report before the window fills")
+		}
+	}
+	if drift, crossed := m.Add(0, 0.5, 0); !crossed || !floatsClose(drift, 0.5, 1e-9) {
+		t.Fatalf("Expected a crossing with drift 0.5, got %f (crossed=%v)", drift, crossed)
+	}
+	if _, crossed := m.Add(0, 0.5, 0); crossed {
+		t.Error("Expected a crossing to be reported only once")
+	}
+
+	// Drift falls back below the threshold, then rises again.
+	for i := 0; i < 4; i++ {
+		m.Add(0, 0, 0)
+	}
+	var crossed bool
+	for i := 0; i < 4 && !crossed; i++ {
+		_, crossed = m.Add(0, 0, -0.5)
+	}
+	if !crossed {
+		t.Error("Expected the monitor to re-arm after drift fell below the threshold")
+	}
+}
+
+func TestIMUFusionSystemBiasDriftCallback(t *testing.T) {
+	const imuCount = 2
+	sys, err := NewIMUFusionSystem(imuCount)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.SetStationarityDetection(20, 0.05, 0.01)
+	sys.SetBiasDriftThreshold(0.05)
+	fired := map[int]int{}
+	sys.OnBiasDrift(func(imuID int, drift float64) {
+		fired[imuID]++
+		if drift <= 0.05 {
+			t.Errorf("Expected reported drift above threshold, got %f", drift)
+		}
+	})
+
+	// IMU 0's bias slowly ramps up while the body sits still; IMU 1 stays clean.
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for step := 1; step <= 300; step++ {
+		ts := base.Add(time.Duration(step) * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{0.0005 * float64(step), 0, 0}},
+			{IMUID: 1, DeviceTimestamp: ts},
+		})
+	}
+
+	if fired[0] != 1 {
+		t.Errorf("Expected one drift report for IMU 0, got %d", fired[0])
+	}
+	if fired[1] != 0 {
+		t.Errorf("Expected no drift report for IMU 1, got %d", fired[1])
+	}
+}
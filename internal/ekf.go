@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/mathext"
+)
+
+// EKF estimates the position, velocity, and accelerometer bias of a single IMU.
+// Each axis is filtered independently with state [position, velocity, bias]: the
+// measured acceleration, minus the current bias estimate, drives the prediction,
+// and the bias becomes observable through position updates.
+// The motion model is currently linear, so the extended filter reduces to a standard
+// Kalman filter; the Jacobians are kept explicit so nonlinear terms can be added.
+type EKF struct {
+	axes       [3]ekfAxis
+	accelNoise float64 // accelerometer white noise standard deviation
+	biasNoise  float64 // bias random walk standard deviation per sqrt(second)
+
+	nisSum   float64 // sum of the normalized innovation squared over updates, see NIS
+	nisCount int
+
+	work ekfWork
+}
+
+// ekfWork holds the intermediate matrices of Predict and update, reused so that filtering
+// does not allocate.
+type ekfWork struct {
+	F, Ft, Q, FP, FPFt, KHP *mat.Dense
+	G, PHt, K               *mat.VecDense
+}
+
+func newEKFWork() ekfWork {
+	return ekfWork{
+		F:    mat.NewDense(3, 3, nil),
+		Ft:   mat.NewDense(3, 3, nil),
+		Q:    mat.NewDense(3, 3, nil),
+		FP:   mat.NewDense(3, 3, nil),
+		FPFt: mat.NewDense(3, 3, nil),
+		KHP:  mat.NewDense(3, 3, nil),
+		G:    mat.NewVecDense(3, nil),
+		PHt:  mat.NewVecDense(3, nil),
+		K:    mat.NewVecDense(3, nil),
+	}
+}
+
+// ekfAxis is the state and covariance along one axis.
+type ekfAxis struct {
+	x *mat.VecDense // [position, velocity, bias]
+	P *mat.Dense    // 3x3 state covariance
+}
+
+// Indices into the per-axis state vector.
+const (
+	ekfPos = iota
+	ekfVel
+	ekfBias
+)
+
+// minMeasurementVariance keeps position updates well-conditioned when the reported variance is zero.
+const minMeasurementVariance = 1e-12
+
+// NewEKF creates an EKF at rest at the origin with zero bias.
+// initialBiasStd is the prior standard deviation of the accelerometer bias.
+func NewEKF(accelNoise, biasNoise, initialBiasStd float64) *EKF {
+	f := &EKF{accelNoise: accelNoise, biasNoise: biasNoise, work: newEKFWork()}
+	for i := range f.axes {
+		f.axes[i] = ekfAxis{
+			x: mat.NewVecDense(3, nil),
+			P: mat.NewDense(3, 3, []float64{
+				0, 0, 0,
+				0, 0, 0,
+				0, 0, initialBiasStd * initialBiasStd,
+			}),
+		}
+	}
+	return f
+}
+
+// Predict propagates the state by dt seconds using the measured acceleration.
+// The linear model assumes the IMU does not rotate, so gyro is unused.
+func (f *EKF) Predict(accel, gyro [3]float64, dt float64) {
+	half := 0.5 * dt * dt
+	w := &f.work
+	// Jacobian of the motion model with respect to [position, velocity, bias].
+	F := []float64{
+		1, dt, -half,
+		0, 1, -dt,
+		0, 0, 1,
+	}
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			w.F.Set(r, c, F[3*r+c])
+			w.Ft.Set(c, r, F[3*r+c])
+		}
+	}
+	// Process noise: accelerometer noise enters like an acceleration, bias follows a random walk.
+	w.G.SetVec(ekfPos, half)
+	w.G.SetVec(ekfVel, dt)
+	w.G.SetVec(ekfBias, 0)
+	w.Q.Outer(f.accelNoise*f.accelNoise, w.G, w.G)
+	w.Q.Set(ekfBias, ekfBias, f.biasNoise*f.biasNoise*dt)
+
+	for i := range f.axes {
+		ax := &f.axes[i]
+		a := accel[i] - ax.x.AtVec(ekfBias)
+		p := ax.x.AtVec(ekfPos) + ax.x.AtVec(ekfVel)*dt + a*half
+		v := ax.x.AtVec(ekfVel) + a*dt
+		ax.x.SetVec(ekfPos, p)
+		ax.x.SetVec(ekfVel, v)
+
+		w.FP.Mul(w.F, ax.P)
+		w.FPFt.Mul(w.FP, w.Ft)
+		ax.P.Add(w.FPFt, w.Q)
+	}
+}
+
+// UpdatePosition corrects the state along axis with a position measurement z of the given variance.
+func (f *EKF) UpdatePosition(axis int, z, variance float64) {
+	f.update(axis, ekfPos, z, variance)
+}
+
+// UpdateVelocity corrects the state along axis with a velocity measurement z of the given variance,
+// e.g. a zero-velocity update while the body is stationary.
+func (f *EKF) UpdateVelocity(axis int, z, variance float64) {
+	f.update(axis, ekfVel, z, variance)
+}
+
+// update applies a direct measurement z of state component idx along axis.
+func (f *EKF) update(axis, idx int, z, variance float64) {
+	if variance < minMeasurementVariance {
+		variance = minMeasurementVariance
+	}
+	ax := &f.axes[axis]
+	w := &f.work
+
+	// H selects component idx, so P*H^T is column idx of P and H*P*H^T is P[idx][idx].
+	for r := 0; r < 3; r++ {
+		w.PHt.SetVec(r, ax.P.At(r, idx))
+	}
+	S := ax.P.At(idx, idx) + variance
+	w.K.ScaleVec(1/S, w.PHt)
+
+	innovation := z - ax.x.AtVec(idx)
+	ax.x.AddScaledVec(ax.x, innovation, w.K)
+	f.nisSum += innovation * innovation / S
+	f.nisCount++
+
+	// P = P - K * (H * P) = P - K * PHt^T, since P is symmetric.
+	w.KHP.Outer(1, w.K, w.PHt)
+	ax.P.Sub(ax.P, w.KHP)
+}
+
+// NIS returns the mean normalized innovation squared, innovation^2 / S for innovation variance S,
+// over the updates since the filter was created or ResetNIS was called, and the number of updates.
+// Each update is scalar, so for a filter whose noise parameters match the data the mean is 1 and
+// falls within NISBounds; a mean above the bounds means the noise is set too low, below too high.
+func (f *EKF) NIS() (float64, int) {
+	if f.nisCount == 0 {
+		return 0, 0
+	}
+	return f.nisSum / float64(f.nisCount), f.nisCount
+}
+
+// ResetNIS discards the updates accumulated by NIS, e.g. after the initial transient.
+func (f *EKF) ResetNIS() {
+	f.nisSum, f.nisCount = 0, 0
+}
+
+// NISBounds returns the two-sided confidence interval, at the given confidence such as 0.95,
+// of the mean NIS of n consistent scalar updates: n times the mean is chi-square with n degrees
+// of freedom. It returns zeros if n is not positive.
+func NISBounds(n int, confidence float64) (lo, hi float64) {
+	if n <= 0 {
+		return 0, 0
+	}
+	// The chi-square quantile with k degrees of freedom is 2 * P^-1(k/2, p), where P is the
+	// regularized lower incomplete gamma function.
+	k := float64(n)
+	tail := (1 - confidence) / 2
+	return 2 * mathext.GammaIncRegInv(k/2, tail) / k, 2 * mathext.GammaIncRegInv(k/2, 1-tail) / k
+}
+
+// SetPosition overwrites the position estimate along each axis, leaving velocity and bias untouched.
+func (f *EKF) SetPosition(pos [3]float64) {
+	for i := range f.axes {
+		f.axes[i].x.SetVec(ekfPos, pos[i])
+	}
+}
+
+// Position returns the estimated position along each axis.
+func (f *EKF) Position() [3]float64 {
+	return f.component(ekfPos)
+}
+
+// Velocity returns the estimated velocity along each axis.
+func (f *EKF) Velocity() [3]float64 {
+	return f.component(ekfVel)
+}
+
+// Bias returns the estimated accelerometer bias along each axis.
+func (f *EKF) Bias() [3]float64 {
+	return f.component(ekfBias)
+}
+
+func (f *EKF) component(idx int) [3]float64 {
+	var out [3]float64
+	for i := range f.axes {
+		out[i] = f.axes[i].x.AtVec(idx)
+	}
+	return out
+}
+
+// ekfCovarianceLen is the length of FilterState.Covariance for an EKF.
+const ekfCovarianceLen = 3 * 9
+
+// State returns a copy of the filter state.
+func (f *EKF) State() FilterState {
+	s := FilterState{
+		Kind:       FilterEKF,
+		Position:   f.Position(),
+		Velocity:   f.Velocity(),
+		Bias:       f.Bias(),
+		Covariance: make([]float64, 0, ekfCovarianceLen),
+	}
+	for i := range f.axes {
+		s.Covariance = append(s.Covariance, f.axes[i].P.RawMatrix().Data...)
+	}
+	return s
+}
+
+// SetState overwrites the filter state. The noise parameters are left unchanged.
+func (f *EKF) SetState(s FilterState) error {
+	if err := checkFilterState(FilterEKF, s); err != nil {
+		return err
+	}
+	for i := range f.axes {
+		f.axes[i].x.SetVec(ekfPos, s.Position[i])
+		f.axes[i].x.SetVec(ekfVel, s.Velocity[i])
+		f.axes[i].x.SetVec(ekfBias, s.Bias[i])
+		f.axes[i].P = mat.NewDense(3, 3, append([]float64(nil), s.Covariance[i*9:(i+1)*9]...))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEKFEstimatesConstantBias(t *testing.T) {
+	trueBias := [3]float64{0.3, -0.2, 0}
+	filter := NewEKF(0.05, 1e-4, 1.0)
+
+	const dt = 0.01
+	// The IMU is stationary at the origin; the accelerometer only reports its bias.
+	for step := 0; step < 2000; step++ {
+		filter.Predict(trueBias, [3]float64{}, dt)
+		filter.UpdatePosition(0, 0, 1e-4)
+		filter.UpdatePosition(1, 0, 1e-4)
+	}
+
+	bias := filter.Bias()
+	for axis := 0; axis < 2; axis++ {
+		if !floatsClose(bias[axis], trueBias[axis], 0.01) {
+			t.Errorf("axis %d: expected bias close to %f, got %f", axis, trueBias[axis], bias[axis])
+		}
+	}
+	// Z is never observed and should stay at its prior.
+	if bias[2] != 0 {
+		t.Errorf("Expected unobserved Z bias to stay 0, got %f", bias[2])
+	}
+
+	pos := filter.Position()
+	vel := filter.Velocity()
+	for axis := 0; axis < 2; axis++ {
+		if !floatsClose(pos[axis], 0, 1e-2) || !floatsClose(vel[axis], 0, 1e-2) {
+			t.Errorf("axis %d: expected filter at rest at origin, got position %f velocity %f", axis, pos[axis], vel[axis])
+		}
+	}
+}
+
+func TestEKFPredictIntegratesAcceleration(t *testing.T) {
+	filter := NewEKF(0.1, 1e-3, 0.5)
+	for step := 0; step < 100; step++ {
+		filter.Predict([3]float64{1, 0, -2}, [3]float64{}, 0.01)
+	}
+
+	// After 1s of constant acceleration: v = a*t, p = a*t^2/2.
+	pos := filter.Position()
+	vel := filter.Velocity()
+	want := [3]float64{1, 0, -2}
+	for axis := range want {
+		if !floatsClose(vel[axis], want[axis], 1e-9) {
+			t.Errorf("axis %d: expected velocity %f, got %f", axis, want[axis], vel[axis])
+		}
+		if !floatsClose(pos[axis], want[axis]/2, 1e-9) {
+			t.Errorf("axis %d: expected position %f, got %f", axis, want[axis]/2, pos[axis])
+		}
+	}
+}
+
+func TestEKFNISConsistency(t *testing.T) {
+	const (
+		accelNoise = 0.05
+		posNoise   = 0.01
+		dt         = 0.01
+		steps      = 3000
+		warmup     = 200
+	)
+	// run filters a stationary IMU with a constant bias, white accelerometer noise, and noisy
+	// position fixes, telling the filter the measurement noise is assumedPosNoise.
+	run := func(assumedPosNoise float64) (float64, int) {
+		rng := rand.New(rand.NewSource(1))
+		filter := NewEKF(accelNoise, 0, 1.0)
+		for step := 0; step < steps; step++ {
+			if step == warmup {
+				filter.ResetNIS()
+			}
+			accel := [3]float64{0.3 + accelNoise*rng.NormFloat64(), -0.2 + accelNoise*rng.NormFloat64(), 0}
+			filter.Predict(accel, [3]float64{}, dt)
+			for axis := 0; axis < 2; axis++ {
+				filter.UpdatePosition(axis, posNoise*rng.NormFloat64(), assumedPosNoise*assumedPosNoise)
+			}
+		}
+		return filter.NIS()
+	}
+
+	tests := []struct {
+		name         string
+		assumedNoise float64
+		expectIn     bool
+	}{
+		{"Matched Noise", posNoise, true},
+		{"Noise Set Too Low", posNoise / 10, false},
+		{"Noise Set Too High", posNoise * 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nis, n := run(tt.assumedNoise)
+			if n != 2*(steps-warmup) {
+				t.Fatalf("Expected %d updates, got %d", 2*(steps-warmup), n)
+			}
+			lo, hi := NISBounds(n, 0.95)
+			if in := nis >= lo && nis <= hi; in != tt.expectIn {
+				t.Errorf("Expected NIS in [%f, %f] to be %v, got %f", lo, hi, tt.expectIn, nis)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package internal
+
+import "math"
+
+// Extrinsics describes how an IMU is mounted on the rigid body.
+type Extrinsics struct {
+	Rotation [2][2]float64 // rotates vectors from the IMU frame into the body frame
+	Offset   Point         // lever arm: IMU position relative to the body reference point
+
+	// Roll and Pitch are the tilt of the IMU from the horizontal plane, in radians, applied
+	// about X then Y. Zero for an IMU mounted level.
+	Roll, Pitch float64
+
+	// LeverArm is the IMU position relative to the body's center of rotation, in the body
+	// frame, used to remove the acceleration the IMU measures because the body rotates.
+	LeverArm Point
+}
+
+// IdentityExtrinsics returns extrinsics for an IMU aligned with, and mounted at, the body reference point.
+func IdentityExtrinsics() Extrinsics {
+	return Extrinsics{Rotation: [2][2]float64{{1, 0}, {0, 1}}}
+}
+
+// Rotate maps a vector from the IMU frame into the body frame.
+func (e Extrinsics) Rotate(x, y float64) (float64, float64) {
+	return e.Rotation[0][0]*x + e.Rotation[0][1]*y, e.Rotation[1][0]*x + e.Rotation[1][1]*y
+}
+
+// RotationalAcceleration returns the acceleration, in the body frame, that an IMU at LeverArm
+// measures when the body turns at rate omega, in rad/s, with angular acceleration alpha, in
+// rad/s^2: the tangential alpha × r plus the centripetal omega × (omega × r) = -omega^2 r.
+func (e Extrinsics) RotationalAcceleration(omega, alpha float64) (float64, float64) {
+	r := e.LeverArm
+	return -alpha*r.Y - omega*omega*r.X, alpha*r.X - omega*omega*r.Y
+}
+
+// Level projects a 3D acceleration in the tilted IMU frame onto the horizontal plane. The
+// vertical component, which carries gravity, is discarded; without it, a tilted IMU would
+// integrate part of gravity as horizontal motion.
+func (e Extrinsics) Level(x, y, z float64) (float64, float64) {
+	R := tiltRotation(e.Roll, e.Pitch)
+	return R[0][0]*x + R[0][1]*y + R[0][2]*z, R[1][0]*x + R[1][1]*y + R[1][2]*z
+}
+
+// tiltRotation returns the rotation from a frame tilted by roll about X, then pitch about Y,
+// back to the level frame.
+func tiltRotation(roll, pitch float64) [3][3]float64 {
+	cr, sr := math.Cos(roll), math.Sin(roll)
+	cp, sp := math.Cos(pitch), math.Sin(pitch)
+	return [3][3]float64{
+		{cp, sp * sr, sp * cr},
+		{0, cr, -sr},
+		{-sp, cp * sr, cp * cr},
+	}
+}
+
+// TiltFromGravity estimates the roll and pitch of a stationary IMU from its accelerometer
+// reading, which then measures only the reaction to gravity. The result suits SetTilt.
+func TiltFromGravity(accel [3]float64) (roll, pitch float64) {
+	roll = math.Atan2(accel[1], accel[2])
+	pitch = math.Atan2(-accel[0], math.Hypot(accel[1], accel[2]))
+	return roll, pitch
+}
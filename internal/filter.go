@@ -0,0 +1,89 @@
+package internal
+
+import "fmt"
+
+// Filter estimates the position, velocity, and accelerometer bias of a single IMU.
+// EKF and UKF both implement it, so either can be selected with FilterKind.
+type Filter interface {
+	// Predict propagates the state by dt seconds using the measured acceleration and
+	// angular velocity (rad/s).
+	Predict(accel, gyro [3]float64, dt float64)
+	// UpdatePosition corrects the state along axis with a position measurement z of the given variance.
+	UpdatePosition(axis int, z, variance float64)
+	// UpdateVelocity corrects the state along axis with a velocity measurement z of the given variance.
+	UpdateVelocity(axis int, z, variance float64)
+	// SetPosition overwrites the position estimate along each axis.
+	SetPosition(pos [3]float64)
+	Position() [3]float64
+	Velocity() [3]float64
+	Bias() [3]float64
+	// State returns a serializable copy of the filter state.
+	State() FilterState
+	// SetState overwrites the filter state with one taken from a filter of the same kind.
+	SetState(FilterState) error
+}
+
+// FilterKind selects the per-IMU Filter implementation.
+type FilterKind int
+
+const (
+	// FilterEKF is a linear per-axis filter that treats accelerations as world-frame.
+	FilterEKF FilterKind = iota
+	// FilterUKF also estimates heading from the gyro, rotating body-frame accelerations into
+	// the world frame through a sigma-point transform.
+	FilterUKF
+)
+
+// String returns the filter name.
+func (k FilterKind) String() string {
+	switch k {
+	case FilterEKF:
+		return "EKF"
+	case FilterUKF:
+		return "UKF"
+	default:
+		return "unknown"
+	}
+}
+
+// checkFilterState reports why s cannot be restored into a Filter of the given kind, or nil.
+func checkFilterState(kind FilterKind, s FilterState) error {
+	var size int
+	var diagonal []int // indices of the variances in Covariance
+	switch kind {
+	case FilterEKF:
+		size = ekfCovarianceLen
+		for axis := 0; axis < 3; axis++ {
+			for r := 0; r < 3; r++ {
+				diagonal = append(diagonal, axis*9+r*3+r)
+			}
+		}
+	case FilterUKF:
+		size = ukfDim * ukfDim
+		for i := 0; i < ukfDim; i++ {
+			diagonal = append(diagonal, i*ukfDim+i)
+		}
+	}
+	if s.Kind != kind || len(s.Covariance) != size {
+		return fmt.Errorf("%v: cannot restore %v state with %d covariance entries", kind, s.Kind, len(s.Covariance))
+	}
+	for _, i := range diagonal {
+		if s.Covariance[i] < 0 {
+			return fmt.Errorf("%v: negative variance at covariance entry %d", kind, i)
+		}
+	}
+	return nil
+}
+
+// FilterState is a serializable copy of a Filter's state and covariance.
+type FilterState struct {
+	Kind     FilterKind
+	Position [3]float64
+	Velocity [3]float64
+	Bias     [3]float64
+	Heading  float64 // yaw in radians, FilterUKF only
+
+	// Covariance is row-major: three per-axis 3x3 blocks over [position, velocity, bias]
+	// for FilterEKF, the full UKF state covariance for FilterUKF.
+	Covariance []float64
+}
@@ -1,16 +1,125 @@
 package internal
 
 import (
+	"fmt"
 	"math"
+	"runtime"
+	"sort"
+	"sync"
 )
 
 const epsilon = 1e-9 // Small tolerance for floating-point comparisons
 
+// GeometryConfig sets the tolerances used by the geometric comparisons in the fusion functions.
+// Lengths compared near coordinates of magnitude m are treated as equal within
+// max(AbsTol, RelTol*m), since the rounding error of a coordinate grows with its magnitude.
+type GeometryConfig struct {
+	AbsTol   float64 // absolute tolerance, in position units
+	RelTol   float64 // tolerance relative to the magnitude of the coordinates and radii involved
+	DedupTol float64 // distance within which candidate intersection points are merged
+
+	// RefineIterations is the number of RefineIntersectionPoint steps applied to the point found
+	// by AllCirclesIntersectAtPoint, moving it away from the circle boundaries; 0 disables it.
+	RefineIterations int
+
+	// Strategy selects which point of the common region AllCirclesIntersectAtPoint reports.
+	Strategy PointStrategy
+
+	// NonOverlap selects what GeometricFusion2D reports when the circles share no point even
+	// at the largest expansion alphaUpperBound.
+	NonOverlap NonOverlapPolicy
+}
+
+// PointStrategy selects the point AllCirclesIntersectAtPoint reports when the circles share a
+// region rather than a single point.
+type PointStrategy int
+
+const (
+	// StrategyDefault prefers the smallest circle center lying inside all circles, then the
+	// inverse-radius weighted centroid of the pairwise intersections inside all circles if it is
+	// itself inside them, then the first such intersection, then the centroid of the centers.
+	StrategyDefault PointStrategy = iota
+	// StrategyDeepest reports the point furthest inside the tightest circle, the one maximising
+	// its smallest margin r_i - |p - c_i|, the point most robust to radii shrinking.
+	StrategyDeepest
+	// StrategyCentroid reports the area centroid of the common region, sampled on a grid.
+	StrategyCentroid
+	// StrategyFirst reports the first point found inside all circles: the first circle center,
+	// else the first pairwise intersection in pair order. It is the cheapest.
+	StrategyFirst
+)
+
+// NonOverlapPolicy selects what GeometricFusion2D reports for circles that no expansion up to
+// alphaUpperBound brings into a common point.
+type NonOverlapPolicy int
+
+const (
+	// NonOverlapExpand relies on alpha expansion alone, reporting the origin at alphaUpperBound
+	// if it fails.
+	NonOverlapExpand NonOverlapPolicy = iota
+	// NonOverlapNearestWeighted reports the inverse-radius weighted mean of the centers at
+	// alphaUpperBound, a point pulled toward the less uncertain circles.
+	NonOverlapNearestWeighted
+)
+
+// deepestIterations is the number of RefineIntersectionPoint steps taken by StrategyDeepest.
+const deepestIterations = 1000
+
+// centroidGridSize is the number of samples per axis StrategyCentroid takes over the bounding box
+// of the common region.
+const centroidGridSize = 64
+
+// DefaultGeometryConfig returns the package defaults: an absolute tolerance of 1e-9 and no
+// relative term, suitable for coordinates of order one.
+func DefaultGeometryConfig() GeometryConfig {
+	return GeometryConfig{AbsTol: epsilon, DedupTol: epsilon}
+}
+
+// tol returns the comparison tolerance for values of the given magnitude.
+func (g GeometryConfig) tol(magnitude float64) float64 {
+	return math.Max(g.AbsTol, g.RelTol*magnitude)
+}
+
+// magnitude returns the largest absolute coordinate or length among its arguments.
+func magnitude(values ...float64) float64 {
+	m := 0.0
+	for _, v := range values {
+		m = math.Max(m, math.Abs(v))
+	}
+	return m
+}
+
 // Position represents a 2D position with uncertainty.
 type Position struct {
 	X float64
 	Y float64
 	R float64 // Uncertainty radius
+
+	// Cov is an optional positive definite covariance for uncertainty that differs between
+	// directions, for example along and across the direction of motion. The zero value means
+	// the uncertainty is the circle of radius R.
+	Cov [2][2]float64
+}
+
+// Covariance returns Cov, or the isotropic covariance R^2 I if Cov is not set.
+func (p Position) Covariance() [2][2]float64 {
+	if p.Cov != ([2][2]float64{}) {
+		return p.Cov
+	}
+	return [2][2]float64{{p.R * p.R, 0}, {0, p.R * p.R}}
+}
+
+// EigenRadius returns the radius of the circle enclosing the uncertainty: the square root of the
+// largest eigenvalue of Cov, or R if Cov is not set. Circle-based fusion uses it to approximate an
+// elliptical uncertainty.
+func (p Position) EigenRadius() float64 {
+	if p.Cov == ([2][2]float64{}) {
+		return p.R
+	}
+	c := p.Cov
+	mean := (c[0][0] + c[1][1]) / 2
+	spread := math.Hypot((c[0][0]-c[1][1])/2, (c[0][1]+c[1][0])/2)
+	return math.Sqrt(math.Max(mean+spread, 0))
 }
 
 // Vec2 is a simple 2D vector.
@@ -23,14 +132,34 @@ func Distance2D(a, b Vec2) float64 {
 	return math.Hypot(a.X-b.X, a.Y-b.Y)
 }
 
-// intersectTwoCircles finds the intersection points of two circles.
+// BatchDistance2D writes the Euclidean distance from origin to each of points into out,
+// which must have at least len(points) elements. It skips math.Hypot's overflow protection,
+// which is unnecessary at the scale of IMU positions, keeping the loop simple enough for the
+// compiler to optimise.
+func BatchDistance2D(origin Vec2, points []Vec2, out []float64) {
+	out = out[:len(points)]
+	for i, p := range points {
+		dx := p.X - origin.X
+		dy := p.Y - origin.Y
+		out[i] = math.Sqrt(dx*dx + dy*dy)
+	}
+}
+
+// intersectTwoCircles finds the intersection points of two circles using the default tolerances.
 // Returns the number of intersection points (0, 1, or 2) and the points themselves.
 func intersectTwoCircles(c1 Vec2, r1 float64, c2 Vec2, r2 float64) (int, Vec2, Vec2) {
+	return DefaultGeometryConfig().intersectTwoCircles(c1, r1, c2, r2)
+}
+
+// intersectTwoCircles finds the intersection points of two circles.
+// Returns the number of intersection points (0, 1, or 2) and the points themselves.
+func (g GeometryConfig) intersectTwoCircles(c1 Vec2, r1 float64, c2 Vec2, r2 float64) (int, Vec2, Vec2) {
 	d := Distance2D(c1, c2)
+	tol := g.tol(magnitude(c1.X, c1.Y, c2.X, c2.Y, r1, r2))
 
 	// Check for cases where circles do not intersect
-	if d > r1+r2+epsilon || d < math.Abs(r1-r2)-epsilon || d < epsilon && math.Abs(r1-r2) > epsilon {
-		return 0, Vec2{}, Vec2{} // No intersection or one contains the other without touching
+	if d > r1+r2+tol || d < math.Abs(r1-r2)-tol || d < tol {
+		return 0, Vec2{}, Vec2{} // No intersection, one contains the other without touching, or concentric
 	}
 
 	// Calculate intersection points using formulas derived from law of cosines
@@ -51,18 +180,46 @@ func intersectTwoCircles(c1 Vec2, r1 float64, c2 Vec2, r2 float64) (int, Vec2, V
 		Y: y2 + h*(c2.X-c1.X)/d,
 	}
 
-	// Check for tangency (one intersection point)
-	if d > r1+r2-epsilon || d < math.Abs(r1-r2)+epsilon || h < epsilon {
-		return 1, p1, Vec2{} // Tangent
+	// Tangent, externally or internally, only when the center distance matches r1+r2 or |r1-r2|
+	// within tolerance; the touching point is then the foot of the chord between the centers.
+	if math.Abs(d-(r1+r2)) < tol || math.Abs(d-math.Abs(r1-r2)) < tol {
+		return 1, Vec2{X: x2, Y: y2}, Vec2{}
 	}
 
 	return 2, p1, p2 // Two intersection points
 }
 
-// isInsideAll checks if a point p is inside all circles defined by centers and radii.
+// LensArea returns the area of the intersection of two circles: zero when they are disjoint or
+// tangent, the smaller disc when one contains the other, and otherwise the lens formed by the
+// two circular segments.
+func LensArea(c1 Vec2, r1 float64, c2 Vec2, r2 float64) float64 {
+	if r1 <= 0 || r2 <= 0 {
+		return 0
+	}
+	d := Distance2D(c1, c2)
+	if d >= r1+r2 {
+		return 0
+	}
+	if d <= math.Abs(r1-r2) {
+		r := math.Min(r1, r2)
+		return math.Pi * r * r
+	}
+	clamp := func(v float64) float64 { return math.Max(-1, math.Min(1, v)) }
+	a1 := math.Acos(clamp((d*d + r1*r1 - r2*r2) / (2 * d * r1)))
+	a2 := math.Acos(clamp((d*d + r2*r2 - r1*r1) / (2 * d * r2)))
+	kite := 0.5 * math.Sqrt(math.Max(0, (-d+r1+r2)*(d+r1-r2)*(d-r1+r2)*(d+r1+r2)))
+	return r1*r1*a1 + r2*r2*a2 - kite
+}
+
+// isInsideAll checks, using the default tolerances, if a point p is inside all circles defined by centers and radii.
 func isInsideAll(p Vec2, centers []Vec2, radii []float64) bool {
+	return DefaultGeometryConfig().isInsideAll(p, centers, radii)
+}
+
+// isInsideAll checks if a point p is inside all circles defined by centers and radii.
+func (g GeometryConfig) isInsideAll(p Vec2, centers []Vec2, radii []float64) bool {
 	for i, c := range centers {
-		if Distance2D(p, c) > radii[i]+epsilon {
+		if Distance2D(p, c) > radii[i]+g.tol(magnitude(p.X, p.Y, c.X, c.Y, radii[i])) {
 			return false
 		}
 	}
@@ -73,6 +230,52 @@ func isInsideAll(p Vec2, centers []Vec2, radii []float64) bool {
 // It finds candidate points from intersections and containment, returning a feasible point if found.
 // Returns (true, p) if such a point exists, else (false, zero).
 func AllCirclesIntersectAtPoint(centers []Vec2, radii []float64) (bool, Vec2) {
+	return DefaultGeometryConfig().AllCirclesIntersectAtPoint(centers, radii)
+}
+
+// AllCirclesIntersectAtPoint is the package-level AllCirclesIntersectAtPoint using g's tolerances.
+func (g GeometryConfig) AllCirclesIntersectAtPoint(centers []Vec2, radii []float64) (bool, Vec2) {
+	return g.allCirclesIntersectAtPoint(centers, radii, nil)
+}
+
+// AllCirclesIntersectAtPointVisit is AllCirclesIntersectAtPoint reporting, for visualization,
+// each pairwise intersection point the solver considers to visit, along with whether it lies
+// inside all circles. Pairs are visited in order on the calling goroutine. No points are
+// visited when a circle center lies inside all circles, since the solver returns it directly.
+func AllCirclesIntersectAtPointVisit(centers []Vec2, radii []float64, visit func(p Vec2, insideAll bool)) (bool, Vec2) {
+	return DefaultGeometryConfig().AllCirclesIntersectAtPointVisit(centers, radii, visit)
+}
+
+// AllCirclesIntersectAtPointVisit is the package-level AllCirclesIntersectAtPointVisit using g's tolerances.
+func (g GeometryConfig) AllCirclesIntersectAtPointVisit(centers []Vec2, radii []float64, visit func(p Vec2, insideAll bool)) (bool, Vec2) {
+	return g.allCirclesIntersectAtPoint(centers, radii, visit)
+}
+
+// allCirclesIntersectAtPoint implements AllCirclesIntersectAtPoint, passing candidates to visit if
+// it is not nil, moving the point found according to g.Strategy, and refining it if
+// g.RefineIterations is set.
+func (g GeometryConfig) allCirclesIntersectAtPoint(centers []Vec2, radii []float64, visit func(Vec2, bool)) (bool, Vec2) {
+	ok, p := g.intersectionPoint(centers, radii, visit)
+	if !ok || len(centers) < 2 {
+		return ok, p
+	}
+	switch g.Strategy {
+	case StrategyDeepest:
+		p = RefineIntersectionPoint(centers, radii, p, deepestIterations)
+	case StrategyCentroid:
+		// A region too thin to contain any sample, such as a tangency, keeps the analytic point.
+		if centroid, found := areaCentroid(centers, radii); found {
+			p = centroid
+		}
+	}
+	if g.RefineIterations > 0 {
+		p = RefineIntersectionPoint(centers, radii, p, g.RefineIterations)
+	}
+	return ok, p
+}
+
+// intersectionPoint is the analytic solver behind allCirclesIntersectAtPoint.
+func (g GeometryConfig) intersectionPoint(centers []Vec2, radii []float64, visit func(Vec2, bool)) (bool, Vec2) {
 	n := len(centers)
 	if n == 0 {
 		return false, Vec2{}
@@ -83,49 +286,38 @@ func AllCirclesIntersectAtPoint(centers []Vec2, radii []float64) (bool, Vec2) {
 
 	containedIndex := -1
 	for i := 0; i < n; i++ {
-		if isInsideAll(centers[i], centers, radii) && (containedIndex == -1 || radii[i] < radii[containedIndex]) {
+		if g.isInsideAll(centers[i], centers, radii) && (containedIndex == -1 || radii[i] < radii[containedIndex]) {
 			containedIndex = i
+			if g.Strategy == StrategyFirst {
+				break
+			}
 		}
 	}
 	if containedIndex != -1 {
 		return true, centers[containedIndex]
 	}
 
-	var candidates []Vec2
-	for i := 0; i < n; i++ {
-		for j := i + 1; j < n; j++ {
-			count, p1, p2 := intersectTwoCircles(centers[i], radii[i], centers[j], radii[j])
-			if count >= 1 {
-				candidates = append(candidates, p1)
-			}
-			if count == 2 {
-				candidates = append(candidates, p2)
-			}
+	buf := getCandidates()
+	candidates := *buf
+	defer func() { putCandidates(buf, candidates) }()
+	if n >= parallelPairThreshold && visit == nil {
+		candidates = g.pairCandidatesParallel(centers, radii)
+	} else {
+		for i := 0; i < n; i++ {
+			candidates = g.appendPairCandidates(candidates, centers, radii, i, visit)
 		}
 	}
 
-	valid := make([]Vec2, 0, len(candidates))
-	for _, p := range candidates {
-		if !isInsideAll(p, centers, radii) || containsVec2(valid, p) {
-			continue
-		}
-		valid = append(valid, p)
-	}
-	if len(valid) == 1 {
-		return true, valid[0]
+	valid := dedupCandidates(candidates, g.DedupTol)
+	if len(valid) == 1 || (len(valid) > 1 && g.Strategy == StrategyFirst) {
+		return true, valid[0].p
 	}
 	if len(valid) > 1 {
-		centroid := Vec2{}
-		for _, p := range valid {
-			centroid.X += p.X
-			centroid.Y += p.Y
-		}
-		centroid.X /= float64(len(valid))
-		centroid.Y /= float64(len(valid))
-		if isInsideAll(centroid, centers, radii) {
+		centroid := weightedCentroid(valid)
+		if g.isInsideAll(centroid, centers, radii) {
 			return true, centroid
 		}
-		return true, valid[0]
+		return true, valid[0].p
 	}
 
 	// 4. Fallback: Check the centroid of the original centers (for area intersections)
@@ -136,7 +328,7 @@ func AllCirclesIntersectAtPoint(centers []Vec2, radii []float64) (bool, Vec2) {
 	}
 	originalCentroid.X /= float64(n)
 	originalCentroid.Y /= float64(n)
-	if isInsideAll(originalCentroid, centers, radii) {
+	if g.isInsideAll(originalCentroid, centers, radii) {
 		return true, originalCentroid
 	}
 
@@ -144,41 +336,839 @@ func AllCirclesIntersectAtPoint(centers []Vec2, radii []float64) (bool, Vec2) {
 	return false, Vec2{}
 }
 
-func containsVec2(points []Vec2, p Vec2) bool {
-	for _, q := range points {
-		if Distance2D(p, q) <= epsilon {
-			return true
+// IntersectionPolygon returns the vertices, in counter-clockwise order, of a polygon
+// approximating the boundary of the circles' common region, or nil if they share no region.
+// The region is convex and bounded by circle arcs: each circle contributes the points of a
+// segments-sided sampling of its circumference that lie inside all other circles, and the
+// pairwise intersections where arcs meet are added as corners, so the polygon follows the
+// boundary exactly at its corners and to within the sampling between them.
+func IntersectionPolygon(centers []Vec2, radii []float64, segments int) []Vec2 {
+	return DefaultGeometryConfig().IntersectionPolygon(centers, radii, segments)
+}
+
+// IntersectionPolygon is the package-level IntersectionPolygon using g's tolerances.
+func (g GeometryConfig) IntersectionPolygon(centers []Vec2, radii []float64, segments int) []Vec2 {
+	if len(centers) == 0 || len(centers) != len(radii) || segments < 3 {
+		return nil
+	}
+	var vertices []Vec2
+	for i, c := range centers {
+		for k := 0; k < segments; k++ {
+			theta := 2 * math.Pi * float64(k) / float64(segments)
+			p := Vec2{X: c.X + radii[i]*math.Cos(theta), Y: c.Y + radii[i]*math.Sin(theta)}
+			if g.isInsideAll(p, centers, radii) {
+				vertices = append(vertices, p)
+			}
+		}
+	}
+	var corners []candidate
+	for i := range centers {
+		corners = g.appendPairCandidates(corners, centers, radii, i, nil)
+	}
+	for _, c := range corners {
+		vertices = append(vertices, c.p)
+	}
+	if len(vertices) == 0 {
+		return nil
+	}
+
+	// The region is convex, so its boundary points are ordered by angle about their mean.
+	var mean Vec2
+	for _, v := range vertices {
+		mean.X += v.X / float64(len(vertices))
+		mean.Y += v.Y / float64(len(vertices))
+	}
+	sort.Slice(vertices, func(a, b int) bool {
+		return math.Atan2(vertices[a].Y-mean.Y, vertices[a].X-mean.X) < math.Atan2(vertices[b].Y-mean.Y, vertices[b].X-mean.X)
+	})
+	polygon := vertices[:1]
+	for _, v := range vertices[1:] {
+		if Distance2D(v, polygon[len(polygon)-1]) > g.DedupTol {
+			polygon = append(polygon, v)
+		}
+	}
+	if len(polygon) > 1 && Distance2D(polygon[0], polygon[len(polygon)-1]) <= g.DedupTol {
+		polygon = polygon[:len(polygon)-1]
+	}
+	return polygon
+}
+
+// areaCentroid returns the centroid of the circles' common region, averaging the centers of a
+// centroidGridSize square grid of cells over its bounding box that lie inside all circles, or false
+// if none do.
+func areaCentroid(centers []Vec2, radii []float64) (Vec2, bool) {
+	lo := Vec2{X: math.Inf(-1), Y: math.Inf(-1)}
+	hi := Vec2{X: math.Inf(1), Y: math.Inf(1)}
+	for i, c := range centers {
+		lo.X = math.Max(lo.X, c.X-radii[i])
+		lo.Y = math.Max(lo.Y, c.Y-radii[i])
+		hi.X = math.Min(hi.X, c.X+radii[i])
+		hi.Y = math.Min(hi.Y, c.Y+radii[i])
+	}
+	if lo.X > hi.X || lo.Y > hi.Y {
+		return Vec2{}, false
+	}
+	stepX := (hi.X - lo.X) / centroidGridSize
+	stepY := (hi.Y - lo.Y) / centroidGridSize
+	var sum Vec2
+	count := 0
+	for ix := 0; ix < centroidGridSize; ix++ {
+		for iy := 0; iy < centroidGridSize; iy++ {
+			p := Vec2{X: lo.X + (float64(ix)+0.5)*stepX, Y: lo.Y + (float64(iy)+0.5)*stepY}
+			if minMargin(p, centers, radii) >= 0 {
+				sum.X += p.X
+				sum.Y += p.Y
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return Vec2{}, false
+	}
+	return Vec2{X: sum.X / float64(count), Y: sum.Y / float64(count)}, true
+}
+
+// minMargin returns the smallest margin r_i - |p - c_i| of p inside any circle, negative if p
+// lies outside one.
+func minMargin(p Vec2, centers []Vec2, radii []float64) float64 {
+	margin := math.Inf(1)
+	for i, c := range centers {
+		margin = math.Min(margin, radii[i]-Distance2D(p, c))
+	}
+	return margin
+}
+
+// RefineIntersectionPoint moves p towards the deepest point of the circles' common region, the one
+// maximising its smallest margin r_i - |p - c_i|, by projected subgradient ascent: each step moves
+// towards the center of the circle p is least inside, by a step shrinking as 1/sqrt(k) from half
+// the smallest radius. The analytic solver can return a point on, or within tolerance outside, a
+// circle boundary; when the common region has an interior, refinement places the point strictly
+// inside every circle. It returns the point with the largest margin seen, never worse than p.
+func RefineIntersectionPoint(centers []Vec2, radii []float64, p Vec2, iterations int) Vec2 {
+	if len(centers) == 0 || len(centers) != len(radii) {
+		return p
+	}
+	initialStep := math.Inf(1)
+	for _, r := range radii {
+		initialStep = math.Min(initialStep, r/2)
+	}
+	best, bestMargin := p, minMargin(p, centers, radii)
+	for k := 0; k < iterations; k++ {
+		worst, worstMargin := 0, math.Inf(1)
+		for i, c := range centers {
+			if m := radii[i] - Distance2D(p, c); m < worstMargin {
+				worst, worstMargin = i, m
+			}
+		}
+		d := Distance2D(p, centers[worst])
+		if d == 0 {
+			break // p is the center of its tightest circle, so no other circle is tighter
+		}
+		step := math.Min(initialStep/math.Sqrt(float64(k+1)), d)
+		p = Vec2{
+			X: p.X + step*(centers[worst].X-p.X)/d,
+			Y: p.Y + step*(centers[worst].Y-p.Y)/d,
+		}
+		if m := minMargin(p, centers, radii); m > bestMargin {
+			best, bestMargin = p, m
+		}
+	}
+	return best
+}
+
+// maxSampledGridPoints bounds the work done by SampledIntersectionPoint; coarser steps are used beyond it.
+const maxSampledGridPoints = 1 << 20
+
+// PointInAtLeastK checks if there exists a point contained in at least k of the circles, so that
+// fusion can proceed when some sensors disagree with the rest. The deepest region of overlapping
+// circles has a pairwise intersection on its boundary or contains a whole circle, so every circle
+// center and pairwise intersection is counted, and the circles containing the best of them are
+// passed to AllCirclesIntersectAtPoint for a representative point of their common region.
+// Returns (true, p) if such a point exists, else (false, zero); k <= 1 only requires a circle.
+func PointInAtLeastK(centers []Vec2, radii []float64, k int) (bool, Vec2) {
+	return DefaultGeometryConfig().PointInAtLeastK(centers, radii, k)
+}
+
+// PointInAtLeastK is the package-level PointInAtLeastK using g's tolerances.
+func (g GeometryConfig) PointInAtLeastK(centers []Vec2, radii []float64, k int) (bool, Vec2) {
+	n := len(centers)
+	if n == 0 || k > n {
+		return false, Vec2{}
+	}
+
+	points := append([]Vec2(nil), centers...)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			count, p1, p2 := g.intersectTwoCircles(centers[i], radii[i], centers[j], radii[j])
+			if count >= 1 {
+				points = append(points, p1)
+			}
+			if count == 2 {
+				points = append(points, p2)
+			}
 		}
 	}
-	return false
+
+	var best []int
+	for _, p := range points {
+		var inside []int
+		for i, c := range centers {
+			if Distance2D(p, c) <= radii[i]+g.tol(magnitude(p.X, p.Y, c.X, c.Y, radii[i])) {
+				inside = append(inside, i)
+			}
+		}
+		if len(inside) > len(best) {
+			best = inside
+		}
+	}
+	if len(best) < k {
+		return false, Vec2{}
+	}
+
+	subCenters := make([]Vec2, len(best))
+	subRadii := make([]float64, len(best))
+	for m, i := range best {
+		subCenters[m], subRadii[m] = centers[i], radii[i]
+	}
+	return g.AllCirclesIntersectAtPoint(subCenters, subRadii)
+}
+
+// SampledIntersectionPoint searches a grid of spacing gridStep over the bounding box of the
+// circles' common region for the deepest point, the one maximising its smallest margin
+// r_i - |p - c_i| inside any circle. It is a slow but robust reference for the analytic
+// AllCirclesIntersectAtPoint. A point is reported if its margin is at least minus half a grid
+// diagonal, so tangencies are found, and near-misses within the grid resolution are accepted.
+// The step is enlarged if the grid would exceed maxSampledGridPoints.
+func SampledIntersectionPoint(centers []Vec2, radii []float64, gridStep float64) (bool, Vec2) {
+	if len(centers) == 0 || len(centers) != len(radii) || gridStep <= 0 {
+		return false, Vec2{}
+	}
+	lo := Vec2{X: math.Inf(-1), Y: math.Inf(-1)}
+	hi := Vec2{X: math.Inf(1), Y: math.Inf(1)}
+	for i, c := range centers {
+		lo.X = math.Max(lo.X, c.X-radii[i])
+		lo.Y = math.Max(lo.Y, c.Y-radii[i])
+		hi.X = math.Min(hi.X, c.X+radii[i])
+		hi.Y = math.Min(hi.Y, c.Y+radii[i])
+	}
+	slack := gridStep * math.Sqrt2 / 2
+	if lo.X > hi.X+slack || lo.Y > hi.Y+slack {
+		return false, Vec2{}
+	}
+	hi.X, hi.Y = math.Max(hi.X, lo.X), math.Max(hi.Y, lo.Y)
+
+	nx := int(math.Ceil((hi.X-lo.X)/gridStep)) + 1
+	ny := int(math.Ceil((hi.Y-lo.Y)/gridStep)) + 1
+	if total := float64(nx) * float64(ny); total > maxSampledGridPoints {
+		gridStep *= math.Sqrt(total / maxSampledGridPoints)
+		slack = gridStep * math.Sqrt2 / 2
+		nx = int(math.Ceil((hi.X-lo.X)/gridStep)) + 1
+		ny = int(math.Ceil((hi.Y-lo.Y)/gridStep)) + 1
+	}
+
+	best, bestMargin := Vec2{}, math.Inf(-1)
+	for ix := 0; ix < nx; ix++ {
+		for iy := 0; iy < ny; iy++ {
+			p := Vec2{X: math.Min(lo.X+float64(ix)*gridStep, hi.X), Y: math.Min(lo.Y+float64(iy)*gridStep, hi.Y)}
+			margin := math.Inf(1)
+			for i, c := range centers {
+				margin = math.Min(margin, radii[i]-Distance2D(p, c))
+			}
+			if margin > bestMargin {
+				best, bestMargin = p, margin
+			}
+		}
+	}
+	if bestMargin < -slack {
+		return false, Vec2{}
+	}
+	return true, best
+}
+
+// parallelPairThreshold is the circle count from which pairwise intersections are computed in parallel.
+const parallelPairThreshold = 16
+
+// candidate is a pairwise intersection point weighted by how tightly its circles constrain it.
+type candidate struct {
+	p Vec2
+	w float64 // sum of the inverse radii of the two generating circles
+}
+
+// appendPairCandidates appends the intersections of circle i with every later circle
+// that lie inside all circles, passing every intersection to visit if it is not nil.
+func (g GeometryConfig) appendPairCandidates(dst []candidate, centers []Vec2, radii []float64, i int, visit func(Vec2, bool)) []candidate {
+	for j := i + 1; j < len(centers); j++ {
+		count, p1, p2 := g.intersectTwoCircles(centers[i], radii[i], centers[j], radii[j])
+		w := inverseRadius(radii[i]) + inverseRadius(radii[j])
+		points := [2]Vec2{p1, p2}
+		for _, p := range points[:count] {
+			inside := g.isInsideAll(p, centers, radii)
+			if visit != nil {
+				visit(p, inside)
+			}
+			if inside {
+				dst = append(dst, candidate{p: p, w: w})
+			}
+		}
+	}
+	return dst
+}
+
+// inverseRadius returns 1/r, treating radii below epsilon as epsilon.
+func inverseRadius(r float64) float64 {
+	return 1 / math.Max(r, epsilon)
+}
+
+// weightedCentroid returns the weighted mean of the candidate points, so that points
+// produced by tighter circles dominate.
+func weightedCentroid(candidates []candidate) Vec2 {
+	var centroid Vec2
+	var total float64
+	for _, c := range candidates {
+		centroid.X += c.w * c.p.X
+		centroid.Y += c.w * c.p.Y
+		total += c.w
+	}
+	centroid.X /= total
+	centroid.Y /= total
+	return centroid
+}
+
+// pairCandidatesParallel computes the same candidates as the serial pair loop, spreading rows
+// of the pair enumeration across workers. Each row's results are kept separately and
+// concatenated in row order, so the output order is deterministic and identical to the serial loop.
+func (g GeometryConfig) pairCandidatesParallel(centers []Vec2, radii []float64) []candidate {
+	n := len(centers)
+	rows := make([][]candidate, n)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Interleave rows so the triangular workload is balanced.
+			for i := w; i < n; i += workers {
+				rows[i] = g.appendPairCandidates(nil, centers, radii, i, nil)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var candidates []candidate
+	for _, row := range rows {
+		candidates = append(candidates, row...)
+	}
+	return candidates
+}
+
+// candidatePool recycles the pairwise candidate slices of the serial intersection search, which
+// would otherwise allocate on every fused frame.
+var candidatePool = sync.Pool{New: func() interface{} { return new([]candidate) }}
+
+// getCandidates takes an empty candidate slice from candidatePool.
+func getCandidates() *[]candidate {
+	return candidatePool.Get().(*[]candidate)
+}
+
+// putCandidates returns buf to candidatePool, keeping the storage of candidates, which was grown
+// from it.
+func putCandidates(buf *[]candidate, candidates []candidate) {
+	*buf = candidates[:0]
+	candidatePool.Put(buf)
+}
+
+// dedupCandidates returns candidates in order, merging any within tol of one already kept.
+// A merged point keeps the largest weight among its duplicates. It works in place, overwriting
+// candidates with the kept points.
+func dedupCandidates(candidates []candidate, tol float64) []candidate {
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if i := indexOfVec2(kept, c.p, tol); i >= 0 {
+			kept[i].w = math.Max(kept[i].w, c.w)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// indexOfVec2 returns the index of the first candidate within tol of p, or -1.
+func indexOfVec2(candidates []candidate, p Vec2, tol float64) int {
+	for i, c := range candidates {
+		if Distance2D(p, c.p) <= tol {
+			return i
+		}
+	}
+	return -1
+}
+
+// Bounds and tolerance for the alpha expansion search.
+const (
+	alphaLowerBound = 1.0
+	alphaUpperBound = 10.0
+	alphaTolerance  = 1e-4
+)
+
+// alphaSearch evaluates whether a set of circles, expanded by a common factor, share a point.
+type alphaSearch struct {
+	geometry GeometryConfig
+	centers  []Vec2
+	radii    []float64
+	expanded []float64
+	evals    int  // number of AllCirclesIntersectAtPoint calls made
+	found    bool // whether any evaluated alpha was feasible
+
+	record bool         // whether to record probes
+	probes []AlphaProbe // evaluated alphas in order, if record is set
+}
+
+// AlphaProbe is one evaluation of the alpha search: whether the circles expanded by Alpha share
+// a point. See FusionTracker.SetRecordProbes.
+type AlphaProbe struct {
+	Alpha      float64
+	Intersects bool
+}
+
+func newAlphaSearch(positions []Position, geometry GeometryConfig) *alphaSearch {
+	s := &alphaSearch{
+		geometry: geometry,
+		centers:  make([]Vec2, len(positions)),
+		radii:    make([]float64, len(positions)),
+		expanded: make([]float64, len(positions)),
+	}
+	for i, pos := range positions {
+		s.centers[i] = Vec2{X: pos.X, Y: pos.Y}
+		s.radii[i] = pos.EigenRadius()
+	}
+	return s
+}
+
+// feasible reports whether the circles expanded by alpha intersect, and where.
+func (s *alphaSearch) feasible(alpha float64) (bool, Vec2) {
+	s.evals++
+	for i := range s.radii {
+		s.expanded[i] = alpha * s.radii[i]
+	}
+	ok, p := s.geometry.AllCirclesIntersectAtPoint(s.centers, s.expanded)
+	s.found = s.found || ok
+	if s.record {
+		s.probes = append(s.probes, AlphaProbe{Alpha: alpha, Intersects: ok})
+	}
+	return ok, p
+}
+
+// resolve returns fused, or the point chosen by the geometry's NonOverlapPolicy if no evaluated
+// alpha was feasible.
+func (s *alphaSearch) resolve(fused Vec2) Vec2 {
+	if s.found || s.geometry.NonOverlap != NonOverlapNearestWeighted || len(s.centers) == 0 {
+		return fused
+	}
+	candidates := make([]candidate, len(s.centers))
+	for i, c := range s.centers {
+		candidates[i] = candidate{p: c, w: inverseRadius(s.radii[i])}
+	}
+	return weightedCentroid(candidates)
+}
+
+// bisect narrows [lo, hi] down to alphaTolerance, where fused is the last known feasible point.
+func (s *alphaSearch) bisect(lo, hi float64, fused Vec2) (float64, Vec2) {
+	for hi-lo > alphaTolerance {
+		alpha := 0.5 * (lo + hi)
+		if ok, p := s.feasible(alpha); ok {
+			hi = alpha
+			fused = p
+		} else {
+			lo = alpha
+		}
+	}
+	return hi, fused
 }
 
 // GeometricFusion2D finds the minimal alpha >= 1 such that all expanded circles intersect at some point.
 // Returns (alpha, fused position).
 func GeometricFusion2D(positions []Position) (float64, Position) {
-	centers := make([]Vec2, len(positions))
-	radii := make([]float64, len(positions))
-	for i, pos := range positions {
-		centers[i] = Vec2{X: pos.X, Y: pos.Y}
-		radii[i] = pos.R
+	return DefaultGeometryConfig().GeometricFusion2D(positions)
+}
+
+// GeometricFusion2D is the package-level GeometricFusion2D using g's tolerances.
+func (g GeometryConfig) GeometricFusion2D(positions []Position) (float64, Position) {
+	s := newAlphaSearch(positions, g)
+	alpha, fused := s.bisect(alphaLowerBound, alphaUpperBound, Vec2{})
+	fused = s.resolve(fused)
+	return alpha, Position{X: fused.X, Y: fused.Y, R: alpha}
+}
+
+// FusionResult is the full outcome of a geometric fusion, for callers that chain further
+// geometry (such as a Procrustes alignment) onto the fused circles without recomputing them.
+type FusionResult struct {
+	Alpha    float64   // minimal expansion factor
+	Position Position  // fused position, with R set to Alpha
+	Centers  []Vec2    // circle centers
+	Radii    []float64 // circle radii expanded by Alpha
+
+	// Intersections are the distinct pairwise boundary intersections of the expanded
+	// circles that lie inside all of them. It is empty when the fused position comes
+	// from a contained center or an area overlap rather than boundary crossings.
+	Intersections []Vec2
+}
+
+// GeometricFusion2DResult is GeometricFusion2D returning the full FusionResult.
+func GeometricFusion2DResult(positions []Position) FusionResult {
+	return DefaultGeometryConfig().GeometricFusion2DResult(positions)
+}
+
+// GeometricFusion2DResult is the package-level GeometricFusion2DResult using g's tolerances.
+func (g GeometryConfig) GeometricFusion2DResult(positions []Position) FusionResult {
+	s := newAlphaSearch(positions, g)
+	alpha, fused := s.bisect(alphaLowerBound, alphaUpperBound, Vec2{})
+	return s.result(alpha, s.resolve(fused))
+}
+
+// result collects the expanded geometry at alpha into a FusionResult.
+func (s *alphaSearch) result(alpha float64, fused Vec2) FusionResult {
+	r := FusionResult{
+		Alpha:    alpha,
+		Position: Position{X: fused.X, Y: fused.Y, R: alpha},
+		Centers:  append([]Vec2(nil), s.centers...),
+		Radii:    make([]float64, len(s.radii)),
+	}
+	for i, radius := range s.radii {
+		r.Radii[i] = alpha * radius
 	}
-	alphaMin, alphaMax := 1.0, 10.0
+	var candidates []candidate
+	for i := range r.Centers {
+		candidates = s.geometry.appendPairCandidates(candidates, r.Centers, r.Radii, i, nil)
+	}
+	for _, c := range dedupCandidates(candidates, s.geometry.DedupTol) {
+		r.Intersections = append(r.Intersections, c.p)
+	}
+	return r
+}
+
+// FusionResidual returns the largest distance from the fused point to the boundary of any circle
+// expanded by the fused alpha, fused.R. It measures how tight the fusion is: a clean intersection
+// lies on every boundary and has a residual near zero, while a point deep inside some circles
+// and far outside none, or a fallback position outside them, has a large one. A residual that
+// stays large across frames suggests a miscalibrated array.
+func FusionResidual(positions []Position, fused Position) float64 {
+	p := Vec2{X: fused.X, Y: fused.Y}
+	var residual float64
+	for _, pos := range positions {
+		d := math.Abs(fused.R*pos.R - Distance2D(p, Vec2{X: pos.X, Y: pos.Y}))
+		residual = math.Max(residual, d)
+	}
+	return residual
+}
+
+// ConfidenceWeights are the relative weights of the terms of FusionConfidence.
+type ConfidenceWeights struct {
+	Agreement float64 // weight of the fraction of IMUs that took part in the fusion
+	Alpha     float64 // weight of 1/alpha, how little the circles had to grow to meet
+	Residual  float64 // weight of how tightly the fused point sits on the circle boundaries
+}
+
+// DefaultConfidenceWeights weighs the three terms of FusionConfidence equally.
+func DefaultConfidenceWeights() ConfidenceWeights {
+	return ConfidenceWeights{Agreement: 1, Alpha: 1, Residual: 1}
+}
+
+// FusionConfidence scores a fused frame in [0, 1] for gating downstream use of the estimate.
+// It is the weighted geometric mean of three terms, each in [0, 1], so that any one poor term
+// lowers the score:
+//
+//	agreement = agreeing / total, the fraction of the IMUs that took part in the fusion
+//	alpha     = 1 / alpha, which is 1 when the circles intersect without expansion
+//	residual  = 1 / (1 + residual / (alpha * radius)), the FusionResidual relative to the
+//	            mean expanded radius, where radius is the mean uncertainty radius
+//
+// That is, exp(sum(w_i * ln(term_i)) / sum(w_i)); a term with zero weight is ignored. Weights
+// must be non-negative; it returns 0 if they sum to zero or total is not positive.
+func FusionConfidence(agreeing, total int, alpha, residual, radius float64, w ConfidenceWeights) float64 {
+	sum := w.Agreement + w.Alpha + w.Residual
+	if total <= 0 || sum <= 0 {
+		return 0
+	}
+	agreement := math.Min(math.Max(float64(agreeing)/float64(total), 0), 1)
+	alpha = math.Max(alpha, 1)
+	tightness := 1.0
+	if scale := alpha * radius; scale > epsilon {
+		tightness = 1 / (1 + residual/scale)
+	}
+	var logSum float64
+	for _, term := range [3][2]float64{{w.Agreement, agreement}, {w.Alpha, 1 / alpha}, {w.Residual, tightness}} {
+		if term[0] > 0 {
+			logSum += term[0] * math.Log(term[1])
+		}
+	}
+	return math.Exp(logSum / sum)
+}
+
+// WeightedFusion2D fuses positions by inverse-covariance weighting, so each position pulls the
+// result most along the direction in which it is most certain. Positions without Cov weigh
+// 1/R^2 in every direction, radii below epsilon counting as epsilon. The fused Cov is the
+// inverse of the summed weights, and its R is the EigenRadius of that covariance. It returns
+// the zero Position if there are no positions or a covariance is not positive definite.
+func WeightedFusion2D(positions []Position) Position {
+	var info [2][2]float64
+	var weighted Vec2
+	for _, p := range positions {
+		c := p.Covariance()
+		if p.Cov == ([2][2]float64{}) {
+			r := math.Max(p.R, epsilon)
+			c = [2][2]float64{{r * r, 0}, {0, r * r}}
+		}
+		w, ok := invert2x2(c)
+		if !ok {
+			return Position{}
+		}
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				info[i][j] += w[i][j]
+			}
+		}
+		weighted.X += w[0][0]*p.X + w[0][1]*p.Y
+		weighted.Y += w[1][0]*p.X + w[1][1]*p.Y
+	}
+	cov, ok := invert2x2(info)
+	if len(positions) == 0 || !ok {
+		return Position{}
+	}
+	fused := Position{
+		X:   cov[0][0]*weighted.X + cov[0][1]*weighted.Y,
+		Y:   cov[1][0]*weighted.X + cov[1][1]*weighted.Y,
+		Cov: cov,
+	}
+	fused.R = fused.EigenRadius()
+	return fused
+}
+
+// invert2x2 inverts a symmetric positive definite 2x2 matrix, reporting false if it is not.
+func invert2x2(m [2][2]float64) ([2][2]float64, bool) {
+	det := m[0][0]*m[1][1] - m[0][1]*m[1][0]
+	if m[0][0] <= 0 || det <= 0 {
+		return [2][2]float64{}, false
+	}
+	return [2][2]float64{{m[1][1] / det, -m[0][1] / det}, {-m[1][0] / det, m[0][0] / det}}, true
+}
+
+// FusionMode selects what FusionTracker reports when the circles share no point even at the
+// largest expansion alphaUpperBound.
+type FusionMode int
+
+const (
+	// FusionStrict reports what GeometricFusion2D does, as set by the NonOverlapPolicy of the
+	// tracker's GeometryConfig.
+	FusionStrict FusionMode = iota
+	// FusionMedianFallback reports the inverse-radius weighted GeometricMedian of the centers,
+	// a best-effort position that degrades gracefully as the IMUs disagree.
+	FusionMedianFallback
+)
+
+// Reasons a frame's circles share no point, as passed to the OnFusionFailure callback.
+const (
+	// FusionFailureDisjoint means some pair of circles does not meet even at the largest alpha.
+	FusionFailureDisjoint = "Disjoint"
+	// FusionFailureAlphaExceeded means every pair of circles meets at the largest alpha, but not
+	// all of them at one point.
+	FusionFailureAlphaExceeded = "AlphaExceeded"
+	// FusionFailureDegenerate means a circle has a radius that is not positive and finite, or
+	// the fusion produced a non-finite position.
+	FusionFailureDegenerate = "Degenerate"
+)
+
+// fusionFailure classifies why positions share no point at any alpha up to maxAlpha.
+func fusionFailure(positions []Position, maxAlpha float64) string {
+	for _, p := range positions {
+		if r := p.EigenRadius(); r <= 0 || math.IsNaN(r) || math.IsInf(r, 0) {
+			return FusionFailureDegenerate
+		}
+	}
+	for i := range positions {
+		for j := i + 1; j < len(positions); j++ {
+			a, b := positions[i], positions[j]
+			d := Distance2D(Vec2{X: a.X, Y: a.Y}, Vec2{X: b.X, Y: b.Y})
+			if d > maxAlpha*(a.EigenRadius()+b.EigenRadius()) {
+				return FusionFailureDisjoint
+			}
+		}
+	}
+	return FusionFailureAlphaExceeded
+}
+
+// FusionTracker performs GeometricFusion2D across consecutive frames.
+// Since alpha changes slowly between frames, each search is seeded with the previous alpha
+// and only a small bracket around it is bisected.
+type FusionTracker struct {
+	geometry  GeometryConfig
+	mode      FusionMode
+	minAlpha  float64 // expansion search bounds, see SetAlphaBounds
+	maxAlpha  float64
+	lastAlpha float64 // alpha from the previous frame, 0 if none
+	lastEvals int     // AllCirclesIntersectAtPoint calls made by the last Fuse
+	lastFound bool    // whether the last Fuse found a common point
+
+	recordProbes bool         // see SetRecordProbes
+	probes       []AlphaProbe // probes of the last Fuse, if recordProbes is set
+}
+
+// NewFusionTracker creates a FusionTracker with no alpha history, searching the alpha range of
+// GeometricFusion2D.
+func NewFusionTracker() *FusionTracker {
+	return &FusionTracker{geometry: DefaultGeometryConfig(), minAlpha: alphaLowerBound, maxAlpha: alphaUpperBound}
+}
+
+// SetAlphaBounds sets the range of expansion factors searched by subsequent calls to Fuse,
+// 1 to 10 by default. Circles that share no point at hi are reported at hi, as selected by the
+// FusionMode and the NonOverlapPolicy.
+func (ft *FusionTracker) SetAlphaBounds(lo, hi float64) error {
+	if lo <= 0 || hi <= lo || math.IsInf(hi, 0) || math.IsNaN(lo) || math.IsNaN(hi) {
+		return fmt.Errorf("alpha bounds must satisfy 0 < lo < hi and be finite, got [%f, %f]", lo, hi)
+	}
+	ft.minAlpha, ft.maxAlpha = lo, hi
+	ft.lastAlpha = 0
+	return nil
+}
+
+// SetGeometryConfig sets the tolerances used by subsequent calls to Fuse.
+func (ft *FusionTracker) SetGeometryConfig(g GeometryConfig) {
+	ft.geometry = g
+}
+
+// SetMode sets the behaviour of subsequent calls to Fuse when no intersection is found.
+func (ft *FusionTracker) SetMode(mode FusionMode) {
+	ft.mode = mode
+}
+
+// SetRecordProbes enables recording the alphas evaluated by each Fuse, for plotting how the
+// search converges; see Probes. It is a debugging aid and off by default.
+func (ft *FusionTracker) SetRecordProbes(enabled bool) {
+	ft.recordProbes = enabled
+	ft.probes = nil
+}
+
+// Probes returns the alphas evaluated by the last Fuse in evaluation order, with whether the
+// circles intersected at each, or nil unless SetRecordProbes is enabled. A first Fuse bisects
+// the range set by SetAlphaBounds; later ones walk from the previous alpha until the boundary
+// is bracketed, then bisect. Either way every intersecting probe is below the earlier
+// intersecting ones and every non-intersecting probe above the earlier non-intersecting ones,
+// and the alpha returned is the last intersecting probe, unless the search ended at a bound.
+func (ft *FusionTracker) Probes() []AlphaProbe {
+	if !ft.recordProbes {
+		return nil
+	}
+	return append([]AlphaProbe(nil), ft.probes...)
+}
+
+// Fuse returns the same result as GeometricFusion2D, using the previous alpha as a starting point.
+func (ft *FusionTracker) Fuse(positions []Position) (float64, Position) {
+	s := newAlphaSearch(positions, ft.geometry)
+	if ft.recordProbes {
+		s.record, s.probes = true, ft.probes[:0]
+	}
+	var alpha float64
 	var fused Vec2
-	for alphaMax-alphaMin > 1e-4 {
-		alpha := 0.5 * (alphaMin + alphaMax)
-		expanded := make([]float64, len(radii))
-		for i := range radii {
-			expanded[i] = alpha * radii[i]
-		}
-		ok, p := AllCirclesIntersectAtPoint(centers, expanded)
-		if ok {
-			alphaMax = alpha
-			fused = p
-		} else {
-			alphaMin = alpha
+	if ft.lastAlpha == 0 {
+		alpha, fused = s.bisect(ft.minAlpha, ft.maxAlpha, Vec2{})
+	} else {
+		alpha, fused = ft.bracket(s)
+	}
+	if !s.found && ft.mode == FusionMedianFallback {
+		fused = GeometricMedian(s.centers, s.radii)
+	} else {
+		fused = s.resolve(fused)
+	}
+	ft.lastAlpha = alpha
+	ft.lastEvals = s.evals
+	ft.lastFound = s.found
+	if ft.recordProbes {
+		ft.probes = s.probes
+	}
+	return alpha, Position{X: fused.X, Y: fused.Y, R: alpha}
+}
+
+// Reset discards the alpha history so the next Fuse performs a full search.
+func (ft *FusionTracker) Reset() {
+	ft.lastAlpha = 0
+}
+
+// bracket expands a window around the previous alpha until it contains the
+// feasibility boundary, then bisects within it.
+func (ft *FusionTracker) bracket(s *alphaSearch) (float64, Vec2) {
+	seed := math.Min(math.Max(ft.lastAlpha, ft.minAlpha), ft.maxAlpha)
+	step := math.Max(0.01*seed, 10*alphaTolerance)
+
+	ok, fused := s.feasible(seed)
+	if ok {
+		// Walk downwards until infeasible or the lower bound is reached.
+		hi := seed
+		for hi > ft.minAlpha {
+			lo := math.Max(ft.minAlpha, hi-step)
+			okLo, p := s.feasible(lo)
+			if !okLo {
+				return s.bisect(lo, hi, fused)
+			}
+			hi, fused = lo, p
+			step *= 2
+		}
+		return hi, fused
+	}
+
+	// Walk upwards until feasible or the upper bound is reached.
+	lo := seed
+	for lo < ft.maxAlpha {
+		hi := math.Min(ft.maxAlpha, lo+step)
+		if okHi, p := s.feasible(hi); okHi {
+			return s.bisect(lo, hi, p)
+		}
+		lo = hi
+		step *= 2
+	}
+	return ft.maxAlpha, Vec2{}
+}
+
+// GatePositions drops positions whose squared Mahalanobis distance from estimate exceeds chi2.
+// Each position's uncertainty radius is used as its standard deviation along both axes, so the
+// distance is compared against a chi-square threshold with two degrees of freedom.
+// Positions with no uncertainty cannot be scored and are always kept. If every position is
+// gated out there is no consensus to defend, and the input is returned unchanged.
+func GatePositions(positions []Position, estimate Vec2, chi2 float64) []Position {
+	return maskPositions(positions, gateMask(positions, estimate, chi2))
+}
+
+// maskPositions returns the positions whose entry in keep is true.
+func maskPositions(positions []Position, keep []bool) []Position {
+	kept := make([]Position, 0, len(positions))
+	for i, pos := range positions {
+		if keep[i] {
+			kept = append(kept, pos)
 		}
 	}
-	return alphaMax, Position{X: fused.X, Y: fused.Y, R: alphaMax}
+	return kept
+}
+
+// gateMask reports which positions GatePositions keeps.
+func gateMask(positions []Position, estimate Vec2, chi2 float64) []bool {
+	keep := make([]bool, len(positions))
+	kept := 0
+	for i, pos := range positions {
+		if pos.R > epsilon {
+			d2 := (pos.X-estimate.X)*(pos.X-estimate.X) + (pos.Y-estimate.Y)*(pos.Y-estimate.Y)
+			if d2/(pos.R*pos.R) > chi2 {
+				continue
+			}
+		}
+		keep[i] = true
+		kept++
+	}
+	if kept == 0 {
+		for i := range keep {
+			keep[i] = true
+		}
+	}
+	return keep
 }
 
 // CircleIntersection checks if two circles intersect.
@@ -189,3 +1179,48 @@ func CircleIntersection(p1, r1, p2, r2 float64) bool {
 	radiusSum := r1 + r2
 	return distanceSquared <= radiusSum*radiusSum
 }
+
+// Weiszfeld iteration limits for GeometricMedian.
+const (
+	medianMaxIterations = 100
+	medianTolerance     = 1e-9
+)
+
+// GeometricMedian returns the point minimising the sum of distances to centers, each weighted
+// by the inverse of its radius so that tighter circles pull harder. It is computed by Weiszfeld
+// iteration starting from the weighted centroid, stopping early if an iterate lands on a center.
+func GeometricMedian(centers []Vec2, radii []float64) Vec2 {
+	if len(centers) == 0 {
+		return Vec2{}
+	}
+	weights := make([]float64, len(centers))
+	candidates := make([]candidate, len(centers))
+	for i, c := range centers {
+		weights[i] = inverseRadius(radii[i])
+		candidates[i] = candidate{p: c, w: weights[i]}
+	}
+	median := weightedCentroid(candidates)
+
+	for iter := 0; iter < medianMaxIterations; iter++ {
+		var next Vec2
+		var total float64
+		for i, c := range centers {
+			d := Distance2D(median, c)
+			if d < epsilon {
+				return c
+			}
+			w := weights[i] / d
+			next.X += w * c.X
+			next.Y += w * c.Y
+			total += w
+		}
+		next.X /= total
+		next.Y /= total
+		step := Distance2D(next, median)
+		median = next
+		if step < medianTolerance*math.Max(1, magnitude(median.X, median.Y)) {
+			break
+		}
+	}
+	return median
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"math"
+	"math/rand"
+	"reflect"
 	"testing"
 )
 
@@ -13,70 +15,71 @@ import (
 // 	return math.Abs(f1-f2) < tol
 // }
 
-func TestAllCirclesIntersectAtPoint(t *testing.T) {
-	tolerance := 0.1 // Grid search might not be perfectly accurate
-
-	tests := []struct {
-		name      string
-		centers   []Vec2
-		radii     []float64
-		expectOk  bool
-		expectPos Vec2 // Expected position if expectOk is true
-	}{
-		{
-			name: "Simple Intersection",
-			centers: []Vec2{
-				{0, 0},
-				{2, 0},
-			},
-			radii:     []float64{1.1, 1.1},
-			expectOk:  true,
-			expectPos: Vec2{1, 0}, // Intersection is around (1, y)
+// intersectionCases are shared by the analytic and sampled intersection tests.
+var intersectionCases = []struct {
+	name      string
+	centers   []Vec2
+	radii     []float64
+	expectOk  bool
+	expectPos Vec2 // Expected position if expectOk is true
+}{
+	{
+		name: "Simple Intersection",
+		centers: []Vec2{
+			{0, 0},
+			{2, 0},
 		},
-		{
-			name: "No Intersection",
-			centers: []Vec2{
-				{0, 0},
-				{3, 0},
-			},
-			radii:     []float64{1, 1},
-			expectOk:  false,
-			expectPos: Vec2{}, // Position doesn't matter
+		radii:     []float64{1.1, 1.1},
+		expectOk:  true,
+		expectPos: Vec2{1, 0}, // Intersection is around (1, y)
+	},
+	{
+		name: "No Intersection",
+		centers: []Vec2{
+			{0, 0},
+			{3, 0},
 		},
-		{
-			name: "Tangent Circles",
-			centers: []Vec2{
-				{0, 0},
-				{2, 0},
-			},
-			radii:     []float64{1, 1},
-			expectOk:  true,
-			expectPos: Vec2{1, 0}, // Tangent point
+		radii:     []float64{1, 1},
+		expectOk:  false,
+		expectPos: Vec2{}, // Position doesn't matter
+	},
+	{
+		name: "Tangent Circles",
+		centers: []Vec2{
+			{0, 0},
+			{2, 0},
 		},
-		{
-			name: "One Contains Another",
-			centers: []Vec2{
-				{0, 0},
-				{0.5, 0},
-			},
-			radii:     []float64{2, 0.5},
-			expectOk:  true,
-			expectPos: Vec2{0.5, 0}, // Center of smaller circle is a valid point
+		radii:     []float64{1, 1},
+		expectOk:  true,
+		expectPos: Vec2{1, 0}, // Tangent point
+	},
+	{
+		name: "One Contains Another",
+		centers: []Vec2{
+			{0, 0},
+			{0.5, 0},
 		},
-		{
-			name: "Three Circles Intersecting",
-			centers: []Vec2{
-				{0, 0},
-				{2, 0},
-				{1, 1.732}, // Equilateral triangle vertices
-			},
-			radii:     []float64{1.2, 1.2, 1.2},
-			expectOk:  true,
-			expectPos: Vec2{1, 0.577}, // Centroid of the triangle
+		radii:     []float64{2, 0.5},
+		expectOk:  true,
+		expectPos: Vec2{0.5, 0}, // Center of smaller circle is a valid point
+	},
+	{
+		name: "Three Circles Intersecting",
+		centers: []Vec2{
+			{0, 0},
+			{2, 0},
+			{1, 1.732}, // Equilateral triangle vertices
 		},
-	}
+		radii:     []float64{1.2, 1.2, 1.2},
+		expectOk:  true,
+		expectPos: Vec2{1, 0.577}, // Centroid of the triangle
+	},
+}
 
-	for _, tt := range tests {
+func TestAllCirclesIntersectAtPoint(t *testing.T) {
+	tolerance := 0.1 // Grid search might not be perfectly accurate
+
+	for _, tt := range intersectionCases {
 		t.Run(tt.name, func(t *testing.T) {
 			ok, pos := AllCirclesIntersectAtPoint(tt.centers, tt.radii)
 			if ok != tt.expectOk {
@@ -92,6 +95,192 @@ func TestAllCirclesIntersectAtPoint(t *testing.T) {
 	}
 }
 
+func TestAllCirclesIntersectAtPointStrategies(t *testing.T) {
+	// A lens between a circle of radius 2 and one of radius 1: its chord is at x = 1.85, the
+	// deepest point is midway between the boundaries at x = 1.75, and its area centroid, weighted
+	// towards the larger cap left of the chord, is at x ~= 1.767.
+	centers := []Vec2{{X: 0, Y: 0}, {X: 2.5, Y: 0}}
+	radii := []float64{2, 1}
+	chordHalf := math.Sqrt(4 - 1.85*1.85)
+	tests := []struct {
+		name     string
+		strategy PointStrategy
+		want     Vec2
+		tol      float64
+	}{
+		{name: "Default", strategy: StrategyDefault, want: Vec2{X: 1.85, Y: 0}, tol: 1e-9},
+		{name: "Deepest", strategy: StrategyDeepest, want: Vec2{X: 1.75, Y: 0}, tol: 1e-3},
+		{name: "Centroid", strategy: StrategyCentroid, want: Vec2{X: 1.767, Y: 0}, tol: 1e-3},
+		{name: "First", strategy: StrategyFirst, want: Vec2{X: 1.85, Y: -chordHalf}, tol: 1e-9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := DefaultGeometryConfig()
+			g.Strategy = tt.strategy
+			ok, p := g.AllCirclesIntersectAtPoint(centers, radii)
+			if !ok {
+				t.Fatalf("Expected an intersection")
+			}
+			if Distance2D(p, tt.want) > tt.tol {
+				t.Errorf("Expected %v, got %v", tt.want, p)
+			}
+		})
+	}
+}
+
+func TestIntersectionPolygonLens(t *testing.T) {
+	centers := []Vec2{{X: 0, Y: 0}, {X: 1, Y: 0}}
+	radii := []float64{1, 1}
+	polygon := IntersectionPolygon(centers, radii, 128)
+	if len(polygon) < 4 {
+		t.Fatalf("Expected a polygon, got %d vertices", len(polygon))
+	}
+	var area float64
+	tips := 0
+	for i, v := range polygon {
+		if !isInsideAll(v, centers, radii) {
+			t.Errorf("Expected vertex %v inside both circles", v)
+		}
+		if m := minMargin(v, centers, radii); math.Abs(m) > 1e-9 {
+			t.Errorf("Expected vertex %v on the lens boundary, got margin %g", v, m)
+		}
+		if math.Abs(v.X-0.5) < 1e-9 && math.Abs(math.Abs(v.Y)-math.Sqrt(0.75)) < 1e-9 {
+			tips++
+		}
+		next := polygon[(i+1)%len(polygon)]
+		area += v.X*next.Y - next.X*v.Y
+	}
+	area /= 2
+	if tips != 2 {
+		t.Errorf("Expected both lens tips as vertices, got %d", tips)
+	}
+	if want := LensArea(centers[0], radii[0], centers[1], radii[1]); area <= 0 || math.Abs(area-want) > 0.01*want {
+		t.Errorf("Expected a counter-clockwise polygon of area close to %f, got %f", want, area)
+	}
+
+	if polygon := IntersectionPolygon([]Vec2{{X: 0, Y: 0}, {X: 3, Y: 0}}, radii, 128); polygon != nil {
+		t.Errorf("Expected no polygon for disjoint circles, got %v", polygon)
+	}
+}
+
+func TestAllCirclesIntersectAtPointVisit(t *testing.T) {
+	// Circles on the corners of a unit equilateral triangle, slightly larger than its circumradius:
+	// each pair meets twice, and of the six intersections only the three bounding the small
+	// common region lie inside all circles.
+	centers := []Vec2{{X: -0.5, Y: 0}, {X: 0.5, Y: 0}, {X: 0, Y: math.Sqrt(3) / 2}}
+	radii := []float64{0.6, 0.6, 0.6}
+	var visited, inside int
+	ok, p := AllCirclesIntersectAtPointVisit(centers, radii, func(v Vec2, insideAll bool) {
+		visited++
+		if insideAll {
+			inside++
+		}
+	})
+	if visited != 6 || inside != 3 {
+		t.Errorf("Expected 6 candidates with 3 inside all circles, got %d with %d inside", visited, inside)
+	}
+	wantOk, want := AllCirclesIntersectAtPoint(centers, radii)
+	if ok != wantOk || p != want {
+		t.Errorf("Expected the same result as AllCirclesIntersectAtPoint %v, got %v", want, p)
+	}
+}
+
+func TestPointInAtLeastK(t *testing.T) {
+	// Three circles share a region around the origin; a faulty fourth sensor is far away.
+	centers := []Vec2{{X: -0.5, Y: 0}, {X: 0.5, Y: 0}, {X: 0, Y: 0.5}, {X: 10, Y: 10}}
+	radii := []float64{1, 1, 1, 1}
+
+	if ok, _ := AllCirclesIntersectAtPoint(centers, radii); ok {
+		t.Fatal("Expected the four circles to share no point")
+	}
+	ok, p := PointInAtLeastK(centers, radii, 3)
+	if !ok {
+		t.Fatal("Expected a point in 3 of the 4 circles")
+	}
+	for i := 0; i < 3; i++ {
+		if d := Distance2D(p, centers[i]); d > radii[i]+1e-9 {
+			t.Errorf("Expected %v inside circle %d, at distance %f", p, i, d)
+		}
+	}
+	if d := Distance2D(p, centers[3]); d <= radii[3] {
+		t.Errorf("Expected %v outside the odd circle out", p)
+	}
+
+	if ok, _ := PointInAtLeastK(centers, radii, 4); ok {
+		t.Error("Expected no point in all 4 circles")
+	}
+	// With k equal to the number of circles it agrees with AllCirclesIntersectAtPoint.
+	_, all := AllCirclesIntersectAtPoint(centers[:3], radii[:3])
+	if ok, p := PointInAtLeastK(centers[:3], radii[:3], 3); !ok || Distance2D(p, all) > 1e-9 {
+		t.Errorf("Expected %v, got %v (ok=%v)", all, p, ok)
+	}
+}
+
+func TestWeightedFusion2DEllipticalUncertainty(t *testing.T) {
+	// Without covariances, equal radii give the midpoint.
+	round := []Position{{X: 0, Y: 0, R: 1}, {X: 2, Y: 2, R: 1}}
+	if fused := WeightedFusion2D(round); !floatsClose(fused.X, 1, 1e-9) || !floatsClose(fused.Y, 1, 1e-9) {
+		t.Errorf("Expected the midpoint (1, 1), got %v", fused)
+	}
+
+	// The first position is confident in X and vague in Y, the second the opposite, so the
+	// fused point takes X from the first and Y from the second.
+	elliptical := []Position{
+		{X: 0, Y: 0, Cov: [2][2]float64{{0.01, 0}, {0, 4}}},
+		{X: 2, Y: 2, Cov: [2][2]float64{{4, 0}, {0, 0.01}}},
+	}
+	fused := WeightedFusion2D(elliptical)
+	if math.Abs(fused.X) > 0.01 || math.Abs(fused.Y-2) > 0.01 {
+		t.Errorf("Expected a fused point near (0, 2), got %v", fused)
+	}
+	if fused.Cov[0][0] >= 0.01 || fused.Cov[1][1] >= 0.01 {
+		t.Errorf("Expected fused variances below either input's best, got %v", fused.Cov)
+	}
+
+	if r := elliptical[0].EigenRadius(); !floatsClose(r, 2, 1e-9) {
+		t.Errorf("Expected eigen-radius 2, got %f", r)
+	}
+	// Circle-based fusion sees the ellipses as their enclosing circles, which already overlap.
+	if alpha, _ := GeometricFusion2D(elliptical); !floatsClose(alpha, 1, 1e-3) {
+		t.Errorf("Expected alpha 1 for the enclosing circles, got %f", alpha)
+	}
+	if fused := WeightedFusion2D([]Position{{X: 1, Y: 1, Cov: [2][2]float64{{1, 2}, {2, 1}}}}); fused != (Position{}) {
+		t.Errorf("Expected the zero Position for an indefinite covariance, got %v", fused)
+	}
+}
+
+func TestFusionConfidence(t *testing.T) {
+	w := DefaultConfidenceWeights()
+	score := func(positions []Position, total int) float64 {
+		alpha, fused := GeometricFusion2D(positions)
+		fused.R = alpha
+		var radius float64
+		for _, p := range positions {
+			radius += p.R / float64(len(positions))
+		}
+		return FusionConfidence(len(positions), total, alpha, FusionResidual(positions, fused), radius, w)
+	}
+
+	// Four IMUs whose circles meet tightly at the origin.
+	tight := []Position{{X: 1, Y: 0, R: 1}, {X: -1, Y: 0, R: 1}, {X: 0, Y: 1, R: 1}, {X: 0, Y: -1, R: 1}}
+	if c := score(tight, 4); c < 0.9 || c > 1 {
+		t.Errorf("Expected high confidence for a tight intersection, got %f", c)
+	}
+
+	// Only two of four IMUs remain, and their circles must grow fivefold to meet.
+	pair := []Position{{X: 0, Y: 0, R: 0.5}, {X: 5, Y: 0, R: 0.5}}
+	if c := score(pair, 4); c < 0 || c > 0.5 {
+		t.Errorf("Expected low confidence for a forced expansion of a single pair, got %f", c)
+	}
+
+	if c := FusionConfidence(2, 4, 1, 0, 1, ConfidenceWeights{Agreement: 1}); !floatsClose(c, 0.5, 1e-12) {
+		t.Errorf("Expected the agreement term alone to give 0.5, got %f", c)
+	}
+	if c := FusionConfidence(2, 4, 1, 0, 1, ConfidenceWeights{}); c != 0 {
+		t.Errorf("Expected 0 for zero weights, got %f", c)
+	}
+}
+
 func TestGeometricFusion2D(t *testing.T) {
 	tolerance := 1e-3   // Binary search tolerance
 	posTolerance := 0.1 // Grid search tolerance from AllCirclesIntersectAtPoint
@@ -150,3 +339,680 @@ func TestGeometricFusion2D(t *testing.T) {
 		})
 	}
 }
+
+// driftingPositions returns a frame of three circles whose required alpha changes slowly with step.
+func driftingPositions(step int) []Position {
+	offset := 0.001 * float64(step)
+	return []Position{
+		{X: 0, Y: 0, R: 1.0},
+		{X: 3 + offset, Y: 0, R: 1.0},
+		{X: 1.5, Y: 2.5 + offset, R: 1.0},
+	}
+}
+
+func TestFusionTrackerMatchesGeometricFusion2D(t *testing.T) {
+	tracker := NewFusionTracker()
+	for step := 0; step < 50; step++ {
+		positions := driftingPositions(step)
+		wantAlpha, wantPos := GeometricFusion2D(positions)
+		gotAlpha, gotPos := tracker.Fuse(positions)
+
+		if !floatsClose(gotAlpha, wantAlpha, 1e-3) {
+			t.Fatalf("step %d: expected alpha close to %f, got %f", step, wantAlpha, gotAlpha)
+		}
+		if math.Abs(gotPos.X-wantPos.X) > 0.1 || math.Abs(gotPos.Y-wantPos.Y) > 0.1 {
+			t.Fatalf("step %d: expected fused position close to (%f, %f), got (%f, %f)", step, wantPos.X, wantPos.Y, gotPos.X, gotPos.Y)
+		}
+	}
+}
+
+func TestFusionTrackerHandlesAlphaJumps(t *testing.T) {
+	tracker := NewFusionTracker()
+	tracker.Fuse([]Position{{X: 0, Y: 0, R: 1}, {X: 2, Y: 0, R: 1.1}})
+
+	// Jump to a frame needing a much larger expansion.
+	alpha, _ := tracker.Fuse([]Position{{X: 0, Y: 0, R: 1}, {X: 8, Y: 0, R: 1}})
+	if !floatsClose(alpha, 4.0, 1e-3) {
+		t.Errorf("Expected alpha close to 4.0 after jump up, got %f", alpha)
+	}
+
+	// And back down to one that already intersects.
+	alpha, _ = tracker.Fuse([]Position{{X: 0, Y: 0, R: 1.1}, {X: 2, Y: 0, R: 1.1}})
+	if !floatsClose(alpha, 1.0, 1e-3) {
+		t.Errorf("Expected alpha close to 1.0 after jump down, got %f", alpha)
+	}
+}
+
+func BenchmarkGeometricFusion2D(b *testing.B) {
+	evals := 0
+	for i := 0; i < b.N; i++ {
+		s := newAlphaSearch(driftingPositions(i%100), DefaultGeometryConfig())
+		s.bisect(alphaLowerBound, alphaUpperBound, Vec2{})
+		evals += s.evals
+	}
+	b.ReportMetric(float64(evals)/float64(b.N), "intersects/op")
+}
+
+func BenchmarkFusionTracker(b *testing.B) {
+	tracker := NewFusionTracker()
+	evals := 0
+	for i := 0; i < b.N; i++ {
+		tracker.Fuse(driftingPositions(i % 100))
+		evals += tracker.lastEvals
+	}
+	b.ReportMetric(float64(evals)/float64(b.N), "intersects/op")
+}
+
+func TestGatePositionsRejectsOutlier(t *testing.T) {
+	estimate := Vec2{X: 1, Y: 0}
+	positions := []Position{
+		{X: 0.9, Y: 0.1, R: 0.2},
+		{X: 1.1, Y: -0.1, R: 0.2},
+		{X: 1.0, Y: 0.15, R: 0.2},
+		{X: 6.0, Y: 4.0, R: 0.2}, // glitching IMU
+	}
+
+	inliers := GatePositions(positions, estimate, 9.21)
+	if len(inliers) != 3 {
+		t.Fatalf("Expected 3 inliers, got %d: %v", len(inliers), inliers)
+	}
+	for _, pos := range inliers {
+		if pos.X == 6.0 {
+			t.Fatalf("Expected outlier to be gated out, got %v", inliers)
+		}
+	}
+
+	_, fused := GeometricFusion2D(inliers)
+	if math.Abs(fused.X-1.0) > 0.2 || math.Abs(fused.Y) > 0.2 {
+		t.Errorf("Expected fused position near the inliers at (1, 0), got (%f, %f)", fused.X, fused.Y)
+	}
+}
+
+func TestGatePositionsKeepsAllWhenEverythingIsGated(t *testing.T) {
+	positions := []Position{
+		{X: 10, Y: 10, R: 0.1},
+		{X: 11, Y: 10, R: 0.1},
+	}
+	if got := GatePositions(positions, Vec2{}, 9.21); len(got) != len(positions) {
+		t.Errorf("Expected all %d positions to be kept, got %d", len(positions), len(got))
+	}
+}
+
+func TestBatchDistance2DMatchesDistance2D(t *testing.T) {
+	origin := Vec2{X: 1, Y: -2}
+	points := []Vec2{{0, 0}, {1, -2}, {4, 2}, {-3, 1.5}}
+	out := make([]float64, len(points))
+	BatchDistance2D(origin, points, out)
+	for i, p := range points {
+		if want := Distance2D(origin, p); !floatsClose(out[i], want, 1e-12) {
+			t.Errorf("point %d: expected distance %f, got %f", i, want, out[i])
+		}
+	}
+}
+
+func benchmarkPoints(n int) []Vec2 {
+	points := make([]Vec2, n)
+	for i := range points {
+		points[i] = Vec2{X: float64(i%100) * 0.1, Y: float64(i/100) * 0.1}
+	}
+	return points
+}
+
+func BenchmarkBatchDistance2D(b *testing.B) {
+	points := benchmarkPoints(10000)
+	out := make([]float64, len(points))
+	origin := Vec2{X: 5, Y: 5}
+	for i := 0; i < b.N; i++ {
+		BatchDistance2D(origin, points, out)
+	}
+}
+
+func BenchmarkDistance2DLoop(b *testing.B) {
+	points := benchmarkPoints(10000)
+	out := make([]float64, len(points))
+	origin := Vec2{X: 5, Y: 5}
+	for i := 0; i < b.N; i++ {
+		for j, p := range points {
+			out[j] = Distance2D(origin, p)
+		}
+	}
+}
+
+// ringCircles places n circles on a unit ring so that they share a small common region
+// near the origin, but no center lies inside every circle.
+func ringCircles(n int, jitter float64, rng *rand.Rand) ([]Vec2, []float64) {
+	centers := make([]Vec2, n)
+	radii := make([]float64, n)
+	for i := range centers {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		centers[i] = Vec2{X: math.Cos(angle) + jitter*rng.NormFloat64(), Y: math.Sin(angle) + jitter*rng.NormFloat64()}
+		radii[i] = 1.3 + jitter*rng.Float64()
+	}
+	return centers, radii
+}
+
+func serialPairCandidates(centers []Vec2, radii []float64) []candidate {
+	var candidates []candidate
+	for i := range centers {
+		candidates = DefaultGeometryConfig().appendPairCandidates(candidates, centers, radii, i, nil)
+	}
+	return candidates
+}
+
+func TestPairCandidatesParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for trial := 0; trial < 20; trial++ {
+		centers, radii := ringCircles(32, 0.05, rng)
+		serial := serialPairCandidates(centers, radii)
+		parallel := DefaultGeometryConfig().pairCandidatesParallel(centers, radii)
+		if len(serial) == 0 {
+			t.Fatalf("trial %d: expected some candidates", trial)
+		}
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Fatalf("trial %d: parallel candidates differ from serial:\n%v\n%v", trial, serial, parallel)
+		}
+	}
+}
+
+func BenchmarkPairCandidatesSerial32(b *testing.B) {
+	centers, radii := ringCircles(32, 0, rand.New(rand.NewSource(1)))
+	for i := 0; i < b.N; i++ {
+		serialPairCandidates(centers, radii)
+	}
+}
+
+func BenchmarkPairCandidatesParallel32(b *testing.B) {
+	centers, radii := ringCircles(32, 0, rand.New(rand.NewSource(1)))
+	for i := 0; i < b.N; i++ {
+		DefaultGeometryConfig().pairCandidatesParallel(centers, radii)
+	}
+}
+
+func TestDedupCandidatesAcrossScales(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Vec2
+		tol    float64
+		expect int
+	}{
+		{"Exact Duplicates", []Vec2{{1, 1}, {1, 1}, {1, 1}}, 1e-9, 1},
+		{"Large Coordinates Near Duplicates", []Vec2{{1e6, 1e6}, {1e6 + 1e-4, 1e6}, {1e6, 1e6 - 1e-4}}, 1e-3, 1},
+		{"Large Coordinates Distinct", []Vec2{{1e6, 1e6}, {1e6 + 1, 1e6}}, 1e-3, 2},
+		{"Small Coordinates Distinct", []Vec2{{1e-6, 0}, {2e-6, 0}, {3e-6, 0}}, 1e-9, 3},
+		{"Small Coordinates Near Duplicates", []Vec2{{1e-6, 0}, {1e-6 + 1e-12, 0}}, 1e-9, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := make([]candidate, len(tt.points))
+			for i, p := range tt.points {
+				candidates[i] = candidate{p: p, w: 1}
+			}
+			got := dedupCandidates(candidates, tt.tol)
+			if len(got) != tt.expect {
+				t.Errorf("Expected %d points after dedup, got %d: %v", tt.expect, len(got), got)
+			}
+			if len(got) > 0 && got[0].p != tt.points[0] {
+				t.Errorf("Expected first point %v to be kept, got %v", tt.points[0], got[0].p)
+			}
+		})
+	}
+}
+
+func TestAllCirclesIntersectAtPointDedupTolAtLargeScale(t *testing.T) {
+	// Three circles meeting at a single point far from the origin: their pairwise intersections
+	// differ only by rounding error, and must merge into one point.
+	origin := Vec2{X: 1e6, Y: -1e6}
+	centers := []Vec2{
+		{X: origin.X - 1, Y: origin.Y},
+		{X: origin.X + 1, Y: origin.Y},
+		{X: origin.X, Y: origin.Y + 1},
+	}
+	radii := []float64{1, 1, 1}
+	g := DefaultGeometryConfig()
+	g.DedupTol = 1e-6
+	ok, p := g.AllCirclesIntersectAtPoint(centers, radii)
+	if !ok {
+		t.Fatal("Expected circles to intersect")
+	}
+	if Distance2D(p, origin) > 1e-6 {
+		t.Errorf("Expected intersection at %v, got %v", origin, p)
+	}
+}
+
+func TestAllCirclesIntersectAtPointWeightsTightCircles(t *testing.T) {
+	// Two tight circles overlap in a small lens; a much larger circle clips it.
+	centers := []Vec2{{0, 0}, {1.5, 0}, {0.75, 3}}
+	radii := []float64{1, 1, 3}
+
+	candidates := dedupCandidates(serialPairCandidates(centers, radii), epsilon)
+	if len(candidates) < 2 {
+		t.Fatalf("Expected several candidate points, got %v", candidates)
+	}
+	var unweighted Vec2
+	for _, c := range candidates {
+		unweighted.X += c.p.X / float64(len(candidates))
+		unweighted.Y += c.p.Y / float64(len(candidates))
+	}
+
+	ok, weighted := AllCirclesIntersectAtPoint(centers, radii)
+	if !ok {
+		t.Fatal("Expected circles to intersect")
+	}
+	if !isInsideAll(weighted, centers, radii) {
+		t.Fatalf("Expected weighted centroid %v inside all circles", weighted)
+	}
+
+	// The upper intersection of the two tight circles carries the most weight.
+	_, tight, _ := intersectTwoCircles(centers[0], radii[0], centers[1], radii[1])
+	if tight.Y < 0 {
+		_, _, tight = intersectTwoCircles(centers[0], radii[0], centers[1], radii[1])
+	}
+	if Distance2D(weighted, tight) >= Distance2D(unweighted, tight) {
+		t.Errorf("Expected weighted centroid %v closer than unweighted %v to the tight intersection %v", weighted, unweighted, tight)
+	}
+}
+
+func TestGeometryConfigRelativeToleranceAtLargeCoordinates(t *testing.T) {
+	// Externally tangent circles far from the origin: rounding in the centers exceeds the
+	// default absolute tolerance, so the tangency is lost without a relative term.
+	relative := DefaultGeometryConfig()
+	relative.RelTol = 1e-13
+	for _, base := range []float64{1e7, 1e8, 1e9} {
+		for k := 0; k < 10; k++ {
+			off := 0.37 * float64(k)
+			centers := []Vec2{{base + off + 0.1, base*0.7 + 0.3}, {base + off + 1.5, base*0.7 + 0.3}}
+			radii := []float64{0.7, 0.7}
+
+			if ok, _ := relative.AllCirclesIntersectAtPoint(centers, radii); !ok {
+				t.Errorf("Expected tangent circles at %v to intersect with a relative tolerance", centers)
+			}
+		}
+	}
+
+	centers := []Vec2{{1e7 + 0.1, 0.7e7 + 0.3}, {1e7 + 1.5, 0.7e7 + 0.3}}
+	radii := []float64{0.7, 0.7}
+	if ok, _ := AllCirclesIntersectAtPoint(centers, radii); ok {
+		t.Fatal("Expected the default absolute tolerance to miss the tangency")
+	}
+	ok, p := relative.AllCirclesIntersectAtPoint(centers, radii)
+	if !ok {
+		t.Fatal("Expected circles to intersect with a relative tolerance")
+	}
+	want := Vec2{X: 1e7 + 0.8, Y: 0.7e7 + 0.3}
+	if Distance2D(p, want) > 1e-6 {
+		t.Errorf("Expected tangent point %v, got %v", want, p)
+	}
+}
+
+func TestDefaultGeometryConfigMatchesPackageFunctions(t *testing.T) {
+	g := DefaultGeometryConfig()
+	for k := 0; k < 50; k++ {
+		alpha, fused := GeometricFusion2D(driftingPositions(k))
+		gAlpha, gFused := g.GeometricFusion2D(driftingPositions(k))
+		if alpha != gAlpha || fused != gFused {
+			t.Errorf("Expected (%v, %v), got (%v, %v)", alpha, fused, gAlpha, gFused)
+		}
+	}
+}
+
+func TestGeometricFusion2DResultIntersectionsLieOnBoundaries(t *testing.T) {
+	for k := 0; k < 20; k++ {
+		positions := driftingPositions(k)
+		r := GeometricFusion2DResult(positions)
+
+		alpha, fused := GeometricFusion2D(positions)
+		if r.Alpha != alpha || r.Position != fused {
+			t.Errorf("Expected (%v, %v), got (%v, %v)", alpha, fused, r.Alpha, r.Position)
+		}
+		if len(r.Intersections) == 0 {
+			t.Fatalf("Expected intersection points at alpha %v", r.Alpha)
+		}
+		for _, p := range r.Intersections {
+			onBoundary := 0
+			for i, c := range r.Centers {
+				if math.Abs(Distance2D(p, c)-r.Radii[i]) < 1e-6 {
+					onBoundary++
+				}
+				if Distance2D(p, c) > r.Radii[i]+1e-6 {
+					t.Errorf("Expected %v inside circle %d", p, i)
+				}
+			}
+			if onBoundary < 2 {
+				t.Errorf("Expected %v on at least two circle boundaries, got %d", p, onBoundary)
+			}
+		}
+	}
+}
+
+func TestGeometricMedian(t *testing.T) {
+	h := math.Sqrt(3) / 2
+	tests := []struct {
+		name    string
+		centers []Vec2
+		radii   []float64
+		want    Vec2
+	}{
+		{
+			name:    "Equilateral triangle",
+			centers: []Vec2{{0, 0}, {10, 0}, {5, 10 * h}},
+			radii:   []float64{1, 1, 1},
+			want:    Vec2{5, 10 * h / 3},
+		},
+		{
+			name:    "Collinear points",
+			centers: []Vec2{{0, 0}, {3, 0}, {10, 0}},
+			radii:   []float64{1, 1, 1},
+			want:    Vec2{3, 0},
+		},
+		{
+			name:    "Square corners",
+			centers: []Vec2{{-20, -20}, {20, -20}, {20, 20}, {-20, 20}},
+			radii:   []float64{0.5, 0.5, 0.5, 0.5},
+			want:    Vec2{0, 0},
+		},
+		{
+			// A weight at least the sum of the others pins the median to its center.
+			name:    "Dominant tight circle",
+			centers: []Vec2{{7, -3}, {-10, 0}, {0, 10}},
+			radii:   []float64{0.1, 1, 1},
+			want:    Vec2{7, -3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GeometricMedian(tt.centers, tt.radii)
+			if Distance2D(got, tt.want) > 1e-4 {
+				t.Errorf("Expected median %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFusionTrackerMedianFallback(t *testing.T) {
+	// Circles far too small to meet even at the largest expansion.
+	positions := []Position{{X: 0, Y: 0, R: 0.1}, {X: 100, Y: 0, R: 0.1}, {X: 50, Y: 80, R: 0.1}}
+
+	strict := NewFusionTracker()
+	if _, fused := strict.Fuse(positions); fused.X != 0 || fused.Y != 0 {
+		t.Errorf("Expected strict mode to report the origin, got %v", fused)
+	}
+
+	fallback := NewFusionTracker()
+	fallback.SetMode(FusionMedianFallback)
+	alpha, fused := fallback.Fuse(positions)
+	centers := []Vec2{{0, 0}, {100, 0}, {50, 80}}
+	want := GeometricMedian(centers, []float64{0.1, 0.1, 0.1})
+	if alpha != alphaUpperBound || fused.X != want.X || fused.Y != want.Y {
+		t.Errorf("Expected (%v, %v), got (%v, %v)", alphaUpperBound, want, alpha, fused)
+	}
+
+	// Feasible frames are unaffected by the mode.
+	a1, p1 := strict.Fuse(driftingPositions(0))
+	a2, p2 := fallback.Fuse(driftingPositions(0))
+	if !floatsClose(a1, a2, 1e-3) || Distance2D(Vec2{p1.X, p1.Y}, Vec2{p2.X, p2.Y}) > 1e-3 {
+		t.Errorf("Expected matching results for feasible positions, got (%v, %v) and (%v, %v)", a1, p1, a2, p2)
+	}
+}
+
+func TestGeometricFusion2DNonOverlapPolicy(t *testing.T) {
+	// A disjoint pair that meets at alpha 2, at x = 2, and one too far apart to meet at any alpha.
+	near := []Position{{X: 0, Y: 0, R: 1}, {X: 3, Y: 0, R: 0.5}}
+	far := []Position{{X: 0, Y: 0, R: 0.1}, {X: 10, Y: 0, R: 0.3}}
+	// Weights 1/0.1 and 1/0.3 put the far pair's midpoint a quarter of the way along.
+	nearest := Vec2{X: 2.5, Y: 0}
+
+	tests := []struct {
+		name      string
+		policy    NonOverlapPolicy
+		positions []Position
+		wantAlpha float64
+		want      Vec2
+	}{
+		{name: "ExpandGrowsAlpha", policy: NonOverlapExpand, positions: near, wantAlpha: 2, want: Vec2{X: 2, Y: 0}},
+		{name: "NearestWeightedGrowsAlpha", policy: NonOverlapNearestWeighted, positions: near, wantAlpha: 2, want: Vec2{X: 2, Y: 0}},
+		{name: "ExpandUnresolved", policy: NonOverlapExpand, positions: far, wantAlpha: alphaUpperBound, want: Vec2{}},
+		{name: "NearestWeightedUnresolved", policy: NonOverlapNearestWeighted, positions: far, wantAlpha: alphaUpperBound, want: nearest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := DefaultGeometryConfig()
+			g.NonOverlap = tt.policy
+			alpha, fused := g.GeometricFusion2D(tt.positions)
+			if !floatsClose(alpha, tt.wantAlpha, 1e-3) {
+				t.Errorf("Expected alpha %v, got %v", tt.wantAlpha, alpha)
+			}
+			if Distance2D(Vec2{X: fused.X, Y: fused.Y}, tt.want) > 1e-3 {
+				t.Errorf("Expected fused position %v, got %v", tt.want, fused)
+			}
+			if result := g.GeometricFusion2DResult(tt.positions); Distance2D(Vec2{X: result.Position.X, Y: result.Position.Y}, tt.want) > 1e-3 {
+				t.Errorf("Expected result position %v, got %v", tt.want, result.Position)
+			}
+		})
+	}
+}
+
+func TestFusionTrackerProbesBracket(t *testing.T) {
+	ft := NewFusionTracker()
+	ft.Fuse(driftingPositions(0))
+	if probes := ft.Probes(); probes != nil {
+		t.Errorf("Expected no probes unless recording, got %v", probes)
+	}
+	ft.Reset()
+	ft.SetRecordProbes(true)
+
+	// A full bisection, then a warm-started walk from the previous alpha.
+	for _, step := range []int{0, 200} {
+		alpha, _ := ft.Fuse(driftingPositions(step))
+		probes := ft.Probes()
+		if len(probes) == 0 || len(probes) != ft.lastEvals {
+			t.Fatalf("step %d: Expected a probe per evaluation (%d), got %v", step, ft.lastEvals, probes)
+		}
+		lo, hi := math.Inf(-1), math.Inf(1)
+		for i, p := range probes {
+			if p.Intersects {
+				if p.Alpha >= hi {
+					t.Errorf("step %d, probe %d: Expected an intersecting alpha below %f, got %f", step, i, hi, p.Alpha)
+				}
+				hi = p.Alpha
+			} else {
+				if p.Alpha <= lo {
+					t.Errorf("step %d, probe %d: Expected a disjoint alpha above %f, got %f", step, i, lo, p.Alpha)
+				}
+				lo = p.Alpha
+			}
+		}
+		if alpha != hi || hi-lo > alphaTolerance {
+			t.Errorf("step %d: Expected alpha %f to close a bracket narrower than %g, got [%f, %f]", step, alpha, alphaTolerance, lo, hi)
+		}
+	}
+}
+
+func TestLensArea(t *testing.T) {
+	// Equal circles of radius r at separation d overlap in 2r²acos(d/2r) - (d/2)sqrt(4r²-d²).
+	const r = 2.0
+	equal := func(d float64) float64 {
+		return 2*r*r*math.Acos(d/(2*r)) - d/2*math.Sqrt(4*r*r-d*d)
+	}
+	tests := []struct {
+		name   string
+		c1, c2 Vec2
+		r1, r2 float64
+		want   float64
+	}{
+		{"Coincident", Vec2{0, 0}, Vec2{0, 0}, r, r, math.Pi * r * r},
+		{"Quarter separation", Vec2{0, 0}, Vec2{r / 2, 0}, r, r, equal(r / 2)},
+		{"Separation r", Vec2{1, 1}, Vec2{1, 1 + r}, r, r, r * r * (2*math.Pi/3 - math.Sqrt(3)/2)},
+		{"Separation sqrt(2)r", Vec2{0, 0}, Vec2{r, r}, r, r, r * r * (math.Pi/2 - 1)},
+		{"Separation 1.9r", Vec2{0, 0}, Vec2{1.9 * r, 0}, r, r, equal(1.9 * r)},
+		{"Externally tangent", Vec2{0, 0}, Vec2{2 * r, 0}, r, r, 0},
+		{"Disjoint", Vec2{0, 0}, Vec2{10, 0}, r, r, 0},
+		{"Contained", Vec2{0, 0}, Vec2{0.5, 0}, 3, 1, math.Pi},
+		{"Internally tangent", Vec2{0, 0}, Vec2{2, 0}, 3, 1, math.Pi},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LensArea(tt.c1, tt.r1, tt.c2, tt.r2)
+			if !floatsClose(got, tt.want, 1e-9) {
+				t.Errorf("Expected area %f, got %f", tt.want, got)
+			}
+			if swapped := LensArea(tt.c2, tt.r2, tt.c1, tt.r1); !floatsClose(swapped, got, 1e-12) {
+				t.Errorf("Expected symmetric area %f, got %f", got, swapped)
+			}
+		})
+	}
+}
+
+func TestSampledIntersectionPointAgreesWithAnalytic(t *testing.T) {
+	const gridStep = 0.01
+	for _, tt := range intersectionCases {
+		t.Run(tt.name, func(t *testing.T) {
+			okAnalytic, analytic := AllCirclesIntersectAtPoint(tt.centers, tt.radii)
+			okSampled, sampled := SampledIntersectionPoint(tt.centers, tt.radii, gridStep)
+			if okSampled != okAnalytic {
+				t.Fatalf("Expected sampled ok=%v to match analytic, got %v", okAnalytic, okSampled)
+			}
+			if !okSampled {
+				return
+			}
+			if Distance2D(sampled, analytic) > 0.1 {
+				t.Errorf("Expected sampled point %v near analytic point %v", sampled, analytic)
+			}
+			for i, c := range tt.centers {
+				if Distance2D(sampled, c) > tt.radii[i]+gridStep {
+					t.Errorf("Expected sampled point %v inside circle %d", sampled, i)
+				}
+			}
+		})
+	}
+}
+
+func TestFusionResidual(t *testing.T) {
+	r := 2 / math.Sqrt(3) // circumradius of the triangle, so the circles meet at its centroid
+	tests := []struct {
+		name      string
+		positions []Position
+		expectMin float64
+		expectMax float64
+	}{
+		{
+			name: "Clean Three Circle Intersection",
+			positions: []Position{
+				{X: 0, Y: 0, R: r},
+				{X: 2, Y: 0, R: r},
+				{X: 1, Y: math.Sqrt(3), R: r},
+			},
+			expectMin: 0,
+			expectMax: 0.01,
+		},
+		{
+			name: "Forced Expansion",
+			positions: []Position{
+				{X: 0, Y: 0, R: 1},
+				{X: 4, Y: 0, R: 1},
+				{X: 2, Y: 0, R: 5}, // expands with the others, leaving the fused point deep inside
+			},
+			expectMin: 9,
+			expectMax: 11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, fused := GeometricFusion2D(tt.positions)
+			residual := FusionResidual(tt.positions, fused)
+			if residual < tt.expectMin || residual > tt.expectMax {
+				t.Errorf("Expected residual in [%f, %f], got %f", tt.expectMin, tt.expectMax, residual)
+			}
+		})
+	}
+}
+
+func TestRefineIntersectionPoint(t *testing.T) {
+	centers := []Vec2{{X: 0, Y: 0}, {X: 1.5, Y: 0}, {X: 0.75, Y: 1.2}}
+	radii := []float64{1, 1, 1}
+	// A pairwise intersection of the first two circles, on both their boundaries.
+	start := Vec2{X: 0.75, Y: math.Sqrt(1 - 0.75*0.75)}
+	if !isInsideAll(start, centers, radii) {
+		t.Fatalf("Expected start %v inside all circles", start)
+	}
+
+	_, deepest := SampledIntersectionPoint(centers, radii, 1e-3)
+	optimal := minMargin(deepest, centers, radii)
+
+	refined := RefineIntersectionPoint(centers, radii, start, 200)
+	margin := minMargin(refined, centers, radii)
+	if margin <= 0.1 || !isInsideAll(refined, centers, radii) {
+		t.Errorf("Expected refined point %v strictly inside all circles, got margin %f", refined, margin)
+	}
+	if margin < optimal-0.01 {
+		t.Errorf("Expected margin near the optimum %f, got %f", optimal, margin)
+	}
+
+	// Refinement through GeometryConfig applies to the solver's point.
+	g := DefaultGeometryConfig()
+	g.RefineIterations = 200
+	ok, p := g.AllCirclesIntersectAtPoint(centers, radii)
+	if !ok || minMargin(p, centers, radii) < optimal-0.01 {
+		t.Errorf("Expected a refined intersection with margin near %f, got %v (ok=%v)", optimal, p, ok)
+	}
+
+	// Disjoint circles are left unchanged.
+	if ok, _ := g.AllCirclesIntersectAtPoint([]Vec2{{X: 0, Y: 0}, {X: 3, Y: 0}}, []float64{1, 1}); ok {
+		t.Error("Expected no intersection for disjoint circles")
+	}
+}
+
+func TestIntersectTwoCirclesNearTangent(t *testing.T) {
+	for _, scale := range []float64{1e-6, 1, 1e6} {
+		g := DefaultGeometryConfig()
+		g.RelTol = 1e-13
+		r := scale
+		tol := g.tol(magnitude(2*r, r))
+
+		// Just inside external tangency: a genuine two-point intersection.
+		d := 2*r - 3*tol
+		count, p1, p2 := g.intersectTwoCircles(Vec2{}, r, Vec2{X: d}, r)
+		if count != 2 {
+			t.Errorf("scale %g: Expected 2 points %g inside external tangency, got %d", scale, 3*tol, count)
+		} else if p1 == p2 || !floatsClose(p1.Y, -p2.Y, tol) {
+			t.Errorf("scale %g: Expected two distinct mirrored points, got %v and %v", scale, p1, p2)
+		}
+
+		// Just inside internal tangency: a small circle touching the inside of a large one.
+		d = r/2 + 3*tol
+		if count, _, _ := g.intersectTwoCircles(Vec2{}, r, Vec2{X: d}, r/2); count != 2 {
+			t.Errorf("scale %g: Expected 2 points %g outside internal tangency, got %d", scale, 3*tol, count)
+		}
+
+		// Within tolerance of tangency: one point, at the touching point rather than off the axis.
+		for _, d := range []float64{2 * r, 2*r - tol/2, 2*r + tol/2} {
+			count, p, _ := g.intersectTwoCircles(Vec2{}, r, Vec2{X: d}, r)
+			if count != 1 {
+				t.Errorf("scale %g, d %g: Expected 1 tangent point, got %d", scale, d, count)
+				continue
+			}
+			if !floatsClose(p.X, d/2, tol) || !floatsClose(p.Y, 0, tol) {
+				t.Errorf("scale %g, d %g: Expected tangent point (%g, 0), got %v", scale, d, d/2, p)
+			}
+		}
+	}
+}
+
+func BenchmarkAllCirclesIntersectAtPoint(b *testing.B) {
+	// Eight circles around a common region that contains none of their centers, so every pair
+	// is intersected.
+	const n = 8
+	centers := make([]Vec2, n)
+	radii := make([]float64, n)
+	for i := range centers {
+		theta := 2 * math.Pi * float64(i) / n
+		centers[i] = Vec2{X: math.Cos(theta), Y: math.Sin(theta)}
+		radii[i] = 1.2
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		AllCirclesIntersectAtPoint(centers, radii)
+	}
+}
@@ -0,0 +1,43 @@
+package internal
+
+import "math"
+
+// WGS84 ellipsoid parameters.
+const (
+	wgs84SemiMajor = 6378137.0        // equatorial radius, m
+	wgs84EccSq     = 6.69437999014e-3 // first eccentricity squared
+	degToRad       = math.Pi / 180    // degrees to radians
+	radToDeg       = 180 / math.Pi    // radians to degrees
+)
+
+// earthRadii returns the meridian and prime vertical radii of curvature at latitude lat (radians).
+func earthRadii(lat float64) (meridian, primeVertical float64) {
+	s := math.Sin(lat)
+	w := 1 - wgs84EccSq*s*s
+	primeVertical = wgs84SemiMajor / math.Sqrt(w)
+	meridian = wgs84SemiMajor * (1 - wgs84EccSq) / (w * math.Sqrt(w))
+	return meridian, primeVertical
+}
+
+// LocalToGeodetic converts a local position in meters, with X east and Y north of the origin,
+// to latitude and longitude in degrees. It scales offsets by the WGS84 radii of curvature at the
+// origin latitude, a flat-earth approximation whose error against an exact tangent-plane (ENU)
+// conversion grows with the square of the distance d from the origin and with latitude, roughly
+// d²·tan|lat|/(2R) for Earth radius R: about a millimeter at 100 m, 9 cm at 1 km, and 0.8 m at
+// 3 km at 45°, and more toward the poles.
+func LocalToGeodetic(p Point, originLat, originLon float64) (lat, lon float64) {
+	meridian, primeVertical := earthRadii(originLat * degToRad)
+	lat = originLat + p.Y/meridian*radToDeg
+	lon = originLon + p.X/(primeVertical*math.Cos(originLat*degToRad))*radToDeg
+	return lat, lon
+}
+
+// GeodeticToLocal is the inverse of LocalToGeodetic, converting latitude and longitude in degrees
+// to a local position in meters east (X) and north (Y) of the origin.
+func GeodeticToLocal(lat, lon, originLat, originLon float64) Point {
+	meridian, primeVertical := earthRadii(originLat * degToRad)
+	return Point{
+		X: (lon - originLon) * degToRad * primeVertical * math.Cos(originLat*degToRad),
+		Y: (lat - originLat) * degToRad * meridian,
+	}
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeodeticRoundTrip(t *testing.T) {
+	origins := []struct{ lat, lon float64 }{
+		{0, 0},
+		{47.3769, 8.5417},
+		{-33.8688, 151.2093},
+		{64.1466, -21.9426},
+	}
+	points := []Point{{0, 0}, {1.5, -2.25}, {250, 400}, {-1200, 900}}
+	for _, o := range origins {
+		for _, p := range points {
+			lat, lon := LocalToGeodetic(p, o.lat, o.lon)
+			back := GeodeticToLocal(lat, lon, o.lat, o.lon)
+			if math.Hypot(back.X-p.X, back.Y-p.Y) > 0.01 {
+				t.Errorf("origin (%f, %f): Expected %v after round trip, got %v", o.lat, o.lon, p, back)
+			}
+		}
+	}
+}
+
+func TestLocalToGeodeticScale(t *testing.T) {
+	// One degree of latitude at the equator is about 110574 m, and of longitude about 111320 m.
+	lat, lon := LocalToGeodetic(Point{X: 1000, Y: 1000}, 0, 0)
+	if !floatsClose(lat, 1000.0/110574, 1e-6) {
+		t.Errorf("Expected latitude %f, got %f", 1000.0/110574, lat)
+	}
+	if !floatsClose(lon, 1000.0/111320, 1e-6) {
+		t.Errorf("Expected longitude %f, got %f", 1000.0/111320, lon)
+	}
+
+	// East-west distances shrink with the cosine of latitude.
+	_, lon60 := LocalToGeodetic(Point{X: 1000}, 60, 0)
+	if lon60 <= 1.9*lon || lon60 >= 2.1*lon {
+		t.Errorf("Expected longitude offset near twice the equatorial one at 60°, got %f vs %f", lon60, lon)
+	}
+}
+
+func TestLocalToGeodeticAgainstExactENU(t *testing.T) {
+	const earthRadius = 6.371e6
+	for _, originLat := range []float64{0, 45, 60, 75, -45} {
+		for _, d := range []float64{100, 1000, 3000, 10000} {
+			// The documented error, d²·tan|lat|/(2R), with a factor of two and a floor of margin.
+			bound := d * d * (math.Tan(math.Abs(originLat)*degToRad) + 0.02) / earthRadius
+			for _, angle := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+				p := Point{X: d * math.Cos(angle*degToRad), Y: d * math.Sin(angle*degToRad)}
+				lat, lon := enuToGeodetic(p, originLat, 10)
+				got := GeodeticToLocal(lat, lon, originLat, 10)
+				if e := math.Hypot(got.X-p.X, got.Y-p.Y); e > bound {
+					t.Errorf("origin latitude %f: Expected error within %f m at %v, got %f m", originLat, bound, p, e)
+				}
+			}
+		}
+	}
+}
+
+// enuToGeodetic is the exact conversion of an east/north offset on the tangent plane at the
+// origin, at zero height, to latitude and longitude, via Earth-centered Earth-fixed coordinates.
+func enuToGeodetic(p Point, originLat, originLon float64) (lat, lon float64) {
+	phi, lambda := originLat*degToRad, originLon*degToRad
+	_, n := earthRadii(phi)
+	x0 := n * math.Cos(phi) * math.Cos(lambda)
+	y0 := n * math.Cos(phi) * math.Sin(lambda)
+	z0 := n * (1 - wgs84EccSq) * math.Sin(phi)
+
+	sp, cp := math.Sin(phi), math.Cos(phi)
+	sl, cl := math.Sin(lambda), math.Cos(lambda)
+	x := x0 - sl*p.X - sp*cl*p.Y
+	y := y0 + cl*p.X - sp*sl*p.Y
+	z := z0 + cp*p.Y
+
+	// Bowring-style fixed-point iteration for latitude; height is discarded.
+	r := math.Hypot(x, y)
+	lat = math.Atan2(z, r*(1-wgs84EccSq))
+	for i := 0; i < 10; i++ {
+		_, n := earthRadii(lat)
+		h := r/math.Cos(lat) - n
+		lat = math.Atan2(z, r*(1-wgs84EccSq*n/(n+h)))
+	}
+	return lat * radToDeg, math.Atan2(y, x) * radToDeg
+}
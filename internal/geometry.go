@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
 	"math"
 )
 
@@ -17,17 +19,89 @@ func (c1 *Circle) Intersects(c2 *Circle) bool {
 	return distance <= (c1.Radius + c2.Radius)
 }
 
+// Relation describes how two circles are positioned relative to each other.
+type Relation int
+
+const (
+	Disjoint     Relation = iota // No common points
+	Intersecting                 // Boundaries cross, or touch externally
+	Contains                     // The other circle lies inside this one
+	ContainedBy                  // This circle lies inside the other
+	Identical                    // Same center and radius
+)
+
+// String returns the name of the relation.
+func (r Relation) String() string {
+	switch r {
+	case Disjoint:
+		return "Disjoint"
+	case Intersecting:
+		return "Intersecting"
+	case Contains:
+		return "Contains"
+	case ContainedBy:
+		return "ContainedBy"
+	case Identical:
+		return "Identical"
+	}
+	return fmt.Sprintf("Relation(%d)", int(r))
+}
+
+// ContainmentRelation classifies how c2 is positioned relative to c1.
+// Externally tangent circles are Intersecting; internally tangent circles are Contains
+// or ContainedBy, since one still lies entirely within the other.
+func (c1 *Circle) ContainmentRelation(c2 *Circle) Relation {
+	distance := math.Hypot(c1.X-c2.X, c1.Y-c2.Y)
+	switch {
+	case distance <= epsilon && math.Abs(c1.Radius-c2.Radius) <= epsilon:
+		return Identical
+	case distance > c1.Radius+c2.Radius+epsilon:
+		return Disjoint
+	case distance <= c1.Radius-c2.Radius+epsilon:
+		return Contains
+	case distance <= c2.Radius-c1.Radius+epsilon:
+		return ContainedBy
+	}
+	return Intersecting
+}
+
 // Expand expands the radius of the circle by a given factor.
 func (c *Circle) Expand(factor float64) {
 	c.Radius *= factor
 }
 
+// Errors returned by FusedPosition.
+var (
+	ErrNoCircles              = errors.New("no circles to fuse")
+	ErrMismatchedLengths      = errors.New("circles and uncertainties differ in length")
+	ErrNegativeUncertainty    = errors.New("negative uncertainty")
+	ErrConflictingConstraints = errors.New("zero-uncertainty circles disagree on position")
+)
+
 // FusedPosition calculates the weighted average position of multiple circles based on their uncertainties.
-func FusedPosition(circles []Circle, uncertainties []float64) (float64, float64) {
+// Each circle is weighted by the inverse of its uncertainty. A zero uncertainty is an exact position
+// with infinite weight: if any are present, the result is that position and the others are ignored.
+func FusedPosition(circles []Circle, uncertainties []float64) (float64, float64, error) {
+	if len(circles) == 0 {
+		return 0, 0, ErrNoCircles
+	}
+	if len(circles) != len(uncertainties) {
+		return 0, 0, ErrMismatchedLengths
+	}
+
 	var weightedX, weightedY, weightSum float64
+	var exact *Circle
 
 	for i, circle := range circles {
-		if uncertainties[i] > 0 {
+		switch {
+		case uncertainties[i] < 0:
+			return 0, 0, fmt.Errorf("circle %d: %w", i, ErrNegativeUncertainty)
+		case uncertainties[i] == 0:
+			if exact != nil && math.Hypot(exact.X-circle.X, exact.Y-circle.Y) > epsilon {
+				return 0, 0, fmt.Errorf("circle %d: %w", i, ErrConflictingConstraints)
+			}
+			exact = &circles[i]
+		default:
 			weight := 1 / uncertainties[i]
 			weightedX += circle.X * weight
 			weightedY += circle.Y * weight
@@ -35,8 +109,8 @@ func FusedPosition(circles []Circle, uncertainties []float64) (float64, float64)
 		}
 	}
 
-	if weightSum > 0 {
-		return weightedX / weightSum, weightedY / weightSum
+	if exact != nil {
+		return exact.X, exact.Y, nil
 	}
-	return 0, 0 // Return origin if no valid circles
-}
\ No newline at end of file
+	return weightedX / weightSum, weightedY / weightSum, nil
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFusedPosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		circles       []Circle
+		uncertainties []float64
+		expectX       float64
+		expectY       float64
+		expectErr     error
+	}{
+		{
+			name:          "Weighted Average",
+			circles:       []Circle{{X: 0, Y: 0}, {X: 3, Y: 3}},
+			uncertainties: []float64{1, 2},
+			expectX:       1, // weights 1 and 0.5
+			expectY:       1,
+		},
+		{
+			name:          "All Zero Uncertainties Agree",
+			circles:       []Circle{{X: 2, Y: -1}, {X: 2, Y: -1}},
+			uncertainties: []float64{0, 0},
+			expectX:       2,
+			expectY:       -1,
+		},
+		{
+			name:          "All Zero Uncertainties Disagree",
+			circles:       []Circle{{X: 2, Y: -1}, {X: 0, Y: 0}},
+			uncertainties: []float64{0, 0},
+			expectErr:     ErrConflictingConstraints,
+		},
+		{
+			name:          "Zero Uncertainty Is A Hard Constraint",
+			circles:       []Circle{{X: 0, Y: 0}, {X: 5, Y: 5}, {X: 1, Y: 4}},
+			uncertainties: []float64{0.1, 0, 0.1},
+			expectX:       5,
+			expectY:       5,
+		},
+		{
+			name:          "Origin Is A Legitimate Result",
+			circles:       []Circle{{X: -1, Y: 0}, {X: 1, Y: 0}},
+			uncertainties: []float64{1, 1},
+			expectX:       0,
+			expectY:       0,
+		},
+		{
+			name:          "Negative Uncertainty",
+			circles:       []Circle{{X: 0, Y: 0}, {X: 1, Y: 1}},
+			uncertainties: []float64{1, -1},
+			expectErr:     ErrNegativeUncertainty,
+		},
+		{
+			name:      "No Circles",
+			expectErr: ErrNoCircles,
+		},
+		{
+			name:          "Mismatched Lengths",
+			circles:       []Circle{{X: 0, Y: 0}},
+			uncertainties: []float64{1, 1},
+			expectErr:     ErrMismatchedLengths,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, err := FusedPosition(tt.circles, tt.uncertainties)
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("Expected error %v, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !floatsClose(x, tt.expectX, 1e-9) || !floatsClose(y, tt.expectY, 1e-9) {
+				t.Errorf("Expected (%f, %f), got (%f, %f)", tt.expectX, tt.expectY, x, y)
+			}
+		})
+	}
+}
+
+func TestCircleContainmentRelation(t *testing.T) {
+	tests := []struct {
+		name   string
+		c1, c2 Circle
+		expect Relation
+	}{
+		{"Disjoint", Circle{X: 0, Y: 0, Radius: 1}, Circle{X: 3, Y: 0, Radius: 1}, Disjoint},
+		{"Overlapping", Circle{X: 0, Y: 0, Radius: 1}, Circle{X: 1.5, Y: 0, Radius: 1}, Intersecting},
+		{"External Tangent", Circle{X: 0, Y: 0, Radius: 1}, Circle{X: 2, Y: 0, Radius: 1}, Intersecting},
+		{"Contains", Circle{X: 0, Y: 0, Radius: 3}, Circle{X: 0.5, Y: 0, Radius: 1}, Contains},
+		{"Contains Concentric", Circle{X: 0, Y: 0, Radius: 3}, Circle{X: 0, Y: 0, Radius: 1}, Contains},
+		{"Internal Tangent Contains", Circle{X: 0, Y: 0, Radius: 3}, Circle{X: 2, Y: 0, Radius: 1}, Contains},
+		{"ContainedBy", Circle{X: 0.5, Y: 0, Radius: 1}, Circle{X: 0, Y: 0, Radius: 3}, ContainedBy},
+		{"Internal Tangent ContainedBy", Circle{X: 2, Y: 0, Radius: 1}, Circle{X: 0, Y: 0, Radius: 3}, ContainedBy},
+		{"Identical", Circle{X: 1, Y: 1, Radius: 2}, Circle{X: 1, Y: 1, Radius: 2}, Identical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c1.ContainmentRelation(&tt.c2); got != tt.expect {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+			// Intersects is unchanged: true for everything but disjoint circles.
+			if got := tt.c1.Intersects(&tt.c2); got != (tt.expect != Disjoint) {
+				t.Errorf("Expected Intersects=%v, got %v", tt.expect != Disjoint, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// fusionGroup is the fusion state of one rigid body when the IMUs are split into groups.
+type fusionGroup struct {
+	ids       []int            // IMUs mounted on the body
+	tracker   *FusionTracker   // warm-started geometric fusion of the group
+	cloud     *PointCloud      // refinement point cloud of the group
+	smoother  *AlphaBetaFilter // output smoothing, nil when disabled
+	lastFused Vec2             // previous fused position, the reference for gating
+	hasFused  bool
+	held      Point // output position held while stationary
+}
+
+// SetGroups splits the IMUs into independent rigid bodies. Each group is fused on its own, with
+// its own reference geometry for the rigid constraint, point cloud, and output smoothing, and
+// every frame emits one FusedSample per group, with Group set to the group's index. Every IMU
+// must belong to exactly one group. Integration, stationarity detection and the body yaw rate
+// used for lever arm compensation remain shared across the frame. With groups, CurrentPosition
+// and FuseTrajectory report the first group fused in a frame, SetOutputRate is ignored, and the
+// group fusion state is not part of State. nil restores a single body. It should be called before
+// Start.
+func (sys *IMUFusionSystem) SetGroups(groups [][]int) error {
+	if groups == nil {
+		sys.filterMu.Lock()
+		sys.groups = nil
+		sys.filterMu.Unlock()
+		return nil
+	}
+	owner := make([]int, sys.imuCount)
+	for i := range owner {
+		owner[i] = -1
+	}
+	for g, ids := range groups {
+		if len(ids) == 0 {
+			return fmt.Errorf("group %d is empty", g)
+		}
+		for _, id := range ids {
+			if id < 0 || id >= sys.imuCount {
+				return fmt.Errorf("group %d: IMU ID %d out of range [0, %d)", g, id, sys.imuCount)
+			}
+			if owner[id] >= 0 {
+				return fmt.Errorf("IMU %d is in groups %d and %d", id, owner[id], g)
+			}
+			owner[id] = g
+		}
+	}
+	for id, g := range owner {
+		if g < 0 {
+			return fmt.Errorf("IMU %d is in no group", id)
+		}
+	}
+
+	state := make([]*fusionGroup, len(groups))
+	for g, ids := range groups {
+		tracker := NewFusionTracker()
+		tracker.SetGeometryConfig(sys.tracker.geometry)
+		tracker.SetMode(sys.tracker.mode)
+		tracker.minAlpha, tracker.maxAlpha = sys.tracker.minAlpha, sys.tracker.maxAlpha
+		cloud := NewPointCloud()
+		cloud.SetCapacity(sys.cloud.capacity)
+		state[g] = &fusionGroup{
+			ids:     append([]int(nil), ids...),
+			tracker: tracker,
+			cloud:   cloud,
+		}
+		if sys.smoother != nil {
+			state[g].smoother = NewAlphaBetaFilter(sys.smoother.alpha, sys.smoother.beta)
+		}
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.groups = state
+	return nil
+}
+
+// fuseGroups fuses each group of an integrated frame. It is called by fuseFrame with filterMu
+// held, and releases it.
+func (fs *FusionState) fuseGroups(start time.Time, frame []IMUData, step frameStep, positions []Point, present []bool, drifts []driftEvent) []fusedFrame {
+	fs.updateUncertainties(step.dt)
+	groups := make([]*fusionGroup, 0, len(fs.groups))
+	indices := make([]int, 0, len(fs.groups))
+	fusions := make([]fusion, 0, len(fs.groups))
+	var failures []string
+	for g, group := range fs.groups {
+		members := fs.scratch.members
+		for i := range members {
+			members[i] = false
+		}
+		for _, i := range group.ids {
+			members[i] = present[i]
+		}
+		if fs.rigidConstraint {
+			fs.enforceRigid(positions, members)
+		}
+		for _, i := range group.ids {
+			if members[i] {
+				group.cloud.AddPointAt(positions[i].X, positions[i].Y, step.now)
+			}
+		}
+		fused, ok := fs.fuseIMUs(group.tracker, &group.lastFused, &group.hasFused, positions, members, len(group.ids), step.now)
+		if fused.failure != "" {
+			failures = append(failures, fused.failure)
+		}
+		if !ok {
+			continue
+		}
+		groups = append(groups, group)
+		indices = append(indices, g)
+		fusions = append(fusions, fused)
+	}
+	fs.filterMu.Unlock()
+	for _, reason := range failures {
+		fs.notifyFusionFailure(frame, reason)
+	}
+	if len(fusions) == 0 {
+		return nil
+	}
+	fs.notifyBiasDrift(drifts)
+
+	results := make([]fusedFrame, len(fusions))
+	cloudLen := 0
+	for k, group := range groups {
+		sample := fs.refineAndHold(group.cloud, group.smoother, &group.held, fusions[k], step)
+		sample.Group = indices[k]
+		results[k] = fusedFrame{sample: sample, alpha: fusions[k].alpha}
+		cloudLen += group.cloud.Len()
+	}
+	fs.metrics.recordFrame(fs.timer.Now().Sub(start), cloudLen)
+
+	fs.currentMu.Lock()
+	fs.current = Position{X: results[0].sample.X, Y: results[0].sample.Y, R: results[0].alpha}
+	fs.hasCurrent = true
+	fs.currentMu.Unlock()
+	return results
+}
+
+// resetGroups discards the fusion history of every group, placing each at the mean of anchors
+// over its IMUs if anchors is not nil. The caller must hold filterMu.
+func (fs *FusionState) resetGroups(anchors []Point) {
+	for _, group := range fs.groups {
+		group.tracker.Reset()
+		group.cloud.Clear()
+		if group.smoother != nil {
+			group.smoother.Reset()
+		}
+		group.hasFused = false
+		if anchors == nil {
+			continue
+		}
+		var mean Vec2
+		for _, i := range group.ids {
+			mean.X += anchors[i].X / float64(len(group.ids))
+			mean.Y += anchors[i].Y / float64(len(group.ids))
+		}
+		group.lastFused = mean
+		group.hasFused = true
+		group.held = Point{X: mean.X, Y: mean.Y}
+	}
+}
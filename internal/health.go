@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// IMUState is the health of a sensor as reported by HealthMonitor.
+type IMUState int
+
+const (
+	IMULive    IMUState = iota // reporting clean samples
+	IMUStale                   // no sample within the stale timeout, or none yet
+	IMUFaulted                 // produced a NaN, Inf, or out-of-range sample within the stale timeout
+	IMUFailed                  // its source failed permanently, see FailingSource
+)
+
+// String returns the lowercase name of the state.
+func (s IMUState) String() string {
+	switch s {
+	case IMULive:
+		return "live"
+	case IMUStale:
+		return "stale"
+	case IMUFaulted:
+		return "faulted"
+	case IMUFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// IMUStatus is a snapshot of one sensor's health.
+type IMUStatus struct {
+	IMUID      int
+	State      IMUState
+	LastSample time.Time // receive time of the latest sample, zero if none
+	SampleRate float64   // smoothed rate in Hz from device sample times, 0 until two samples
+	Noise      float64   // smoothed standard deviation of the acceleration magnitude
+	Faults     uint64    // samples that were non-finite or beyond the fault limit
+}
+
+// healthSmoothing is the weight of each new sample in the smoothed sample interval and noise.
+const healthSmoothing = 0.05
+
+// HealthMonitor tracks the sample rate, noise, and faults of each IMU from the samples it observes.
+// It is safe for concurrent use.
+type HealthMonitor struct {
+	mu         sync.Mutex
+	staleAfter time.Duration
+	faultLimit float64 // acceleration magnitude beyond which a sample is faulty, 0 to disable
+	sensors    map[int]*sensorHealth
+}
+
+// sensorHealth is the running state behind an IMUStatus.
+type sensorHealth struct {
+	lastReceived time.Time
+	lastSample   time.Time // device sample time of the latest sample
+	lastFault    time.Time // receive time of the latest faulty sample
+	interval     float64   // smoothed seconds between samples, 0 until two samples
+	mean, varAcc float64   // smoothed mean and variance of the acceleration magnitude
+	samples      uint64
+	faults       uint64
+}
+
+// NewHealthMonitor creates a HealthMonitor reporting an IMU stale once staleAfter passes
+// without a sample from it.
+func NewHealthMonitor(staleAfter time.Duration) *HealthMonitor {
+	return &HealthMonitor{staleAfter: staleAfter, sensors: make(map[int]*sensorHealth)}
+}
+
+// SetStaleTimeout sets how long an IMU may go without a sample before it is reported stale, and
+// how long a fault keeps it reported faulted.
+func (h *HealthMonitor) SetStaleTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.staleAfter = d
+}
+
+// SetFaultLimit treats samples whose acceleration magnitude exceeds limit as faults, like
+// non-finite ones. A limit <= 0 only faults non-finite samples, the default.
+func (h *HealthMonitor) SetFaultLimit(limit float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faultLimit = limit
+}
+
+// Observe records a sample received at the given time, or at its receive timestamp if set.
+func (h *HealthMonitor) Observe(data IMUData, at time.Time) {
+	if !data.Timestamp.IsZero() {
+		at = data.Timestamp
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.sensors[data.IMUID]
+	if !ok {
+		s = &sensorHealth{}
+		h.sensors[data.IMUID] = s
+	}
+	s.lastReceived = at
+
+	a := data.Acceleration
+	magnitude := math.Sqrt(a[0]*a[0] + a[1]*a[1] + a[2]*a[2])
+	if !data.Finite() || (h.faultLimit > 0 && magnitude > h.faultLimit) {
+		s.faults++
+		s.lastFault = at
+		return
+	}
+
+	ts := data.SampleTime()
+	if s.samples > 0 {
+		if dt := ts.Sub(s.lastSample).Seconds(); dt > 0 {
+			if s.interval == 0 {
+				s.interval = dt
+			} else {
+				s.interval += healthSmoothing * (dt - s.interval)
+			}
+		}
+		d := magnitude - s.mean
+		s.mean += healthSmoothing * d
+		s.varAcc = (1 - healthSmoothing) * (s.varAcc + healthSmoothing*d*d)
+	} else {
+		s.mean = magnitude
+	}
+	s.lastSample = ts
+	s.samples++
+}
+
+// Status returns the health of an IMU as of now.
+func (h *HealthMonitor) Status(imuID int, now time.Time) IMUStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := IMUStatus{IMUID: imuID, State: IMUStale}
+	s, ok := h.sensors[imuID]
+	if !ok {
+		return status
+	}
+	status.LastSample = s.lastReceived
+	status.Noise = math.Sqrt(s.varAcc)
+	status.Faults = s.faults
+	if s.interval > 0 {
+		status.SampleRate = 1 / s.interval
+	}
+	switch {
+	case now.Sub(s.lastReceived) > h.staleAfter:
+		status.State = IMUStale
+	case s.faults > 0 && now.Sub(s.lastFault) <= h.staleAfter:
+		status.State = IMUFaulted
+	default:
+		status.State = IMULive
+	}
+	return status
+}
+
+// Reset forgets every IMU.
+func (h *HealthMonitor) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sensors = make(map[int]*sensorHealth)
+}
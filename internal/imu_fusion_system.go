@@ -2,58 +2,260 @@ package internal
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 )
 
-// IMUFusionSystem is the main struct orchestrating the fusion pipeline.
+// IMUFusionSystem is the main struct orchestrating the fusion pipeline. It runs acquisition,
+// synchronization, and output around an embedded FusionState holding the fusion math.
 type IMUFusionSystem struct {
-	acq        *DataAcquisition
-	sync       *Synchronizer
-	calib      []*IMU
-	cloud      *PointCloud
-	positions  []Point   // per-IMU position state
-	velocities []Point   // per-IMU velocity state
-	lastTime   time.Time // last timestamp for integration
-	noiseLevel float64   // IMU noise level for uncertainty calculation
-	imuCount   int       // number of IMUs
-	stopChan   chan struct{}
-	stopWg     sync.WaitGroup
-}
-
-// NewIMUFusionSystem initializes the IMU fusion system.
-func NewIMUFusionSystem(imuCount int) (*IMUFusionSystem, error) {
+	*FusionState
+	acq      *DataAcquisition
+	sync     *Synchronizer
+	clock    Clock // receive times and health; see SetClock
+	stopChan chan struct{}
+	stopWg   sync.WaitGroup
+
+	pauseMu    sync.Mutex
+	paused     bool
+	running    bool        // between Start and Stop, guarded by pauseMu
+	frameMu    sync.Mutex  // held by processDataLoop while it processes frames
+	pending    [][]IMUData // frames buffered while paused, owned by processDataLoop
+	maxPending int         // cap on buffered frames; the oldest are dropped beyond it
+
+	output          func(FusedSample) // receives each fused and refined position
+	metricsCallback func(Metrics)     // optional, invoked after each frame
+
+	outputPeriod time.Duration   // resampled output period, 0 to emit every frame
+	averager     *frameAverager  // combines frames between fusions, nil to fuse every frame; see SetFusionRate
+	unitScale    float64         // factor from internal units to emitted positions, see SetUnitScale
+	origin       Point           // world position of the internal origin, in emitted units; see SetOrigin
+	resampler    outputResampler // latest sample when resampling
+}
+
+// FusionState is the state of the fusion math for a set of IMUs: calibration, per-IMU filters
+// and uncertainties, geometric fusion, and point cloud refinement. It has no goroutines or I/O,
+// so FuseFrame can drive it directly; IMUFusionSystem embeds one and feeds it aligned frames.
+type FusionState struct {
+	metrics     pipelineCounters // first for 64-bit atomic alignment
+	calib       []*IMU
+	extrinsics  []Extrinsics // per-IMU mounting; tilt is leveled before calibration, rotation applied after
+	cloud       *PointCloud
+	tracker     *FusionTracker   // warm-started geometric fusion across frames
+	filters     []Filter         // per-IMU position, velocity, and bias state
+	filterMu    sync.Mutex       // guards filters against readers outside processDataLoop
+	lastTime    time.Time        // last timestamp for integration
+	haveFrame   bool             // whether lastTime comes from a processed frame, after which it only advances
+	noiseLevel  float64          // IMU noise level for uncertainty calculation
+	logger      Logger           // receives warnings, see WithLogger
+	timer       Clock            // times frame fusion for the latency metrics, see SetClock
+	uncertainty UncertaintyModel // per-IMU uncertainty radius over dead-reckoning time, guarded by filterMu
+	adaptive    *AdaptiveNoise   // motion scaling of the radii, nil to disable; guarded by filterMu
+
+	// deadReckoning is the per-IMU time in seconds since its position was last confirmed by a
+	// good fusion, over which its uncertainty has grown. uncertainties are the resulting radii
+	// from the last frame. Both are guarded by filterMu.
+	deadReckoning []float64
+	uncertainties []float64
+	disabled      []bool       // IMUs excluded from fusion by DisableIMU, guarded by filterMu
+	scratch       frameScratch // per-frame buffers reused across frames, guarded by filterMu
+	imuCount      int          // number of IMUs
+
+	currentMu  sync.Mutex
+	current    Position // latest fused and refined position, with R the fused alpha
+	hasCurrent bool
+
+	// refinementRadius is the point cloud search radius used to refine the fused position.
+	// It is a distance in position units, unlike the fused alpha, which is a unitless scale
+	// factor on the per-IMU uncertainty radii and so cannot serve as a spatial radius.
+	refinementRadius float64
+	distanceWidth    float64       // Gaussian distance kernel width for refinement, 0 to disable
+	ageWidth         time.Duration // exponential age kernel time constant for refinement, 0 to disable
+	huberDelta       float64       // Huber loss threshold for robust refinement, 0 for the plain weighted mean
+	medianRefinement bool          // refine to the weighted geometric median, the default, instead of a mean
+
+	strictTimestamps bool // skip, rather than integrate, frames whose timestamp does not advance
+
+	smoother *AlphaBetaFilter // optional output smoothing, nil to disable
+
+	accelFilters []*LowPassFilter // per-IMU acceleration low-pass, nil to disable; guarded by filterMu
+
+	rigidConstraint  bool          // fit the mounting geometry to the IMU positions each frame
+	referenceWeights []float64     // per-IMU weights in the rigid fit, nil for equal; guarded by filterMu
+	referenceFit     *referenceFit // pending AutoFitReference, nil when idle; guarded by filterMu
+
+	confidenceWeights ConfidenceWeights // weights of the FusionConfidence reported per frame
+
+	gatingThreshold float64 // chi-square outlier gate on per-IMU positions, 0 to disable
+	lastFused       Vec2    // previous fused position, the reference for gating
+	hasFused        bool
+
+	stationarity *StationarityDetector
+	stationary   bool  // guarded by filterMu
+	held         Point // output position held while stationary
+
+	drift       *BiasDriftMonitor
+	onBiasDrift func(imuID int, drift float64)
+
+	onFusionFailure func(frame []IMUData, reason string)
+
+	bodyRate     float64 // yaw rate of the previous frame, for the angular acceleration
+	haveBodyRate bool
+
+	motion *MotionMonitor // recent body-frame accelerations, guarded by filterMu
+
+	groups []*fusionGroup // independent rigid bodies, nil for one; see SetGroups
+
+	stages stageBus // subscribers to intermediate values, see Subscribe
+}
+
+// Default filter bias model: random walk density and prior standard deviation, in m/s^2,
+// and the gyro noise used by the UKF heading model, in rad/s.
+const (
+	defaultBiasNoise      = 1e-3
+	defaultInitialBiasStd = 0.5
+	defaultGyroNoise      = 0.01
+)
+
+// Default stationarity detection: window length in frames, variance limits, and the
+// variance of the zero-velocity update applied while stationary.
+const (
+	defaultStationaryWindow     = 50
+	defaultStationaryAccelLimit = 0.05 // (m/s^2)^2
+	defaultStationaryGyroLimit  = 0.01 // (rad/s)^2
+	zeroVelocityVariance        = 1e-6 // (m/s)^2
+	defaultDriftWindow          = 100  // stationary frames averaged by the bias drift monitor
+)
+
+// Default point cloud refinement search radius and kernel widths, in position units and time.
+const (
+	defaultRefinementRadius = 0.05
+	defaultDistanceWidth    = 0.025
+	defaultAgeWidth         = 500 * time.Millisecond
+)
+
+// goodFusionAlpha is the largest fused alpha at which the IMUs are taken to agree, confirming
+// their positions and resetting their dead-reckoning uncertainty.
+const goodFusionAlpha = 3.0
+
+// defaultCloudHistory is the number of most recent points kept in the refinement point cloud.
+const defaultCloudHistory = 10000
+
+// defaultMotionHistory is the number of most recent accelerations kept per IMU for MotionRMS
+// (one second at 1000Hz).
+const defaultMotionHistory = 1000
+
+// defaultMaxPending is the number of frames buffered while paused (one second at 1000Hz).
+const defaultMaxPending = 1000
+
+// NewIMUFusionSystem initializes the IMU fusion system with simulated IMUs, configured by opts
+// applied to DefaultFusionConfig.
+func NewIMUFusionSystem(imuCount int, opts ...Option) (*IMUFusionSystem, error) {
+	return NewIMUFusionSystemWithSource(imuCount, NewSimulatedSource(imuCount, 1*time.Millisecond), opts...)
+}
+
+// NewIMUFusionSystemWithSource initializes the IMU fusion system reading from the given source,
+// configured by opts applied to DefaultFusionConfig.
+func NewIMUFusionSystemWithSource(imuCount int, source Source, opts ...Option) (*IMUFusionSystem, error) {
+	cfg, err := newFusionConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	state := newFusionState(imuCount, cfg)
+	if err := state.tracker.SetAlphaBounds(cfg.MinAlpha, cfg.MaxAlpha); err != nil {
+		return nil, err
+	}
+	state.tracker.SetMode(cfg.Mode)
+
 	sync := NewSynchronizer()
-	acq := NewDataAcquisition(imuCount, sync) // Pass synchronizer to acquisition
+	acq := NewDataAcquisitionFromSource(imuCount, source, sync) // Pass synchronizer to acquisition
+	clock := RealClock{}
+	state.lastTime = clock.Now()
+	return &IMUFusionSystem{
+		FusionState: state,
+		acq:         acq,
+		sync:        sync,
+		clock:       clock,
+		stopChan:    make(chan struct{}),
+		maxPending:  defaultMaxPending,
+		output:      printOutput,
+		unitScale:   1,
+	}, nil
+}
+
+// NewFusionState creates the fusion state for imuCount IMUs with the defaults of
+// IMUFusionSystem: uncalibrated IMUs at the origin, EKF filters, and point cloud refinement.
+func NewFusionState(imuCount int) *FusionState {
+	return newFusionState(imuCount, DefaultFusionConfig())
+}
+
+// newFusionState creates the fusion state for imuCount IMUs with the noise level and logger of
+// cfg. The tracker is left at its defaults.
+func newFusionState(imuCount int, cfg FusionConfig) *FusionState {
 	calib := make([]*IMU, imuCount)
 	for i := 0; i < imuCount; i++ {
 		calib[i] = NewIMU()
 		calib[i].ID = i // Assign ID
 	}
 	cloud := NewPointCloud()
-	positions := make([]Point, imuCount)
-	velocities := make([]Point, imuCount)
-	now := time.Now()
-	noise := 0.1 // default noise level
-	return &IMUFusionSystem{
-		acq:        acq,
-		sync:       sync,
-		calib:      calib,
-		cloud:      cloud,
-		positions:  positions,
-		velocities: velocities,
-		lastTime:   now,
-		noiseLevel: noise,
-		imuCount:   imuCount,
-		stopChan:   make(chan struct{}),
-	}, nil
+	cloud.SetCapacity(defaultCloudHistory)
+	noise := cfg.Noise
+	filters := make([]Filter, imuCount)
+	extrinsics := make([]Extrinsics, imuCount)
+	for i := range filters {
+		filters[i] = NewEKF(noise, defaultBiasNoise, defaultInitialBiasStd)
+		extrinsics[i] = IdentityExtrinsics()
+	}
+	return &FusionState{
+		calib:       calib,
+		extrinsics:  extrinsics,
+		cloud:       cloud,
+		tracker:     NewFusionTracker(),
+		filters:     filters,
+		noiseLevel:  noise,
+		uncertainty: WhiteNoiseModel{NoiseLevel: noise},
+		logger:      cfg.Logger,
+		timer:       RealClock{},
+
+		deadReckoning: make([]float64, imuCount),
+		uncertainties: make([]float64, imuCount),
+		disabled:      make([]bool, imuCount),
+		imuCount:      imuCount,
+
+		refinementRadius: defaultRefinementRadius,
+		medianRefinement: true,
+		distanceWidth:    defaultDistanceWidth,
+		ageWidth:         defaultAgeWidth,
+
+		confidenceWeights: DefaultConfidenceWeights(),
+
+		stationarity: NewStationarityDetector(defaultStationaryWindow, defaultStationaryAccelLimit, defaultStationaryGyroLimit),
+		drift:        NewBiasDriftMonitor(imuCount, defaultDriftWindow, 0),
+		motion:       NewMotionMonitor(imuCount, defaultMotionHistory),
+	}
+}
+
+// printOutput writes a fused position to stdout.
+func printOutput(sample FusedSample) {
+	fmt.Printf("Fused position: (%.3f, %.3f)\n", sample.X, sample.Y)
 }
 
 // Start starts the data acquisition and processing loop.
 func (sys *IMUFusionSystem) Start() {
+	sys.pauseMu.Lock()
+	sys.running = true
+	sys.pauseMu.Unlock()
 	sys.acq.Start()
 	sys.stopWg.Add(1)
 	go sys.processDataLoop()
+	if sys.outputPeriod > 0 {
+		sys.stopWg.Add(1)
+		go func() {
+			defer sys.stopWg.Done()
+			sys.resampler.run(sys.outputPeriod, sys.output, sys.stopChan)
+		}()
+	}
 }
 
 // Stop stops the data acquisition and processing.
@@ -61,6 +263,783 @@ func (sys *IMUFusionSystem) Stop() {
 	close(sys.stopChan)
 	sys.acq.Stop()
 	sys.stopWg.Wait()
+	sys.pauseMu.Lock()
+	sys.running = false
+	sys.pauseMu.Unlock()
+}
+
+// SetRefinementRadius sets the point cloud search radius used to refine fused positions.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetRefinementRadius(radius float64) {
+	sys.refinementRadius = radius
+}
+
+// Uncertainties returns the per-IMU uncertainty radii used in the last frame. Each grows, by
+// default as noise * sqrt(t), over the time t since the IMU was last part of a good fusion; see
+// SetUncertaintyModel.
+func (sys *IMUFusionSystem) Uncertainties() []float64 {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	return append([]float64(nil), sys.uncertainties...)
+}
+
+// SetUncertaintyModel sets how each IMU's uncertainty radius grows with the time since its
+// position was last confirmed, which sizes its circle in geometric fusion. nil restores the
+// default WhiteNoiseModel.
+func (sys *IMUFusionSystem) SetUncertaintyModel(model UncertaintyModel) {
+	if model == nil {
+		model = WhiteNoiseModel{NoiseLevel: sys.noiseLevel}
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.uncertainty = model
+}
+
+// SetAdaptiveNoise scales each IMU's uncertainty radius by its recent motion, on top of the
+// UncertaintyModel; see AdaptiveNoise. The zero AdaptiveNoise disables scaling, the default.
+func (sys *IMUFusionSystem) SetAdaptiveNoise(a AdaptiveNoise) error {
+	if a == (AdaptiveNoise{}) {
+		sys.filterMu.Lock()
+		sys.adaptive = nil
+		sys.filterMu.Unlock()
+		return nil
+	}
+	if !(a.Reference > 0) || math.IsInf(a.Reference, 0) {
+		return fmt.Errorf("adaptive noise reference must be positive and finite, got %f", a.Reference)
+	}
+	if !(a.MinScale > 0) || !(a.MaxScale >= a.MinScale) || math.IsInf(a.MaxScale, 0) {
+		return fmt.Errorf("adaptive noise scales must satisfy 0 < min <= max < inf, got [%f, %f]", a.MinScale, a.MaxScale)
+	}
+	if a.Window <= 0 {
+		return fmt.Errorf("adaptive noise window must be positive, got %v", a.Window)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.adaptive = &a
+	return nil
+}
+
+// SetCloudHistory bounds the refinement point cloud to the n most recent points, so memory
+// and search cost stay constant over long runs and refinement only sees recent neighbours.
+// A bound <= 0 keeps every point.
+func (sys *IMUFusionSystem) SetCloudHistory(n int) {
+	sys.cloud.SetCapacity(n)
+	for _, group := range sys.groups {
+		group.cloud.SetCapacity(n)
+	}
+}
+
+// SetRefinementKernel sets how point cloud neighbours are weighted during refinement:
+// by a Gaussian in distance with standard deviation distanceWidth, and an exponential in age
+// with time constant ageWidth, so that close and recent points count more. A width <= 0
+// disables that kernel; disabling both averages neighbours equally. It should be called before Start.
+func (sys *IMUFusionSystem) SetRefinementKernel(distanceWidth float64, ageWidth time.Duration) {
+	sys.distanceWidth = distanceWidth
+	sys.ageWidth = ageWidth
+}
+
+// SetRefinementHuber refines to a mean of the neighbours instead of their geometric median:
+// a Huber-weighted mean (see PointCloud.HuberMean), in which neighbours further than delta from
+// the estimate are down-weighted, or for a delta <= 0 the plain kernel-weighted mean.
+// SetRefinementMedian(true) restores the median. It should be called before Start.
+func (sys *IMUFusionSystem) SetRefinementHuber(delta float64) {
+	sys.huberDelta = delta
+	sys.medianRefinement = false
+}
+
+// SetRefinementMedian refines to the kernel-weighted geometric median of the neighbours (see
+// PointCloud.GeometricMedian), the default, or when disabled to the mean set by
+// SetRefinementHuber. The median is more central than the mean when the neighbours are spread
+// asymmetrically about the fused position, and a stray neighbour pulls it no further than its
+// weight allows. It should be called before Start.
+func (sys *IMUFusionSystem) SetRefinementMedian(enabled bool) {
+	sys.medianRefinement = enabled
+}
+
+// SetStationarityDetection configures stationarity detection over a window of frames.
+// The body is stationary when the summed per-axis variance of acceleration and of angular
+// velocity are below accelLimit and gyroLimit. A window <= 0 disables detection.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetStationarityDetection(window int, accelLimit, gyroLimit float64) {
+	sys.stationarity = NewStationarityDetector(window, accelLimit, gyroLimit)
+}
+
+// IsStationary reports whether the body was detected at rest in the last processed frame.
+// While stationary, per-IMU velocities are driven to zero and the output position is held.
+func (sys *IMUFusionSystem) IsStationary() bool {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	return sys.stationary
+}
+
+// SetBiasDriftThreshold enables the bias drift monitor. While stationary, the mean calibrated
+// acceleration of each IMU over a sliding window should be zero; when its magnitude exceeds
+// threshold, the OnBiasDrift callback is invoked to suggest recalibration. 0 disables the monitor.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetBiasDriftThreshold(threshold float64) {
+	sys.drift.SetThreshold(threshold)
+}
+
+// OnBiasDrift registers fn to be called when an IMU's stationary bias drift exceeds the threshold
+// set by SetBiasDriftThreshold. It runs on the processing goroutine. It should be called before Start.
+func (sys *IMUFusionSystem) OnBiasDrift(fn func(imuID int, drift float64)) {
+	sys.onBiasDrift = fn
+}
+
+// OnFusionFailure registers fn to be called with a frame whose circles share no point at any
+// expansion, and the reason: FusionFailureDisjoint, FusionFailureAlphaExceeded or
+// FusionFailureDegenerate. The frame is still emitted as selected by SetFusionMode, except for
+// a non-finite fusion, which is dropped. With fusion groups it is called once per failing
+// group. It runs on the processing goroutine. It should be called before Start.
+func (sys *IMUFusionSystem) OnFusionFailure(fn func(frame []IMUData, reason string)) {
+	sys.onFusionFailure = fn
+}
+
+// GetEstimatedBias returns the online accelerometer bias estimate for the given IMU.
+// The pipeline is planar, so the Z component is unobserved and stays at its prior of zero.
+func (sys *IMUFusionSystem) GetEstimatedBias(imuID int) [3]float64 {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return [3]float64{}
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	return sys.filters[imuID].Bias()
+}
+
+// Heading returns the body heading in radians, the circular mean (see MeanAngle) of the heading
+// estimates of the enabled IMUs' filters, which integrate the body-frame yaw rate. ok is false
+// unless SetFilterKind selected FilterUKF, the only filter that estimates heading.
+func (sys *IMUFusionSystem) Heading() (heading float64, ok bool) {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	var headings []float64
+	for i, f := range sys.filters {
+		if u, isUKF := f.(*UKF); isUKF && !sys.disabled[i] {
+			headings = append(headings, u.Heading())
+		}
+	}
+	if len(headings) == 0 {
+		return 0, false
+	}
+	return MeanAngle(headings, nil), true
+}
+
+// SetExtrinsics sets the mounting of an IMU on the rigid body. Calibrated accelerations are
+// rotated into the body frame before integration, and the lever-arm offset is removed from
+// the IMU's position before fusion so that all IMUs estimate the same body reference point.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetExtrinsics(imuID int, rotation [2][2]float64, offset Point) error {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.extrinsics[imuID].Rotation = rotation
+	sys.extrinsics[imuID].Offset = offset
+	// The filter tracks the IMU's own position, which starts at its mounting point.
+	sys.filters[imuID].SetPosition([3]float64{offset.X, offset.Y, 0})
+	return nil
+}
+
+// SetRigidConstraint enforces the fixed distances between the IMUs. Each frame, the mounting
+// offsets of the IMUs present are fitted, by rotation and translation only, to their integrated
+// positions with a weighted Procrustes fit (see SetReferenceWeights), and each IMU's position is
+// replaced by its mounting point on the fitted body before fusion. Frames with fewer than three
+// IMUs, or whose offsets are collinear, are fused unconstrained. It should be called before Start.
+func (sys *IMUFusionSystem) SetRigidConstraint(enabled bool) {
+	sys.rigidConstraint = enabled
+}
+
+// SetReferenceWeights sets how much each IMU's position counts in the rigid constraint, so a
+// loosely mounted IMU can contribute less to the fit. weights[i] applies to IMU i; nil restores
+// equal weights.
+func (sys *IMUFusionSystem) SetReferenceWeights(weights []float64) error {
+	if weights != nil && len(weights) != sys.imuCount {
+		return fmt.Errorf("got %d reference weights, system has %d IMUs", len(weights), sys.imuCount)
+	}
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("reference weight %d must be finite and non-negative, got %f", i, w)
+		}
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.referenceWeights = append([]float64(nil), weights...)
+	return nil
+}
+
+// autoFitFrames is the number of stationary frames AutoFitReference averages.
+const autoFitFrames = 20
+
+// referenceFit accumulates the IMU positions of the stationary frames seen by AutoFitReference.
+type referenceFit struct {
+	remaining int     // stationary frames still to collect
+	sums      []Point // per-IMU sum of positions
+	counts    []int   // per-IMU number of positions
+}
+
+// add records an IMU position from a stationary frame.
+func (f *referenceFit) add(imuID int, p Point) {
+	f.sums[imuID].X += p.X
+	f.sums[imuID].Y += p.Y
+	f.counts[imuID]++
+}
+
+// AutoFitReference measures the reference geometry of the rigid constraint, the IMU offsets,
+// instead of relying on the configured layout. Over the next autoFitFrames stationary frames it
+// averages each IMU's own position, then sets every IMU's offset to its mean position relative to
+// the centroid of those means, which becomes the body reference point. Accelerations at rest say
+// nothing about spacing, so the positions must come from a known placement, such as Rezero with
+// each IMU at its surveyed position and zero offsets. The body axes are taken to be aligned with
+// the world frame during the fit; mounting rotations are kept. IMUs without samples in the fit,
+// such as disabled ones, keep their offsets. It may be called while the system is running.
+func (sys *IMUFusionSystem) AutoFitReference() {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.referenceFit = &referenceFit{
+		remaining: autoFitFrames,
+		sums:      make([]Point, sys.imuCount),
+		counts:    make([]int, sys.imuCount),
+	}
+}
+
+// advanceReferenceFit counts a stationary frame towards the pending AutoFitReference and applies
+// the fit once enough have been collected. The caller must hold filterMu.
+func (fs *FusionState) advanceReferenceFit() {
+	fit := fs.referenceFit
+	fit.remaining--
+	if fit.remaining > 0 {
+		return
+	}
+	fs.referenceFit = nil
+	var centroid Point
+	n := 0
+	for i, c := range fit.counts {
+		if c > 0 {
+			centroid.X += fit.sums[i].X / float64(c)
+			centroid.Y += fit.sums[i].Y / float64(c)
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	centroid.X /= float64(n)
+	centroid.Y /= float64(n)
+	for i, c := range fit.counts {
+		if c > 0 {
+			fs.extrinsics[i].Offset = Point{X: fit.sums[i].X/float64(c) - centroid.X, Y: fit.sums[i].Y/float64(c) - centroid.Y}
+		}
+	}
+	fs.logger.Printf("AutoFitReference: reference geometry fitted from %d IMUs\n", n)
+}
+
+// enforceRigid replaces the body reference positions of the present IMUs with those of the
+// weighted rigid fit of their mounting offsets, leaving them unchanged if the fit is ill-posed.
+// The caller must hold filterMu.
+func (fs *FusionState) enforceRigid(positions []Point, present []bool) {
+	var ids []int
+	var offsets, raw []Point
+	var weights []float64
+	for i := 0; i < fs.imuCount; i++ {
+		if !present[i] {
+			continue
+		}
+		offset := fs.extrinsics[i].Offset
+		ids = append(ids, i)
+		offsets = append(offsets, offset)
+		raw = append(raw, Point{X: positions[i].X + offset.X, Y: positions[i].Y + offset.Y})
+		w := 1.0
+		if fs.referenceWeights != nil {
+			w = fs.referenceWeights[i]
+		}
+		weights = append(weights, w)
+	}
+	if len(ids) < 3 || IsDegenerate(offsets) {
+		return
+	}
+	fit := ProcrustesFitWeightedOptions(offsets, raw, IsotropicWeights(weights), true)
+	if len(fit.Aligned) != len(ids) {
+		return
+	}
+	for k, i := range ids {
+		positions[i] = Point{X: fit.Aligned[k].X - offsets[k].X, Y: fit.Aligned[k].Y - offsets[k].Y}
+	}
+}
+
+// CalibrateGyro estimates an IMU's gyro bias from angular velocity samples in rad/s taken at
+// rest. The bias is subtracted from every later sample. See IMU.CalibrateGyroBias. It should be
+// called before Start.
+func (sys *IMUFusionSystem) CalibrateGyro(imuID int, samples [][3]float64) (CalibrationResult, error) {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return CalibrationResult{}, fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	return sys.calib[imuID].CalibrateGyroBias(samples), nil
+}
+
+// CalibrateIMU calibrates an IMU from raw 3-axis accelerometer samples taken at rest. The samples
+// are leveled with the IMU's tilt, as in fusion, so that only the bias remains in the offsets and
+// not the projection of gravity. See IMU.Calibrate. It should be called before Start.
+func (sys *IMUFusionSystem) CalibrateIMU(imuID int, samples [][3]float64) (CalibrationResult, error) {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return CalibrationResult{}, fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	ext := sys.extrinsics[imuID]
+	leveled := make([][]float64, len(samples))
+	for i, s := range samples {
+		x, y := ext.Level(s[0], s[1], s[2])
+		leveled[i] = []float64{x, y}
+	}
+	return sys.calib[imuID].Calibrate(leveled), nil
+}
+
+// SetTilt sets the roll and pitch of an IMU from the horizontal plane, in radians, for example
+// as estimated by TiltFromGravity. Accelerations are projected onto the horizontal plane using
+// all three axes before calibration and the mounting rotation, so gravity does not leak into the
+// 2D motion. Calibration offsets then apply to leveled accelerations, so a tilted IMU should be
+// calibrated with CalibrateIMU after SetTilt. It should be called before Start.
+func (sys *IMUFusionSystem) SetTilt(imuID int, roll, pitch float64) error {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.extrinsics[imuID].Roll = roll
+	sys.extrinsics[imuID].Pitch = pitch
+	return nil
+}
+
+// SetLeverArm sets the position of an IMU relative to the body's center of rotation, in the
+// body frame. While the body turns, the centripetal and tangential accelerations this causes at
+// the IMU are subtracted before integration, using the body yaw rate averaged over the IMUs'
+// gyros and its change since the previous frame. This is usually the extrinsics offset when the
+// body reference point is the center of rotation. It should be called before Start.
+func (sys *IMUFusionSystem) SetLeverArm(imuID int, r Point) error {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.extrinsics[imuID].LeverArm = r
+	return nil
+}
+
+// MotionRMS returns the root mean square of each IMU's calibrated, body-frame acceleration
+// over the window before the latest sample, or 0 for an IMU with no samples in it. Up to the
+// last defaultMotionHistory samples of each IMU are kept, which bounds the window. It can drive
+// adaptive tuning, such as raising the filters' process noise while the body is moving.
+func (sys *IMUFusionSystem) MotionRMS(window time.Duration) []float64 {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	return sys.motion.RMS(window)
+}
+
+// SetConfidenceWeights sets the weights of the terms of the FusionConfidence reported with each
+// fused sample. See DefaultConfidenceWeights. It should be called before Start.
+func (sys *IMUFusionSystem) SetConfidenceWeights(w ConfidenceWeights) error {
+	if w.Agreement < 0 || w.Alpha < 0 || w.Residual < 0 || w.Agreement+w.Alpha+w.Residual <= 0 {
+		return fmt.Errorf("confidence weights must be non-negative and not all zero, got %+v", w)
+	}
+	sys.confidenceWeights = w
+	return nil
+}
+
+// SetAccelFilter low-pass filters each IMU's calibrated acceleration before it is integrated,
+// with a first-order filter of the given cutoff frequency in Hz, attenuating sensor noise above
+// it. The cutoff should lie well above the frequencies of the motion being tracked, whose
+// accelerations are otherwise delayed. A cutoff <= 0 disables the filter, the default.
+func (sys *IMUFusionSystem) SetAccelFilter(cutoffHz float64) {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	if cutoffHz <= 0 {
+		sys.accelFilters = nil
+		return
+	}
+	sys.accelFilters = make([]*LowPassFilter, sys.imuCount)
+	for i := range sys.accelFilters {
+		sys.accelFilters[i] = NewLowPassFilter(cutoffHz)
+	}
+}
+
+// SetOutputSmoothing smooths the refined output with an AlphaBetaFilter of the given gains, and
+// reports the smoothed velocity in FusedSample. Smaller gains reduce jitter but respond more
+// slowly to changes in motion. An alpha <= 0 disables smoothing, the default.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetOutputSmoothing(alpha, beta float64) {
+	if alpha <= 0 {
+		sys.smoother = nil
+	} else {
+		sys.smoother = NewAlphaBetaFilter(alpha, beta)
+	}
+	for _, group := range sys.groups {
+		group.smoother = nil
+		if sys.smoother != nil {
+			group.smoother = NewAlphaBetaFilter(alpha, beta)
+		}
+	}
+}
+
+// Rezero resets accumulated drift by placing each IMU back on a known anchor, for example when
+// the body returns to a marked home position. positions[i] is the body reference point
+// according to IMU i, so its filter is moved to that point plus the IMU's lever arm; velocities
+// are zeroed and uncertainties restart from zero, while bias estimates are kept. The point cloud,
+// which holds the drifted trajectory, is cleared, and CurrentPosition reports the anchor until the
+// next frame. It may be called while the system is running; a frame in progress completes first,
+// so it must not be called from the output, metrics or bias drift callbacks.
+func (sys *IMUFusionSystem) Rezero(positions []Point) error {
+	if len(positions) != sys.imuCount {
+		return fmt.Errorf("got %d anchor positions, system has %d IMUs", len(positions), sys.imuCount)
+	}
+	sys.frameMu.Lock()
+	defer sys.frameMu.Unlock()
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+
+	var mean Vec2
+	for i, f := range sys.filters {
+		state := f.State()
+		offset := sys.extrinsics[i].Offset
+		state.Position = [3]float64{positions[i].X + offset.X, positions[i].Y + offset.Y, 0}
+		state.Velocity = [3]float64{}
+		if err := f.SetState(state); err != nil {
+			return err
+		}
+		sys.deadReckoning[i] = 0
+		mean.X += positions[i].X / float64(sys.imuCount)
+		mean.Y += positions[i].Y / float64(sys.imuCount)
+	}
+	// The next frame is gated and held relative to the anchor rather than the drifted position.
+	sys.lastFused = mean
+	sys.hasFused = true
+	sys.held = Point{X: mean.X, Y: mean.Y}
+	sys.currentMu.Lock()
+	sys.current = Position{X: mean.X, Y: mean.Y, R: sys.current.R}
+	sys.hasCurrent = true
+	sys.currentMu.Unlock()
+	if sys.smoother != nil {
+		sys.smoother.Reset()
+	}
+	sys.cloud.Clear()
+	sys.resetGroups(positions)
+	return nil
+}
+
+// RezeroAll is Rezero with every IMU placed on the common body reference point p.
+func (sys *IMUFusionSystem) RezeroAll(p Point) error {
+	positions := make([]Point, sys.imuCount)
+	for i := range positions {
+		positions[i] = p
+	}
+	return sys.Rezero(positions)
+}
+
+// DisableIMU excludes a failed IMU from fusion while the system runs. The synchronizer stops
+// waiting for its samples, so frames are formed from the remaining IMUs, and its filter is
+// frozen. At least one IMU must remain enabled.
+func (sys *IMUFusionSystem) DisableIMU(imuID int) error {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	if sys.disabled[imuID] {
+		return nil
+	}
+	enabled := 0
+	for _, d := range sys.disabled {
+		if !d {
+			enabled++
+		}
+	}
+	if enabled == 1 {
+		return fmt.Errorf("cannot disable IMU %d, it is the only one enabled", imuID)
+	}
+	sys.disabled[imuID] = true
+	sys.sync.SetEnabled(imuID, false)
+	return nil
+}
+
+// EnableIMU returns an IMU disabled by DisableIMU to fusion. Its filter was frozen while the
+// body moved on, so it is re-seeded at its mounting point on the last fused position, with
+// the mean velocity of the enabled IMUs, and its uncertainty restarts from zero.
+func (sys *IMUFusionSystem) EnableIMU(imuID int) error {
+	if imuID < 0 || imuID >= sys.imuCount {
+		return fmt.Errorf("IMU ID %d out of range [0, %d)", imuID, sys.imuCount)
+	}
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	if !sys.disabled[imuID] {
+		return nil
+	}
+	if sys.hasFused {
+		state := sys.filters[imuID].State()
+		offset := sys.extrinsics[imuID].Offset
+		state.Position = [3]float64{sys.lastFused.X + offset.X, sys.lastFused.Y + offset.Y, 0}
+		state.Velocity = [3]float64{}
+		var n float64
+		for i, f := range sys.filters {
+			if sys.disabled[i] {
+				continue
+			}
+			v := f.Velocity()
+			for axis := range v {
+				state.Velocity[axis] += v[axis]
+			}
+			n++
+		}
+		for axis := range state.Velocity {
+			state.Velocity[axis] /= n
+		}
+		if err := sys.filters[imuID].SetState(state); err != nil {
+			return err
+		}
+	}
+	sys.deadReckoning[imuID] = 0
+	sys.disabled[imuID] = false
+	sys.sync.SetEnabled(imuID, true)
+	return nil
+}
+
+// SetStrictTimestamps controls frames whose timestamp does not advance past the previous frame.
+// Such frames are always counted in Metrics.NonMonotonicFrames. By default they are integrated
+// with a negligible time step; in strict mode they are skipped entirely.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetStrictTimestamps(strict bool) {
+	sys.strictTimestamps = strict
+}
+
+// SetClock sets the clock samples are stamped with on receipt and IMU health is judged against,
+// the system clock by default. Samples without a device timestamp are integrated over the
+// intervals between these stamps, so a FakeClock makes their time steps deterministic. Frame
+// latency is measured on it too. It should be called before Start.
+func (sys *IMUFusionSystem) SetClock(clock Clock) {
+	sys.clock = clock
+	sys.timer = clock
+	sys.acq.SetClock(clock)
+	sys.sync.SetClock(clock)
+	sys.lastTime = clock.Now()
+}
+
+// SetGatingThreshold enables outlier gating before fusion. Each IMU position whose squared
+// Mahalanobis distance from the previous fused position exceeds chi2 is excluded from the frame.
+// Typical values are 9.21 (99%) or 13.82 (99.9%) for two degrees of freedom; 0 disables gating.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetGatingThreshold(chi2 float64) {
+	sys.gatingThreshold = chi2
+}
+
+// SetFilterKind replaces every per-IMU filter with a fresh one of the given kind, keeping
+// the current position estimates. It should be called before Start.
+func (sys *IMUFusionSystem) SetFilterKind(kind FilterKind) {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	for i, old := range sys.filters {
+		var f Filter
+		switch kind {
+		case FilterUKF:
+			ukf := NewUKF(sys.noiseLevel, defaultBiasNoise, defaultInitialBiasStd, defaultGyroNoise, DefaultUKFParams())
+			ukf.SetLogger(sys.logger)
+			f = ukf
+		default:
+			f = NewEKF(sys.noiseLevel, defaultBiasNoise, defaultInitialBiasStd)
+		}
+		f.SetPosition(old.Position())
+		sys.filters[i] = f
+	}
+}
+
+// SetFusionMode sets what is fused when the per-IMU circles share no point; see FusionMode.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetFusionMode(mode FusionMode) {
+	sys.tracker.SetMode(mode)
+	for _, group := range sys.groups {
+		group.tracker.SetMode(mode)
+	}
+}
+
+// SetRecordAlphaProbes enables recording the alphas evaluated by the geometric fusion of each
+// frame; see AlphaProbes. It is a debugging aid and off by default. IMU groups are fused by
+// trackers of their own, whose probes are not recorded.
+func (sys *IMUFusionSystem) SetRecordAlphaProbes(enabled bool) {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	sys.tracker.SetRecordProbes(enabled)
+}
+
+// AlphaProbes returns the alphas evaluated by the geometric fusion of the last frame fused, as
+// FusionTracker.Probes, or nil unless SetRecordAlphaProbes is enabled. It is safe to call while
+// the system is running.
+func (sys *IMUFusionSystem) AlphaProbes() []AlphaProbe {
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	return sys.tracker.Probes()
+}
+
+// SetAngularUnit declares the unit the source reports angular velocity in; samples are
+// converted to rad/s on ingest. It should be called before Start.
+func (sys *IMUFusionSystem) SetAngularUnit(unit AngularUnit) {
+	sys.acq.SetAngularUnit(unit)
+}
+
+// SetBackpressure bounds the samples waiting to be fused, so a stalled processing loop drops
+// samples or blocks the source rather than buffering without bound. See
+// DataAcquisition.SetBackpressure. It should be called before Start.
+func (sys *IMUFusionSystem) SetBackpressure(capacity int, policy BackpressurePolicy) {
+	sys.acq.SetBackpressure(capacity, policy)
+}
+
+// SetUnitScale sets the factor applied to emitted positions, velocities and residuals. Positions
+// are integrated in the units of the ingested acceleration times seconds squared, so
+// accelerations in m/s^2 give meters; the scale converts them to other units, or applies a
+// scale found by calibrating the accelerometers. Only emitted samples are scaled: CurrentPosition,
+// FuseTrajectory and Rezero use the internal units. It should be called before Start.
+func (sys *IMUFusionSystem) SetUnitScale(scale float64) error {
+	if scale <= 0 || math.IsInf(scale, 0) || math.IsNaN(scale) {
+		return fmt.Errorf("unit scale must be positive and finite, got %f", scale)
+	}
+	sys.unitScale = scale
+	return nil
+}
+
+// SetOrigin places the internal origin, where positions start accumulating, at p in the world
+// frame: emitted positions are shifted by p after SetUnitScale is applied, so p is in emitted
+// units. Like the unit scale it only affects emitted samples: CurrentPosition, FuseTrajectory and
+// Rezero anchors stay in the internal frame, so a body rezeroed to anchor a is emitted at
+// a*scale + p. The rigid constraint fits IMU positions relative to each other and is unaffected.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetOrigin(p Point) {
+	sys.origin = p
+}
+
+// SetFusionRate fuses at most hz times per second of sample time, rather than once per aligned
+// frame, to save CPU when the body moves slowly. The frames in between are averaged per IMU and
+// fused as one frame stamped with the latest samples. A rate <= 0 fuses every frame, the default.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetFusionRate(hz float64) {
+	if hz <= 0 {
+		sys.averager = nil
+		return
+	}
+	sys.averager = newFrameAverager(time.Duration(float64(time.Second) / hz))
+}
+
+// SetOutputRate emits fused positions at a fixed rate instead of once per frame.
+// Each tick emits the most recent fused position; if no new frame has arrived since the
+// previous tick, the last value is repeated with Stale set. A rate <= 0 restores per-frame output.
+// It should be called before Start.
+func (sys *IMUFusionSystem) SetOutputRate(hz float64) {
+	if hz <= 0 {
+		sys.outputPeriod = 0
+		return
+	}
+	sys.outputPeriod = time.Duration(float64(time.Second) / hz)
+}
+
+// CurrentPosition returns the position fused and refined from the latest frame, with R set to
+// its fused alpha. ok is false until a frame has been fused.
+// It is safe to call while the system is running.
+func (fs *FusionState) CurrentPosition() (Position, bool) {
+	fs.currentMu.Lock()
+	defer fs.currentMu.Unlock()
+	return fs.current, fs.hasCurrent
+}
+
+// Metrics returns a snapshot of the pipeline counters.
+func (sys *IMUFusionSystem) Metrics() Metrics {
+	return sys.metricsSnapshot()
+}
+
+// Statuses returns the health of each IMU: when it last reported, its sample rate and noise, and
+// whether it is live, stale after SetStaleTimeout without samples, faulted by non-finite or
+// out-of-range readings, or failed once a FailingSource has given up. It is safe to call while
+// the system is running.
+func (sys *IMUFusionSystem) Statuses() []IMUStatus {
+	now := sys.clock.Now()
+	failing, _ := sys.acq.source.(FailingSource)
+	failed := failing != nil && failing.Err() != nil
+	statuses := make([]IMUStatus, sys.imuCount)
+	for i := range statuses {
+		statuses[i] = sys.sync.Health().Status(i, now)
+		if failed {
+			statuses[i].State = IMUFailed
+		}
+	}
+	return statuses
+}
+
+// SetStaleTimeout sets how long an IMU may go without a sample before Statuses reports it stale,
+// 100ms by default, and how long a fault keeps it reported faulted.
+func (sys *IMUFusionSystem) SetStaleTimeout(d time.Duration) {
+	sys.sync.Health().SetStaleTimeout(d)
+}
+
+// SetFaultLimit makes Statuses report an IMU faulted when its acceleration magnitude exceeds
+// limit, as it does for non-finite readings. A limit <= 0 disables the check, the default.
+func (sys *IMUFusionSystem) SetFaultLimit(limit float64) {
+	sys.sync.Health().SetFaultLimit(limit)
+}
+
+// LatencyHistogram returns how long fusing and refining each frame has taken, bucketed from
+// 50us to 100ms with a final unbounded bucket, exposing tail latency that
+// Metrics.AvgFusionDuration hides. It is safe to call while the system is running.
+func (sys *IMUFusionSystem) LatencyHistogram() []Bucket {
+	return sys.metrics.latencyHistogram()
+}
+
+// metricsSnapshot adds the samples rejected by the synchronizer to the pipeline counters.
+func (sys *IMUFusionSystem) metricsSnapshot() Metrics {
+	m := sys.metrics.snapshot()
+	m.InvalidSamples += sys.sync.Invalid()
+	m.DroppedSamples = sys.acq.Dropped()
+	return m
+}
+
+// SetMetricsCallback registers fn to be called with updated metrics after every processed frame.
+// It runs on the processing goroutine, so it should return quickly. It should be called before Start.
+func (sys *IMUFusionSystem) SetMetricsCallback(fn func(Metrics)) {
+	sys.metricsCallback = fn
+}
+
+// Pause stops processDataLoop from fusing frames without stopping acquisition.
+// Aligned frames are buffered meanwhile, up to a cap, and processed in order on Resume.
+// Pause returns once any frames already being processed have finished, so it must not be
+// called from the output or metrics callbacks.
+func (sys *IMUFusionSystem) Pause() {
+	sys.pauseMu.Lock()
+	sys.paused = true
+	sys.pauseMu.Unlock()
+
+	sys.frameMu.Lock()
+	defer sys.frameMu.Unlock()
+}
+
+// Resume continues fusion after Pause, starting with any buffered frames.
+func (sys *IMUFusionSystem) Resume() {
+	sys.pauseMu.Lock()
+	defer sys.pauseMu.Unlock()
+	sys.paused = false
+}
+
+func (sys *IMUFusionSystem) isPaused() bool {
+	sys.pauseMu.Lock()
+	defer sys.pauseMu.Unlock()
+	return sys.paused
+}
+
+// isRunning reports whether processDataLoop may be processing frames.
+func (sys *IMUFusionSystem) isRunning() bool {
+	sys.pauseMu.Lock()
+	defer sys.pauseMu.Unlock()
+	return sys.running && !sys.paused
+}
+
+// bufferFrames appends frames to the pause buffer, dropping the oldest beyond maxPending.
+func (sys *IMUFusionSystem) bufferFrames(frames [][]IMUData) {
+	sys.pending = append(sys.pending, frames...)
+	if over := len(sys.pending) - sys.maxPending; over > 0 {
+		sys.metrics.recordDropped(over)
+		sys.pending = append(sys.pending[:0], sys.pending[over:]...)
+	}
 }
 
 // processDataLoop runs the main fusion logic.
@@ -75,7 +1054,16 @@ func (sys *IMUFusionSystem) processDataLoop() {
 
 		// Get aligned data frames from the synchronizer
 		alignedFrames := sys.sync.GetAlignedData(sys.imuCount)
+		sys.frameMu.Lock()
+		if sys.isPaused() {
+			sys.bufferFrames(alignedFrames)
+			alignedFrames = nil
+		} else if len(sys.pending) > 0 {
+			alignedFrames = append(sys.pending, alignedFrames...)
+			sys.pending = nil
+		}
 		if len(alignedFrames) == 0 {
+			sys.frameMu.Unlock()
 			select {
 			case <-sys.stopChan:
 				return
@@ -85,69 +1073,538 @@ func (sys *IMUFusionSystem) processDataLoop() {
 		}
 
 		for _, frame := range alignedFrames {
-			// Assuming frame is sorted by IMUID or has a known order
-			// Use the timestamp from the first data point in the frame
-			now := frame[0].Timestamp
-			dt := now.Sub(sys.lastTime).Seconds()
-			if dt <= 0 { // Avoid division by zero or negative time steps
-				dt = 1e-9 // Use a very small positive dt
-			}
-			sys.lastTime = now
-
-			currentPositions := make([]Point, sys.imuCount)
-			// Integrate data for each IMU in the aligned frame
-			for _, data := range frame {
-				imuIndex := data.IMUID // Use IMUID to index into calibration/state arrays
-				if imuIndex >= sys.imuCount {
-					fmt.Printf("Error: IMUID %d out of bounds\n", imuIndex)
-					continue // Skip data point if ID is invalid
-				}
-
-				// Calibrate acceleration
-				ax, ay := sys.calib[imuIndex].ApplyCalibration(data.Acceleration[0], data.Acceleration[1])
-
-				// Integrate velocity and position
-				sys.velocities[imuIndex].X += ax * dt
-				sys.velocities[imuIndex].Y += ay * dt
-				sys.positions[imuIndex].X += sys.velocities[imuIndex].X * dt
-				sys.positions[imuIndex].Y += sys.velocities[imuIndex].Y * dt
-
-				currentPositions[imuIndex] = sys.positions[imuIndex]
-
-				// Add to point cloud
-				sys.cloud.AddPoint(sys.positions[imuIndex].X, sys.positions[imuIndex].Y)
-			}
+			sys.processFrame(frame)
+		}
+		sys.frameMu.Unlock()
+	}
+}
 
-			// Estimate uncertainties per IMU
-			uncertainties := make([]float64, sys.imuCount)
-			for i := 0; i < sys.imuCount; i++ {
-				u := NewUncertainty(sys.noiseLevel, dt)
-				uncertainties[i] = u.Estimate()
+// processFrame fuses a single aligned frame and emits the result. Under SetFusionRate, frames
+// are accumulated until the next fusion is due.
+func (sys *IMUFusionSystem) processFrame(frame []IMUData) {
+	if sys.averager != nil {
+		averaged, ok := sys.averager.add(frame)
+		if !ok {
+			return
+		}
+		frame = averaged
+	}
+	results := sys.fuseFrame(frame)
+	if len(results) == 0 {
+		return
+	}
+	for _, r := range results {
+		sample := r.sample
+		if sys.unitScale != 1 {
+			sample.X *= sys.unitScale
+			sample.Y *= sys.unitScale
+			sample.VX *= sys.unitScale
+			sample.VY *= sys.unitScale
+			sample.Residual *= sys.unitScale
+		}
+		sample.X += sys.origin.X
+		sample.Y += sys.origin.Y
+		if sys.stages.active(StageFusedPosition) {
+			sys.stages.publish(StageFusedPosition, sample)
+		}
+		if sys.outputPeriod > 0 && sys.groups == nil {
+			sys.resampler.update(sample)
+		} else {
+			sys.output(sample)
+		}
+	}
+	if sys.metricsCallback != nil {
+		sys.metricsCallback(sys.metricsSnapshot())
+	}
+}
+
+// FuseTrajectory runs the integration and fusion pipeline over recorded aligned frames,
+// synchronously and without emitting output, and returns the refined position of each frame
+// with R set to its fusion alpha. Frames skipped under SetStrictTimestamps are omitted. With IMU
+// groups, it returns the position of the first group fused in each frame.
+// On a system that has not processed frames, integration starts at the first frame's time.
+// It returns nil while the processing loop is running; pause or stop it first.
+func (sys *IMUFusionSystem) FuseTrajectory(frames [][]IMUData) []Position {
+	sys.frameMu.Lock()
+	defer sys.frameMu.Unlock()
+	if sys.isRunning() {
+		sys.logger.Printf("FuseTrajectory: Warning - system is running, pause or stop it first.\n")
+		return nil
+	}
+	if !sys.haveFrame && len(frames) > 0 && len(frames[0]) > 0 {
+		sys.lastTime = frames[0][0].SampleTime()
+	}
+	trajectory := make([]Position, 0, len(frames))
+	for _, frame := range frames {
+		if results := sys.fuseFrame(frame); len(results) > 0 {
+			r := results[0]
+			trajectory = append(trajectory, Position{X: r.sample.X, Y: r.sample.Y, R: r.alpha})
+		}
+	}
+	return trajectory
+}
+
+// fusedFrame is the refined sample fused from a frame, with its fusion alpha.
+type fusedFrame struct {
+	sample FusedSample
+	alpha  float64
+}
+
+// fuseFrame integrates, fuses, and refines a single aligned frame. It returns one result, or one
+// per group fused with IMU groups, and none if the frame was skipped.
+func (fs *FusionState) fuseFrame(frame []IMUData) []fusedFrame {
+	start := fs.timer.Now()
+	if len(frame) == 0 {
+		return nil
+	}
+	// Assuming frame is sorted by IMUID or has a known order
+	// Use the sample time from the first data point in the frame
+	now := frame[0].SampleTime()
+	dt := now.Sub(fs.lastTime).Seconds()
+	clamped := dt <= 0
+	if clamped { // Avoid division by zero or negative time steps
+		if fs.haveFrame {
+			fs.metrics.recordNonMonotonic()
+			if fs.strictTimestamps {
+				fs.logger.Printf("Warning: skipping frame at %v, not after previous frame at %v\n", now, fs.lastTime)
+				return nil
 			}
+			fs.logger.Printf("Warning: frame at %v is not after previous frame at %v\n", now, fs.lastTime)
+		}
+		dt = 1e-9 // Use a very small positive dt
+	}
+	fs.advanceLastTime(now)
+	return fs.fuse(start, frame, now, dt, clamped)
+}
+
+// advanceLastTime records now as the time of the last frame processed. After the first frame,
+// lastTime only moves forward, so a frame integrated despite a non-monotonic timestamp does
+// not pull the time step of the next frame back to it.
+func (fs *FusionState) advanceLastTime(now time.Time) {
+	if !fs.haveFrame || now.After(fs.lastTime) {
+		fs.lastTime = now
+	}
+	fs.haveFrame = true
+}
+
+// FuseFrame runs one aligned frame through the fusion math of state, as the processing loop of
+// IMUFusionSystem does: each sample is leveled, calibrated and integrated over dt seconds, the
+// uncertainties grow, the IMU positions are fused geometrically, and the result is refined
+// against the point cloud. The frame's sample time stamps the point cloud and motion history
+// but, unlike in the processing loop, does not set the time step. It returns the refined
+// position with R the fusion alpha, or, with IMU groups, that of the first group fused, and the
+// zero Position if nothing could be fused. A dt <= 0 is treated as a negligible step. FuseFrame
+// must not be called on the state of a running IMUFusionSystem.
+func FuseFrame(state *FusionState, frame []IMUData, dt float64) Position {
+	start := state.timer.Now()
+	if len(frame) == 0 {
+		return Position{}
+	}
+	clamped := dt <= 0
+	if clamped {
+		dt = 1e-9
+	}
+	now := frame[0].SampleTime()
+	state.advanceLastTime(now)
+	results := state.fuse(start, frame, now, dt, clamped)
+	if len(results) == 0 {
+		return Position{}
+	}
+	return Position{X: results[0].sample.X, Y: results[0].sample.Y, R: results[0].alpha}
+}
+
+// fuse integrates a frame at time now over dt seconds, then fuses and refines it. clamped
+// reports that dt was clamped after a non-monotonic timestamp.
+func (fs *FusionState) fuse(start time.Time, frame []IMUData, now time.Time, dt float64, clamped bool) []fusedFrame {
+	stationary := fs.stationarity.Update(frame)
 
-			// Geometric fusion
-			posList := make([]Position, sys.imuCount)
-			for i := 0; i < sys.imuCount; i++ {
-				posList[i] = Position{X: currentPositions[i].X, Y: currentPositions[i].Y, R: uncertainties[i]}
+	if !stationary {
+		fs.drift.Reset()
+	}
+	var drifts []driftEvent
+
+	fs.filterMu.Lock()
+	wasStationary := fs.stationary
+	fs.stationary = stationary
+	currentPositions, present := fs.scratch.frame(fs.imuCount)
+	omega, alpha := fs.bodyRotation(frame, dt, clamped)
+	// Integrate data for each IMU in the aligned frame
+	for _, data := range frame {
+		imuIndex := data.IMUID // Use IMUID to index into calibration/state arrays
+		if imuIndex < 0 || imuIndex >= fs.imuCount {
+			fs.logger.Printf("Error: IMUID %d out of bounds\n", imuIndex)
+			continue // Skip data point if ID is invalid
+		}
+		if fs.disabled[imuIndex] {
+			continue
+		}
+		if !data.Finite() {
+			// Frames from FuseTrajectory or Restore bypass the synchronizer's ingest check.
+			fs.metrics.recordInvalid()
+			continue
+		}
+		if fs.stages.active(StageRawSample) {
+			fs.stages.publish(StageRawSample, data)
+		}
+		present[imuIndex] = true
+		fs.scratch.quality[imuIndex] = data.weight()
+
+		// Level the acceleration, calibrate it, and rotate it into the body frame. Leveling comes
+		// first so that gravity is removed from all three axes before the offsets are applied.
+		ext := fs.extrinsics[imuIndex]
+		ax, ay := ext.Level(data.Acceleration[0], data.Acceleration[1], data.Acceleration[2])
+		ax, ay = fs.calib[imuIndex].ApplyCalibration(ax, ay)
+		if fs.accelFilters != nil {
+			a := fs.accelFilters[imuIndex].Update(Point{X: ax, Y: ay}, dt)
+			ax, ay = a.X, a.Y
+		}
+		gyro := fs.calib[imuIndex].ApplyGyroCalibration(data.AngularVelocity)
+		if fs.stages.active(StageCalibratedSample) {
+			fs.stages.publish(StageCalibratedSample, CalibratedSample{IMUID: imuIndex, Timestamp: now, Acceleration: Point{X: ax, Y: ay}, AngularVelocity: gyro})
+		}
+		if stationary {
+			if drift, crossed := fs.drift.Add(imuIndex, ax, ay); crossed {
+				drifts = append(drifts, driftEvent{imuID: imuIndex, drift: drift})
 			}
-			_, fused := GeometricFusion2D(posList)
-
-			// Point cloud refinement
-			neighbors := sys.cloud.RadiusSearch(fused.X, fused.Y, fused.R)
-			sumX, sumY := 0.0, 0.0
-			count := len(neighbors)
-			for _, pt := range neighbors {
-				sumX += pt.X
-				sumY += pt.Y
+		}
+		ax, ay = ext.Rotate(ax, ay)
+		rx, ry := ext.RotationalAcceleration(omega, alpha)
+		ax, ay = ax-rx, ay-ry
+		fs.motion.Add(imuIndex, now, ax, ay)
+
+		// Integrate velocity and position, correcting for the estimated bias
+		filter := fs.filters[imuIndex]
+		filter.Predict([3]float64{ax, ay, 0}, gyro, dt)
+		if stationary {
+			// Zero-velocity update
+			filter.UpdateVelocity(0, 0, zeroVelocityVariance)
+			filter.UpdateVelocity(1, 0, zeroVelocityVariance)
+		}
+		p := filter.Position()
+		if stationary && fs.referenceFit != nil {
+			fs.referenceFit.add(imuIndex, Point{X: p[0], Y: p[1]})
+		}
+
+		// Remove the lever arm so every IMU reports the body reference point
+		currentPositions[imuIndex] = Point{X: p[0] - ext.Offset.X, Y: p[1] - ext.Offset.Y}
+		if fs.stages.active(StagePerIMUPosition) {
+			fs.stages.publish(StagePerIMUPosition, IMUPosition{IMUID: imuIndex, Timestamp: now, Position: currentPositions[imuIndex]})
+		}
+	}
+	if stationary && fs.referenceFit != nil {
+		fs.advanceReferenceFit()
+	}
+	step := frameStep{now: now, dt: dt, clamped: clamped, stationary: stationary, wasStationary: wasStationary}
+	if fs.groups != nil {
+		return fs.fuseGroups(start, frame, step, currentPositions, present, drifts)
+	}
+	if fs.rigidConstraint {
+		fs.enforceRigid(currentPositions, present)
+	}
+
+	// Add to point cloud
+	for i, ok := range present {
+		if ok {
+			fs.cloud.AddPointAt(currentPositions[i].X, currentPositions[i].Y, now)
+		}
+	}
+
+	fs.updateUncertainties(dt)
+	fused, ok := fs.fuseIMUs(fs.tracker, &fs.lastFused, &fs.hasFused, currentPositions, present, fs.imuCount, now)
+	fs.filterMu.Unlock()
+	fs.notifyFusionFailure(frame, fused.failure)
+	if !ok {
+		return nil
+	}
+	fs.notifyBiasDrift(drifts)
+
+	sample := fs.refineAndHold(fs.cloud, fs.smoother, &fs.held, fused, step)
+	fs.metrics.recordFrame(fs.timer.Now().Sub(start), fs.cloud.Len())
+
+	fs.currentMu.Lock()
+	fs.current = Position{X: sample.X, Y: sample.Y, R: fused.alpha}
+	fs.hasCurrent = true
+	fs.currentMu.Unlock()
+
+	return []fusedFrame{{sample: sample, alpha: fused.alpha}}
+}
+
+// frameScratch holds the slices fuseFrame and fuseIMUs would otherwise allocate every frame.
+// Their contents are only valid until the next frame.
+type frameScratch struct {
+	positions []Point    // body reference position of each IMU
+	present   []bool     // IMUs integrated in the frame
+	quality   []float64  // IMUData.Quality of each IMU's sample
+	members   []bool     // present IMUs of the group being fused
+	ids       []int      // IMU ID of each entry of posList
+	posList   []Position // positions taking part in fusion
+	included  []bool     // entries of posList passing the gate
+	motion    []float64  // per-IMU RMS acceleration for AdaptiveNoise
+}
+
+// frame returns zeroed position and presence buffers for imuCount IMUs.
+func (s *frameScratch) frame(imuCount int) ([]Point, []bool) {
+	if cap(s.positions) < imuCount {
+		s.positions = make([]Point, imuCount)
+		s.present = make([]bool, imuCount)
+		s.quality = make([]float64, imuCount)
+		s.members = make([]bool, imuCount)
+	}
+	s.positions, s.present, s.members = s.positions[:imuCount], s.present[:imuCount], s.members[:imuCount]
+	s.quality = s.quality[:imuCount]
+	for i := range s.positions {
+		s.positions[i] = Point{}
+		s.present[i] = false
+		s.quality[i] = 1
+	}
+	return s.positions, s.present
+}
+
+// frameStep is the timing and stationarity of the frame being fused.
+type frameStep struct {
+	now                       time.Time
+	dt                        float64
+	clamped                   bool // dt was clamped after a non-monotonic timestamp
+	stationary, wasStationary bool
+}
+
+// fusion is the geometric fusion of the IMUs present in a frame, before refinement.
+type fusion struct {
+	position   Vec2
+	velocity   Vec2 // inverse-variance weighted mean of the fused IMUs' velocities
+	alpha      float64
+	residual   float64
+	confidence float64
+	failure    string // why the circles share no point, "" if they do; see OnFusionFailure
+}
+
+// updateUncertainties grows each IMU's uncertainty over the time since it was last confirmed.
+// The caller must hold filterMu.
+func (fs *FusionState) updateUncertainties(dt float64) {
+	for i := 0; i < fs.imuCount; i++ {
+		fs.deadReckoning[i] += dt
+		fs.uncertainties[i] = fs.uncertainty.Estimate(fs.deadReckoning[i])
+	}
+	if fs.adaptive != nil {
+		if len(fs.scratch.motion) != fs.imuCount {
+			fs.scratch.motion = make([]float64, fs.imuCount)
+		}
+		for i, rms := range fs.motion.rmsInto(fs.scratch.motion, fs.adaptive.Window) {
+			fs.uncertainties[i] *= fs.adaptive.scale(rms)
+		}
+	}
+}
+
+// fuseIMUs fuses the body reference positions of the IMUs marked in present with tracker, gating
+// them against lastFused, and feeds the result back to their filters. Whenever a single IMU is
+// left, whether the others were dropped from the frame, disabled, or gated out, its position is
+// passed through unfused. total is the number of IMUs that could have taken part, for the
+// confidence. It returns false if no IMU was present or the fusion was not finite. The caller
+// must hold filterMu.
+func (fs *FusionState) fuseIMUs(tracker *FusionTracker, lastFused *Vec2, hasFused *bool, positions []Point, present []bool, total int, now time.Time) (fusion, bool) {
+	// ids maps posList back to IMU IDs
+	ids, posList := fs.scratch.ids[:0], fs.scratch.posList[:0]
+	defer func() { fs.scratch.ids, fs.scratch.posList = ids, posList }()
+	for i, ok := range present {
+		if ok {
+			ids = append(ids, i)
+			posList = append(posList, Position{X: positions[i].X, Y: positions[i].Y, R: fs.uncertainties[i] / fs.scratch.quality[i]})
+		}
+	}
+	if len(posList) == 0 {
+		return fusion{}, false
+	}
+	included := fs.scratch.included[:0]
+	for range posList {
+		included = append(included, true)
+	}
+	fs.scratch.included = included
+	if fs.gatingThreshold > 0 && *hasFused && len(posList) > 1 {
+		included = gateMask(posList, *lastFused, fs.gatingThreshold)
+		posList = maskPositions(posList, included)
+	}
+	if len(posList) == 1 {
+		// A lone IMU has nothing to be fused with: its integrated position is used as is, with
+		// alpha 1 so that R is its own uncertainty. It is fed back only to the IMUs gated out.
+		p := posList[0]
+		*lastFused = Vec2{X: p.X, Y: p.Y}
+		*hasFused = true
+		for k, i := range ids {
+			if !included[k] {
+				fs.correctFilter(i, p.X, p.Y, fs.uncertainties[i])
 			}
-			finalX, finalY := fused.X, fused.Y
-			if count > 0 {
-				finalX = sumX / float64(count)
-				finalY = sumY / float64(count)
+		}
+		return fusion{
+			position:   Vec2{X: p.X, Y: p.Y},
+			velocity:   fs.fusedVelocity(ids, included),
+			alpha:      1,
+			confidence: FusionConfidence(1, total, 1, 0, p.R, fs.confidenceWeights),
+		}, true
+	}
+	_, fused := tracker.Fuse(posList)
+	if math.IsNaN(fused.X) || math.IsInf(fused.X, 0) || math.IsNaN(fused.Y) || math.IsInf(fused.Y, 0) {
+		// Feeding this back would poison every filter, so the frame is dropped instead.
+		fs.metrics.recordNonFinite()
+		fs.logger.Printf("Warning: skipping frame at %v, fusion produced a non-finite position\n", now)
+		return fusion{failure: FusionFailureDegenerate}, false
+	}
+	var failure string
+	if !tracker.lastFound {
+		failure = fusionFailure(posList, tracker.maxAlpha)
+	}
+	residual := FusionResidual(posList, fused)
+	var meanRadius float64
+	for _, p := range posList {
+		meanRadius += p.R / float64(len(posList))
+	}
+	confidence := FusionConfidence(len(posList), total, fused.R, residual, meanRadius, fs.confidenceWeights)
+	*lastFused = Vec2{X: fused.X, Y: fused.Y}
+	*hasFused = true
+
+	// A good fusion confirms the positions that took part in it
+	if fused.R <= goodFusionAlpha {
+		for k, ok := range included {
+			if ok {
+				fs.deadReckoning[ids[k]] = 0
 			}
+		}
+	}
+
+	// Feed the fused position back to each filter so relative biases become observable
+	for _, i := range ids {
+		fs.correctFilter(i, fused.X, fused.Y, fused.R*fs.uncertainties[i])
+	}
+	return fusion{
+		position:   Vec2{X: fused.X, Y: fused.Y},
+		velocity:   fs.fusedVelocity(ids, included),
+		alpha:      fused.R,
+		residual:   residual,
+		confidence: confidence,
+		failure:    failure,
+	}, true
+}
+
+// correctFilter updates the filter of IMU i with the body reference position (x, y), offset to
+// the IMU's mounting point, at standard deviation r. The caller must hold filterMu.
+func (fs *FusionState) correctFilter(i int, x, y, r float64) {
+	offset := fs.extrinsics[i].Offset
+	fs.filters[i].UpdatePosition(0, x+offset.X, r*r)
+	fs.filters[i].UpdatePosition(1, y+offset.Y, r*r)
+}
+
+// fusedVelocity returns the mean of the filter velocities of the IMUs in ids that passed the
+// gate, weighted by the inverse square of their uncertainty radii like the fusion. The caller
+// must hold filterMu.
+func (fs *FusionState) fusedVelocity(ids []int, included []bool) Vec2 {
+	var v Vec2
+	var total float64
+	for k, i := range ids {
+		if !included[k] {
+			continue
+		}
+		r := math.Max(fs.uncertainties[i]/fs.scratch.quality[i], epsilon)
+		w := 1 / (r * r)
+		vel := fs.filters[i].Velocity()
+		v.X += w * vel[0]
+		v.Y += w * vel[1]
+		total += w
+	}
+	if total == 0 {
+		return Vec2{}
+	}
+	return Vec2{X: v.X / total, Y: v.Y / total}
+}
 
-			// Output fused and refined position
-			fmt.Printf("Fused position: (%.3f, %.3f)\n", finalX, finalY)
+// notifyBiasDrift reports threshold crossings to the OnBiasDrift callback. The caller must not
+// hold filterMu.
+func (fs *FusionState) notifyBiasDrift(drifts []driftEvent) {
+	if fs.onBiasDrift != nil {
+		for _, d := range drifts {
+			fs.onBiasDrift(d.imuID, d.drift)
 		}
 	}
 }
+
+// notifyFusionFailure reports a frame whose fusion failed for reason to the OnFusionFailure
+// callback, if reason is not empty. The caller must not hold filterMu.
+func (fs *FusionState) notifyFusionFailure(frame []IMUData, reason string) {
+	if fs.onFusionFailure != nil && reason != "" {
+		fs.onFusionFailure(frame, reason)
+	}
+}
+
+// refineAndHold refines a fusion against cloud, smooths it with smoother if not nil, and holds
+// it at held while the body is stationary, returning the sample to emit.
+func (fs *FusionState) refineAndHold(cloud *PointCloud, smoother *AlphaBetaFilter, held *Point, fused fusion, step frameStep) FusedSample {
+	// Point cloud refinement
+	finalX, finalY := fs.refineIn(cloud, Position{X: fused.position.X, Y: fused.position.Y, R: fused.alpha}, step.now)
+	vel := Point{X: fused.velocity.X, Y: fused.velocity.Y}
+	if smoother != nil {
+		var pos Point
+		// A clamped time step would turn the residual into a huge velocity correction, so the
+		// smoother holds its estimate through a non-monotonic frame.
+		smoothDt := step.dt
+		if step.clamped {
+			smoothDt = 0
+		}
+		pos, vel = smoother.Update(Point{X: finalX, Y: finalY}, smoothDt)
+		finalX, finalY = pos.X, pos.Y
+	}
+
+	// Hold the output while stationary so it does not jitter with noise
+	if step.stationary {
+		if !step.wasStationary {
+			*held = Point{X: finalX, Y: finalY}
+		}
+		finalX, finalY = held.X, held.Y
+		vel = Point{}
+	}
+	return FusedSample{Timestamp: step.now, X: finalX, Y: finalY, VX: vel.X, VY: vel.Y, Residual: fused.residual, Confidence: fused.confidence}
+}
+
+// bodyRotation returns the body yaw rate, the mean calibrated gyro Z rate of the enabled IMUs in frame,
+// and its angular acceleration since the previous frame, zero after a clamped time step.
+// The caller must hold filterMu.
+func (fs *FusionState) bodyRotation(frame []IMUData, dt float64, clamped bool) (float64, float64) {
+	var sum float64
+	n := 0
+	for _, data := range frame {
+		if data.IMUID < 0 || data.IMUID >= fs.imuCount || fs.disabled[data.IMUID] || !data.Finite() {
+			continue
+		}
+		sum += fs.calib[data.IMUID].ApplyGyroCalibration(data.AngularVelocity)[2]
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	omega := sum / float64(n)
+	alpha := 0.0
+	if fs.haveBodyRate && !clamped {
+		alpha = (omega - fs.bodyRate) / dt
+	}
+	fs.bodyRate = omega
+	fs.haveBodyRate = true
+	return omega, alpha
+}
+
+// refine replaces the fused position with the kernel-weighted geometric median, or mean, of the
+// point cloud within refinementRadius, or returns it unchanged if there are no neighbours.
+func (fs *FusionState) refine(fused Position, now time.Time) (float64, float64) {
+	return fs.refineIn(fs.cloud, fused, now)
+}
+
+// refineIn is refine against the given point cloud.
+func (fs *FusionState) refineIn(cloud *PointCloud, fused Position, now time.Time) (float64, float64) {
+	var mean Point
+	var ok bool
+	if fs.medianRefinement {
+		mean, ok = cloud.GeometricMedian(fused.X, fused.Y, fs.refinementRadius, now, fs.distanceWidth, fs.ageWidth)
+	} else {
+		mean, ok = cloud.HuberMean(fused.X, fused.Y, fs.refinementRadius, now, fs.distanceWidth, fs.ageWidth, fs.huberDelta)
+	}
+	if !ok {
+		return fused.X, fused.Y
+	}
+	return mean.X, mean.Y
+}
@@ -1,6 +1,13 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -24,3 +31,1909 @@ func TestIMUFusionSystemStopReturns(t *testing.T) {
 		t.Fatal("Stop did not return")
 	}
 }
+
+func TestIMUFusionSystemPauseResume(t *testing.T) {
+	const imuCount = 2
+	src := &chanSource{samples: make(chan IMUData, 16)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	processed := make(chan time.Time, 16)
+	sys.output = func(sample FusedSample) { processed <- sample.Timestamp }
+
+	sys.Pause()
+	sys.Start()
+	defer sys.Stop()
+
+	base := time.Unix(0, 0).Add(time.Second)
+	var want []time.Time
+	for i := 0; i < 3; i++ {
+		ts := base.Add(time.Duration(i) * time.Millisecond)
+		want = append(want, ts)
+		for id := 0; id < imuCount; id++ {
+			src.samples <- IMUData{IMUID: id, DeviceTimestamp: ts}
+		}
+	}
+
+	select {
+	case ts := <-processed:
+		t.Fatalf("frame %v processed while paused", ts)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	sys.Resume()
+	for i, ts := range want {
+		select {
+		case got := <-processed:
+			if !got.Equal(ts) {
+				t.Errorf("frame %d: expected timestamp %v, got %v", i, ts, got)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("timed out waiting for buffered frame %d", i)
+		}
+	}
+}
+
+// TestIMUFusionSystemCurrentPositionWhileRunning is meant to be run with -race.
+func TestIMUFusionSystemCurrentPositionWhileRunning(t *testing.T) {
+	const imuCount, frames = 2, 50
+	src := &chanSource{samples: make(chan IMUData, imuCount*frames)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	processed := make(chan FusedSample, frames)
+	sys.output = func(sample FusedSample) { processed <- sample }
+
+	if _, ok := sys.CurrentPosition(); ok {
+		t.Error("Expected no current position before any fusion")
+	}
+
+	sys.Start()
+	defer sys.Stop()
+
+	stop := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				sys.CurrentPosition()
+			}
+		}
+	}()
+
+	base := time.Unix(0, 0).Add(time.Second)
+	for i := 0; i < frames; i++ {
+		ts := base.Add(time.Duration(i) * time.Millisecond)
+		for id := 0; id < imuCount; id++ {
+			src.samples <- IMUData{IMUID: id, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}}
+		}
+	}
+	var last FusedSample
+	for i := 0; i < frames; i++ {
+		select {
+		case last = <-processed:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for frame %d", i)
+		}
+	}
+	close(stop)
+	<-readerDone
+
+	pos, ok := sys.CurrentPosition()
+	if !ok {
+		t.Fatal("Expected a current position after fusion")
+	}
+	if pos.X != last.X || pos.Y != last.Y {
+		t.Errorf("Expected current position (%f, %f) to match the last output, got (%f, %f)", last.X, last.Y, pos.X, pos.Y)
+	}
+}
+
+func TestIMUFusionSystemPauseBufferCap(t *testing.T) {
+	sys, err := NewIMUFusionSystem(1)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.maxPending = 2
+
+	base := time.Unix(0, 0)
+	var frames [][]IMUData
+	for i := 0; i < 3; i++ {
+		frames = append(frames, []IMUData{{DeviceTimestamp: base.Add(time.Duration(i) * time.Millisecond)}})
+	}
+	sys.bufferFrames(frames)
+
+	if len(sys.pending) != 2 {
+		t.Fatalf("Expected 2 buffered frames, got %d", len(sys.pending))
+	}
+	if !sys.pending[0][0].SampleTime().Equal(frames[1][0].SampleTime()) {
+		t.Errorf("Expected oldest frame to be dropped, first buffered is %v", sys.pending[0][0].SampleTime())
+	}
+	if dropped := sys.Metrics().DroppedFrames; dropped != 1 {
+		t.Errorf("Expected 1 dropped frame, got %d", dropped)
+	}
+}
+
+func TestIMUFusionSystemRefinementIgnoresAlpha(t *testing.T) {
+	sys, err := NewIMUFusionSystem(1)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.SetRefinementRadius(1.0)
+	now := time.Now()
+	sys.cloud.AddPointAt(0.5, 0, now)
+	sys.cloud.AddPointAt(-0.5, 1, now)
+	sys.cloud.AddPointAt(5, 5, now) // outside the refinement radius
+
+	for _, alpha := range []float64{1.0, 2.5, 10.0} {
+		x, y := sys.refine(Position{X: 0, Y: 0.5, R: alpha}, now)
+		if !floatsClose(x, 0, 1e-9) || !floatsClose(y, 0.5, 1e-9) {
+			t.Errorf("alpha %f: expected refined position (0, 0.5), got (%f, %f)", alpha, x, y)
+		}
+	}
+
+	// Without neighbours the fused position is returned unchanged.
+	sys.SetRefinementRadius(0.1)
+	if x, y := sys.refine(Position{X: 0, Y: 0.5, R: 10}, now); x != 0 || y != 0.5 {
+		t.Errorf("Expected unrefined position (0, 0.5), got (%f, %f)", x, y)
+	}
+}
+
+func TestIMUFusionSystemRefinementDefaultsToMedian(t *testing.T) {
+	sys, err := NewIMUFusionSystem(1)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.SetRefinementRadius(2)
+	sys.SetRefinementKernel(0, 0)
+	now := time.Now()
+	for _, x := range []float64{0, 0.1, 1} {
+		sys.cloud.AddPointAt(x, 0, now)
+	}
+
+	// The stray neighbour at x = 1 drags the mean to a third of the way, but not the median.
+	fused := Position{X: 0, Y: 0, R: 1}
+	if x, _ := sys.refine(fused, now); !floatsClose(x, 0.1, 1e-3) {
+		t.Errorf("Expected the median 0.1 by default, got %f", x)
+	}
+	sys.SetRefinementHuber(0)
+	if x, _ := sys.refine(fused, now); !floatsClose(x, 1.1/3, 1e-9) {
+		t.Errorf("Expected the mean %f, got %f", 1.1/3, x)
+	}
+	sys.SetRefinementMedian(true)
+	if x, _ := sys.refine(fused, now); !floatsClose(x, 0.1, 1e-3) {
+		t.Errorf("Expected the median 0.1 restored, got %f", x)
+	}
+}
+
+func TestIMUFusionSystemGetEstimatedBiasOutOfRange(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	for _, id := range []int{-1, 2} {
+		if bias := sys.GetEstimatedBias(id); bias != ([3]float64{}) {
+			t.Errorf("Expected zero bias for IMU %d, got %v", id, bias)
+		}
+	}
+}
+
+func TestIMUFusionSystemMetricsCountFrames(t *testing.T) {
+	const imuCount = 2
+	src := &chanSource{samples: make(chan IMUData, 16)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	callbacks := make(chan Metrics, 16)
+	sys.SetMetricsCallback(func(m Metrics) { callbacks <- m })
+	sys.Start()
+	defer sys.Stop()
+
+	base := time.Unix(0, 0).Add(time.Second)
+	for i := 0; i < 3; i++ {
+		for id := 0; id < imuCount; id++ {
+			src.samples <- IMUData{IMUID: id, DeviceTimestamp: base.Add(time.Duration(i) * time.Millisecond)}
+		}
+		select {
+		case m := <-callbacks:
+			if m.FramesProcessed != uint64(i+1) {
+				t.Errorf("Expected %d frames processed in callback, got %d", i+1, m.FramesProcessed)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("timed out waiting for frame %d", i)
+		}
+	}
+
+	m := sys.Metrics()
+	if m.FramesProcessed != 3 {
+		t.Errorf("Expected 3 frames processed, got %d", m.FramesProcessed)
+	}
+	if m.CloudPoints != 3*imuCount {
+		t.Errorf("Expected %d cloud points, got %d", 3*imuCount, m.CloudPoints)
+	}
+	if m.AvgFusionDuration <= 0 {
+		t.Errorf("Expected positive average fusion duration, got %v", m.AvgFusionDuration)
+	}
+}
+
+func TestIMUFusionSystemRejectsNonFiniteSamples(t *testing.T) {
+	const imuCount = 2
+	src := &chanSource{samples: make(chan IMUData, 16)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	callbacks := make(chan Metrics, 16)
+	sys.SetMetricsCallback(func(m Metrics) { callbacks <- m })
+	sys.Start()
+	defer sys.Stop()
+
+	base := time.Unix(0, 0).Add(time.Second)
+	for i := 0; i < 4; i++ {
+		for id := 0; id < imuCount; id++ {
+			accel := [3]float64{0.5, 0, 0}
+			if i == 1 && id == 0 {
+				accel[0] = math.NaN()
+			}
+			src.samples <- IMUData{IMUID: id, DeviceTimestamp: base.Add(time.Duration(i) * time.Millisecond), Acceleration: accel}
+		}
+		select {
+		case <-callbacks:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("timed out waiting for frame %d", i)
+		}
+	}
+
+	if m := sys.Metrics(); m.InvalidSamples != 1 {
+		t.Errorf("Expected 1 invalid sample, got %d", m.InvalidSamples)
+	}
+	p, ok := sys.CurrentPosition()
+	if !ok || math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsNaN(p.R) {
+		t.Errorf("Expected a finite position, got %v", p)
+	}
+	for i, f := range sys.filters {
+		pos, vel := f.Position(), f.Velocity()
+		for _, v := range append(pos[:], vel[:]...) {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("IMU %d: Expected finite filter state, got position %v velocity %v", i, pos, vel)
+				break
+			}
+		}
+	}
+}
+
+func TestIMUFusionSystemExtrinsicsAlignRotatedIMUs(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+
+	// IMU 1 is rotated 90° so its X axis points along body +Y, and is mounted 0.1 to the right.
+	rot90 := [2][2]float64{{0, -1}, {1, 0}}
+	if err := sys.SetExtrinsics(1, rot90, Point{X: 0.1, Y: 0}); err != nil {
+		t.Fatalf("SetExtrinsics failed: %v", err)
+	}
+	if err := sys.SetExtrinsics(2, rot90, Point{}); err == nil {
+		t.Error("Expected error for out-of-range IMU ID")
+	}
+
+	// The body accelerates along +X: IMU 0 sees (1, 0), IMU 1 sees (0, -1) in its own frame.
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for step := 1; step <= 10; step++ {
+		ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{0, -1, 0}},
+		})
+	}
+
+	v0 := sys.filters[0].Velocity()
+	v1 := sys.filters[1].Velocity()
+	if !floatsClose(v0[0], v1[0], 1e-6) || !floatsClose(v0[1], v1[1], 1e-6) {
+		t.Errorf("Expected matching body-frame velocities, got %v and %v", v0, v1)
+	}
+	if v1[0] <= 0 || !floatsClose(v1[1], 0, 1e-6) {
+		t.Errorf("Expected IMU 1 to move along +X, got velocity %v", v1)
+	}
+
+	p0 := sys.filters[0].Position()
+	p1 := sys.filters[1].Position()
+	if !floatsClose(p1[0]-0.1, p0[0], 1e-6) || !floatsClose(p1[1], p0[1], 1e-6) {
+		t.Errorf("Expected IMU 1 to stay 0.1 from IMU 0, got %v and %v", p0, p1)
+	}
+}
+
+func TestIMUFusionSystemSkipsOutOfRangeIMUID(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	ts := time.Unix(1, 0)
+	sys.lastTime = ts.Add(-10 * time.Millisecond)
+	sys.processFrame([]IMUData{
+		{IMUID: -1, DeviceTimestamp: ts},
+		{IMUID: 0, DeviceTimestamp: ts},
+		{IMUID: 2, DeviceTimestamp: ts},
+	})
+	if _, ok := sys.CurrentPosition(); !ok {
+		t.Error("Expected the frame to be fused from the valid sample")
+	}
+}
+
+func TestIMUFusionSystemTiltCompensation(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+
+	// IMU 1 is tilted; its reading at rest gives the tilt.
+	roll, pitch := 0.2, -0.4
+	R := tiltRotation(roll, pitch)
+	tilted := func(level [3]float64) [3]float64 {
+		var body [3]float64
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				body[i] += R[j][i] * level[j] // the transpose maps level to tilted
+			}
+		}
+		return body
+	}
+	gotRoll, gotPitch := TiltFromGravity(tilted([3]float64{0, 0, 9.81}))
+	if !floatsClose(gotRoll, roll, 1e-9) || !floatsClose(gotPitch, pitch, 1e-9) {
+		t.Errorf("Expected tilt (%f, %f) from gravity, got (%f, %f)", roll, pitch, gotRoll, gotPitch)
+	}
+	if err := sys.SetTilt(1, gotRoll, gotPitch); err != nil {
+		t.Fatalf("SetTilt failed: %v", err)
+	}
+	if err := sys.SetTilt(2, roll, pitch); err == nil {
+		t.Error("Expected error for out-of-range IMU ID")
+	}
+
+	// The body accelerates horizontally along +X while gravity acts on both IMUs.
+	level := [3]float64{1, 0, 9.81}
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for step := 1; step <= 10; step++ {
+		ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: level},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: tilted(level)},
+		})
+	}
+
+	v0 := sys.filters[0].Velocity()
+	v1 := sys.filters[1].Velocity()
+	if !floatsClose(v0[0], v1[0], 1e-6) || !floatsClose(v0[1], v1[1], 1e-6) {
+		t.Errorf("Expected the tilted IMU to match the level one, got %v and %v", v1, v0)
+	}
+	if v1[0] <= 0 || !floatsClose(v1[1], 0, 1e-6) {
+		t.Errorf("Expected IMU 1 to move along +X only, got velocity %v", v1)
+	}
+
+	// A calibrated tilted IMU at rest stays at rest: calibration removes only its bias, and
+	// gravity does not leak back in through leveling.
+	calibrated, err := NewIMUFusionSystem(1)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	calibrated.output = func(FusedSample) {}
+	calibrated.SetStationarityDetection(0, 0, 0) // no zero-velocity updates to mask leakage
+	if err := calibrated.SetTilt(0, roll, pitch); err != nil {
+		t.Fatalf("SetTilt failed: %v", err)
+	}
+	bias := [3]float64{0.05, -0.02, 0}
+	atRest := tilted([3]float64{bias[0], bias[1], 9.81})
+	if _, err := calibrated.CalibrateIMU(0, [][3]float64{atRest, atRest, atRest}); err != nil {
+		t.Fatalf("CalibrateIMU failed: %v", err)
+	}
+	calibrated.lastTime = base
+	for step := 1; step <= 10; step++ {
+		ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+		calibrated.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts, Acceleration: atRest}})
+	}
+	if v := calibrated.filters[0].Velocity(); !floatsClose(v[0], 0, 1e-9) || !floatsClose(v[1], 0, 1e-9) {
+		t.Errorf("Expected calibrated tilted IMU to stay at rest, got velocity %v", v)
+	}
+}
+
+func TestIMUFusionSystemDisableIMU(t *testing.T) {
+	sys, err := NewIMUFusionSystem(4)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	outputs := 0
+	sys.output = func(FusedSample) { outputs++ }
+
+	// Every IMU reports the body accelerating along +X, via the synchronizer as when running.
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	step := 0
+	run := func(frames int) {
+		for i := 0; i < frames; i++ {
+			step++
+			ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+			for id := 0; id < 4; id++ {
+				sys.sync.AddData(IMUData{IMUID: id, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}})
+			}
+			for _, frame := range sys.sync.GetAlignedData(sys.imuCount) {
+				sys.processFrame(frame)
+			}
+		}
+	}
+
+	run(10)
+	if err := sys.DisableIMU(3); err != nil {
+		t.Fatalf("DisableIMU failed: %v", err)
+	}
+	frozen := sys.filters[3].Position()
+	before := sys.lastFused
+	run(10)
+
+	if outputs != 20 {
+		t.Errorf("Expected fusion to continue with three IMUs, got %d outputs for 20 frames", outputs)
+	}
+	if sys.filters[3].Position() != frozen {
+		t.Errorf("Expected disabled IMU to be frozen at %v, got %v", frozen, sys.filters[3].Position())
+	}
+	if sys.lastFused.X <= before.X {
+		t.Errorf("Expected fused position to keep moving along +X from %v, got %v", before, sys.lastFused)
+	}
+
+	if err := sys.EnableIMU(3); err != nil {
+		t.Fatalf("EnableIMU failed: %v", err)
+	}
+	p := sys.filters[3].Position()
+	if !floatsClose(p[0], sys.lastFused.X, 1e-9) || !floatsClose(p[1], sys.lastFused.Y, 1e-9) {
+		t.Errorf("Expected re-enabled IMU re-seeded at %v, got %v", sys.lastFused, p)
+	}
+	run(5)
+	if outputs != 25 {
+		t.Errorf("Expected fusion with all four IMUs again, got %d outputs for 25 frames", outputs)
+	}
+
+	for id := 0; id < 3; id++ {
+		if err := sys.DisableIMU(id); err != nil {
+			t.Fatalf("DisableIMU(%d) failed: %v", id, err)
+		}
+	}
+	if err := sys.DisableIMU(3); err == nil {
+		t.Error("Expected error disabling the last enabled IMU")
+	}
+	if err := sys.DisableIMU(4); err == nil {
+		t.Error("Expected error for out-of-range IMU ID")
+	}
+}
+
+func TestIMUFusionSystemRezero(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	if err := sys.SetExtrinsics(1, [2][2]float64{{1, 0}, {0, 1}}, Point{X: 0.1, Y: 0}); err != nil {
+		t.Fatalf("SetExtrinsics failed: %v", err)
+	}
+
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	step := 0
+	run := func(frames int, accel [3]float64) {
+		for i := 0; i < frames; i++ {
+			step++
+			ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+			sys.processFrame([]IMUData{
+				{IMUID: 0, DeviceTimestamp: ts, Acceleration: accel},
+				{IMUID: 1, DeviceTimestamp: ts, Acceleration: accel},
+			})
+		}
+	}
+
+	// Drift away with a spurious acceleration, which would otherwise be detected as rest.
+	sys.SetStationarityDetection(0, 0, 0)
+	run(100, [3]float64{0.5, -0.3, 0})
+	if p := sys.filters[0].Position(); math.Hypot(p[0], p[1]) < 0.05 {
+		t.Fatalf("Expected the body to drift away from the origin, got %v", p)
+	}
+
+	anchor := Point{X: 5, Y: -2}
+	if err := sys.RezeroAll(anchor); err != nil {
+		t.Fatalf("RezeroAll failed: %v", err)
+	}
+	for i, want := range []Point{anchor, {X: anchor.X + 0.1, Y: anchor.Y}} {
+		p, v := sys.filters[i].Position(), sys.filters[i].Velocity()
+		if !floatsClose(p[0], want.X, 1e-9) || !floatsClose(p[1], want.Y, 1e-9) {
+			t.Errorf("IMU %d: Expected position %v after rezeroing, got %v", i, want, p)
+		}
+		if v != ([3]float64{}) {
+			t.Errorf("IMU %d: Expected zero velocity after rezeroing, got %v", i, v)
+		}
+	}
+	if p, ok := sys.CurrentPosition(); !ok || p.X != anchor.X || p.Y != anchor.Y {
+		t.Errorf("Expected current position %v right after rezeroing, got %v", anchor, p)
+	}
+
+	// At rest, integration continues from the anchor.
+	run(20, [3]float64{})
+	if p, _ := sys.CurrentPosition(); !pointsClose(Point{X: p.X, Y: p.Y}, anchor, 0.01) {
+		t.Errorf("Expected fused position to stay near the anchor %v, got %v", anchor, p)
+	}
+
+	if err := sys.Rezero([]Point{anchor}); err == nil {
+		t.Error("Expected error for a mismatched number of anchors")
+	}
+}
+
+func TestIMUFusionSystemSnapshotRestoreRoundTrip(t *testing.T) {
+	newSystem := func() *IMUFusionSystem {
+		sys, err := NewIMUFusionSystem(2)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		sys.output = func(FusedSample) {}
+		sys.SetGatingThreshold(9.21)
+		return sys
+	}
+	frame := func(step int) []IMUData {
+		ts := time.Unix(1, 0).Add(time.Duration(step) * 10 * time.Millisecond)
+		ax := math.Sin(0.1 * float64(step))
+		return []IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{ax, 0.2, 0}},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{ax + 0.05, 0.1, 0}},
+		}
+	}
+
+	original := newSystem()
+	for step := 0; step < 50; step++ {
+		original.processFrame(frame(step))
+	}
+
+	encoded, err := json.Marshal(original.Snapshot())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var state State
+	if err := json.Unmarshal(encoded, &state); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	restored := newSystem()
+	if err := restored.Restore(state); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	for step := 50; step < 100; step++ {
+		original.processFrame(frame(step))
+		restored.processFrame(frame(step))
+	}
+	a, b := original.Snapshot(), restored.Snapshot()
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected identical state after restore, got\n%+v\n%+v", a, b)
+	}
+}
+
+func TestIMUFusionSystemRestoreKeepsFrameTime(t *testing.T) {
+	newSystem := func() *IMUFusionSystem {
+		sys, err := NewIMUFusionSystem(1)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		sys.output = func(FusedSample) {}
+		return sys
+	}
+	base := time.Unix(1, 0)
+	frame := func(ms int) []IMUData {
+		return []IMUData{{IMUID: 0, DeviceTimestamp: base.Add(time.Duration(ms) * time.Millisecond)}}
+	}
+
+	original := newSystem()
+	original.processFrame(frame(10))
+	original.processFrame(frame(20))
+	state := original.Snapshot()
+	if !state.HaveFrame {
+		t.Fatal("Expected the snapshot to record that a frame was processed")
+	}
+	// Whether a position was fused says nothing about whether LastTime is a frame time.
+	state.HasFused = false
+
+	restored := newSystem()
+	if err := restored.Restore(state); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored.processFrame(frame(15))
+	if n := restored.Metrics().NonMonotonicFrames; n != 1 {
+		t.Errorf("Expected the late frame counted as non-monotonic, got %d", n)
+	}
+	if want := base.Add(20 * time.Millisecond); !restored.lastTime.Equal(want) {
+		t.Errorf("Expected last time %v, got %v", want, restored.lastTime)
+	}
+}
+
+func TestIMUFusionSystemRestoreRequiresPause(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	state := sys.Snapshot()
+
+	sys.Start()
+	defer sys.Stop()
+	if err := sys.Restore(state); err != ErrRunning {
+		t.Errorf("Expected ErrRunning while running, got %v", err)
+	}
+	sys.Pause()
+	if err := sys.Restore(state); err != nil {
+		t.Errorf("Expected Restore to succeed while paused, got %v", err)
+	}
+	sys.Resume()
+
+	other, err := NewIMUFusionSystem(3)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	if err := other.Restore(state); err == nil {
+		t.Error("Expected error restoring a state with a different IMU count")
+	}
+}
+
+func TestIMUFusionSystemRestoreRejectsBeforeApplying(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.Pause()
+	sys.pending = [][]IMUData{{{IMUID: 0}}}
+	before := sys.Snapshot()
+
+	// A valid stationarity window and first filter, but the wrong kind for the second filter.
+	bad := sys.Snapshot()
+	bad.Stationarity.Next = 1
+	bad.Filters[0].Position = [3]float64{1, 2, 0}
+	bad.Filters[1].Kind = FilterUKF
+	if err := sys.Restore(bad); err == nil {
+		t.Fatal("Expected error restoring a filter of the wrong kind")
+	}
+	if after := sys.Snapshot(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected a rejected Restore to leave the state unchanged, got %+v", after)
+	}
+	if len(sys.pending) != 1 {
+		t.Errorf("Expected a rejected Restore to keep buffered frames, got %d", len(sys.pending))
+	}
+
+	if err := sys.Restore(before); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(sys.pending) != 0 {
+		t.Errorf("Expected Restore to discard %d buffered frames", len(sys.pending))
+	}
+}
+
+func TestIMUFusionSystemSmoothingHoldsThroughOutOfOrderFrame(t *testing.T) {
+	sys, err := NewIMUFusionSystem(1)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	var samples []FusedSample
+	sys.output = func(sample FusedSample) { samples = append(samples, sample) }
+	sys.SetOutputSmoothing(0.5, 0.1)
+
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for _, ms := range []int{10, 20, 30, 25, 40, 50} {
+		ts := base.Add(time.Duration(ms) * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}}})
+	}
+
+	if len(samples) != 6 {
+		t.Fatalf("Expected 6 outputs, got %d", len(samples))
+	}
+	for i, s := range samples {
+		// Accelerating at 1 m/s^2 for 50ms cannot exceed a few cm/s or move more than a few mm.
+		if math.Abs(s.VX) > 1 || math.Abs(s.X) > 0.01 {
+			t.Errorf("frame %d: Expected bounded smoothed output, got position %f velocity %f", i, s.X, s.VX)
+		}
+	}
+}
+
+func TestIMUFusionSystemDetectsNonMonotonicFrames(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		sys, err := NewIMUFusionSystem(1)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		sys.output = func(FusedSample) {}
+		sys.SetStrictTimestamps(strict)
+
+		base := time.Unix(1, 0)
+		latest := 0
+		for _, ms := range []int{10, 20, 15, 30} {
+			ts := base.Add(time.Duration(ms) * time.Millisecond)
+			sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}}})
+			if ms > latest {
+				latest = ms
+			}
+			// The late frame never moves the last time backwards.
+			if want := base.Add(time.Duration(latest) * time.Millisecond); !sys.lastTime.Equal(want) {
+				t.Errorf("strict=%v: after the frame at %dms, Expected last time %v, got %v", strict, ms, want, sys.lastTime)
+			}
+		}
+
+		m := sys.Metrics()
+		if m.NonMonotonicFrames != 1 {
+			t.Errorf("strict=%v: Expected 1 non-monotonic frame, got %d", strict, m.NonMonotonicFrames)
+		}
+		wantProcessed := uint64(4)
+		if strict {
+			wantProcessed = 3
+		}
+		if m.FramesProcessed != wantProcessed {
+			t.Errorf("strict=%v: Expected %d frames processed, got %d", strict, wantProcessed, m.FramesProcessed)
+		}
+		if want := base.Add(30 * time.Millisecond); !sys.lastTime.Equal(want) {
+			t.Errorf("strict=%v: Expected last time %v, got %v", strict, want, sys.lastTime)
+		}
+	}
+}
+
+func TestIMUFusionSystemCloudHistoryBound(t *testing.T) {
+	const imuCount, bound = 2, 50
+	sys, err := NewIMUFusionSystem(imuCount)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.SetCloudHistory(bound)
+
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for step := 1; step <= 200; step++ {
+		ts := base.Add(time.Duration(step) * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}})
+
+		want := step * imuCount
+		if want > bound {
+			want = bound
+		}
+		if got := sys.Metrics().CloudPoints; got != want {
+			t.Fatalf("step %d: Expected %d cloud points, got %d", step, want, got)
+		}
+	}
+}
+
+func TestIMUFusionSystemFuseTrajectory(t *testing.T) {
+	const dt = 10 * time.Millisecond
+	newSystem := func() *IMUFusionSystem {
+		sys, err := NewIMUFusionSystem(2)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		sys.output = func(FusedSample) { t.Error("Expected no output from FuseTrajectory") }
+		sys.SetStationarityDetection(0, 0, 0)
+		sys.SetRefinementRadius(0)
+		return sys
+	}
+
+	// Both IMUs accelerate along +X at 1 m/s^2, so they agree and the position updates
+	// never move the filters: the fused track is exactly x = t^2/2.
+	base := time.Unix(1, 0)
+	frames := make([][]IMUData, 20)
+	for i := range frames {
+		ts := base.Add(time.Duration(i) * dt)
+		frames[i] = []IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+		}
+	}
+
+	trajectory := newSystem().FuseTrajectory(frames)
+	if len(trajectory) != len(frames) {
+		t.Fatalf("Expected %d positions, got %d", len(frames), len(trajectory))
+	}
+	for i, p := range trajectory {
+		elapsed := (time.Duration(i) * dt).Seconds()
+		if want := 0.5 * elapsed * elapsed; !floatsClose(p.X, want, 1e-9) || !floatsClose(p.Y, 0, 1e-9) {
+			t.Errorf("frame %d: Expected (%f, 0), got (%f, %f)", i, want, p.X, p.Y)
+		}
+		if p.R < alphaLowerBound || p.R > alphaLowerBound+2*alphaTolerance {
+			t.Errorf("frame %d: Expected alpha near %f, got %f", i, alphaLowerBound, p.R)
+		}
+	}
+
+	if again := newSystem().FuseTrajectory(frames); !reflect.DeepEqual(again, trajectory) {
+		t.Error("Expected FuseTrajectory to be deterministic")
+	}
+}
+
+func TestIMUFusionSystemUncertaintyGrowsDuringGap(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.SetStationarityDetection(0, 0, 0)
+
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	step := 0
+	frame := func() {
+		step++
+		ts := base.Add(time.Duration(step) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}})
+	}
+
+	// Agreeing IMUs are confirmed every frame, so the uncertainty covers one frame only.
+	frame()
+	fresh := NewUncertainty(sys.noiseLevel, 0.01).Estimate()
+	for i, u := range sys.Uncertainties() {
+		if !floatsClose(u, fresh, 1e-12) {
+			t.Errorf("IMU %d: Expected uncertainty %f, got %f", i, fresh, u)
+		}
+	}
+
+	// IMU 1 jumps far away: from the first failed fusion on, both uncertainties grow.
+	sys.filters[1].SetPosition([3]float64{100, 0, 0})
+	frame()
+	prev := sys.Uncertainties()
+	for gap := 0; gap < 20; gap++ {
+		frame()
+		cur := sys.Uncertainties()
+		for i := range cur {
+			if cur[i] <= prev[i] {
+				t.Fatalf("gap frame %d, IMU %d: Expected uncertainty to grow from %f, got %f", gap, i, prev[i], cur[i])
+			}
+		}
+		prev = cur
+	}
+
+	// Once the IMUs agree again the uncertainty resets.
+	sys.filters[1].SetPosition(sys.filters[0].Position())
+	frame()
+	frame()
+	for i, u := range sys.Uncertainties() {
+		if !floatsClose(u, fresh, 1e-12) {
+			t.Errorf("IMU %d: Expected uncertainty to reset to %f, got %f", i, fresh, u)
+		}
+	}
+}
+
+func TestIMUFusionSystemRigidConstraintReferenceWeights(t *testing.T) {
+	sys, err := NewIMUFusionSystem(3)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	identity := [2][2]float64{{1, 0}, {0, 1}}
+	for i, offset := range []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}} {
+		if err := sys.SetExtrinsics(i, identity, offset); err != nil {
+			t.Fatalf("SetExtrinsics failed: %v", err)
+		}
+	}
+	present := []bool{true, true, true}
+	// The body is at the origin, but the loosely mounted IMU 2 has drifted.
+	drifted := func() []Point { return []Point{{X: 0, Y: 0}, {X: 0, Y: 0}, {X: 0.6, Y: 0}} }
+
+	equal := drifted()
+	sys.enforceRigid(equal, present)
+	if pointsClose(equal[0], Point{}, 0.05) {
+		t.Errorf("Expected an equally weighted drifted IMU to pull the fit, got %v", equal)
+	}
+
+	if err := sys.SetReferenceWeights([]float64{1, 1, 0.01}); err != nil {
+		t.Fatalf("SetReferenceWeights failed: %v", err)
+	}
+	weighted := drifted()
+	sys.enforceRigid(weighted, present)
+	for i, p := range weighted {
+		if !pointsClose(p, Point{}, 0.02) {
+			t.Errorf("IMU %d: Expected the fit to follow the trusted IMUs to the origin, got %v", i, p)
+		}
+	}
+
+	if err := sys.SetReferenceWeights([]float64{1, 1}); err == nil {
+		t.Error("Expected error for a mismatched number of weights")
+	}
+	if err := sys.SetReferenceWeights([]float64{1, -1, 1}); err == nil {
+		t.Error("Expected error for a negative weight")
+	}
+}
+
+func TestIMUFusionSystemLeverArmCompensation(t *testing.T) {
+	// The body spins about its center, speeding up at 1 rad/s^2, and the IMU sits 1m out along
+	// X. It measures only the centripetal and tangential acceleration of the rotation, so once
+	// that is removed its translation is zero.
+	run := func(compensate bool) Point {
+		sys, err := NewIMUFusionSystem(1)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		sys.output = func(FusedSample) {}
+		sys.SetStationarityDetection(0, 0, 0)
+		sys.SetRefinementRadius(0)
+		if compensate {
+			if err := sys.SetLeverArm(0, Point{X: 1, Y: 0}); err != nil {
+				t.Fatalf("SetLeverArm failed: %v", err)
+			}
+		}
+		base := time.Unix(1, 0)
+		sys.lastTime = base
+		for i := 1; i <= 50; i++ {
+			omega, alpha := 2+0.01*float64(i), 1.0
+			sys.processFrame([]IMUData{{
+				IMUID:           0,
+				DeviceTimestamp: base.Add(time.Duration(i) * 10 * time.Millisecond),
+				Acceleration:    [3]float64{-omega * omega, alpha, 0},
+				AngularVelocity: [3]float64{0, 0, omega},
+			}})
+		}
+		p := sys.filters[0].Position()
+		return Point{X: p[0], Y: p[1]}
+	}
+
+	if p := run(false); p.X > -0.5 {
+		t.Fatalf("Expected spurious radial drift without compensation, got %v", p)
+	}
+	// The angular acceleration is unknown on the first frame only.
+	if p := run(true); math.Hypot(p.X, p.Y) > 0.01 {
+		t.Errorf("Expected no drift with lever-arm compensation, got %v", p)
+	}
+
+	sys, _ := NewIMUFusionSystem(1)
+	if err := sys.SetLeverArm(1, Point{}); err == nil {
+		t.Error("Expected error for an out-of-range IMU ID")
+	}
+}
+
+func TestIMUFusionSystemMotionRMS(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	const window = 100 * time.Millisecond
+	frame := 0
+	feed := func(n int, accel float64) {
+		for i := 0; i < n; i++ {
+			frame++
+			ts := base.Add(time.Duration(frame) * 10 * time.Millisecond)
+			// Only IMU 0 feels the burst.
+			sys.processFrame([]IMUData{
+				{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{accel, 0, 0}},
+				{IMUID: 1, DeviceTimestamp: ts},
+			})
+		}
+	}
+
+	feed(20, 0)
+	if rms := sys.MotionRMS(window); rms[0] != 0 || rms[1] != 0 {
+		t.Errorf("Expected no motion at rest, got %v", rms)
+	}
+	feed(20, 2)
+	if rms := sys.MotionRMS(window); !floatsClose(rms[0], 2, 1e-9) || rms[1] != 0 {
+		t.Errorf("Expected RMS 2 for IMU 0 during the burst, got %v", rms)
+	}
+	// Halfway through the window after the burst, half the samples still carry it.
+	feed(5, 0)
+	if rms := sys.MotionRMS(window); !floatsClose(rms[0], math.Sqrt(2), 1e-9) {
+		t.Errorf("Expected RMS sqrt(2) as the burst leaves the window, got %v", rms)
+	}
+	feed(20, 0)
+	if rms := sys.MotionRMS(window); rms[0] != 0 {
+		t.Errorf("Expected the RMS to decay to 0 after the burst, got %v", rms)
+	}
+}
+
+func TestIMUFusionSystemUnitScale(t *testing.T) {
+	run := func(scale float64) []FusedSample {
+		sys, err := NewIMUFusionSystem(1)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		var samples []FusedSample
+		sys.output = func(sample FusedSample) { samples = append(samples, sample) }
+		sys.SetStationarityDetection(0, 0, 0)
+		sys.SetOutputSmoothing(0.5, 0.1)
+		if err := sys.SetUnitScale(scale); err != nil {
+			t.Fatalf("SetUnitScale failed: %v", err)
+		}
+		base := time.Unix(1, 0)
+		sys.lastTime = base
+		for i := 1; i <= 20; i++ {
+			sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: base.Add(time.Duration(i) * 10 * time.Millisecond), Acceleration: [3]float64{1, -0.5, 0}}})
+		}
+		return samples
+	}
+
+	meters, millimeters := run(1), run(1000)
+	if len(meters) != 20 || len(millimeters) != 20 {
+		t.Fatalf("Expected 20 samples each, got %d and %d", len(meters), len(millimeters))
+	}
+	for i := range meters {
+		m, mm := meters[i], millimeters[i]
+		if !floatsClose(mm.X, 1000*m.X, 1e-9) || !floatsClose(mm.Y, 1000*m.Y, 1e-9) || !floatsClose(mm.VX, 1000*m.VX, 1e-9) {
+			t.Errorf("frame %d: Expected %v scaled by 1000, got %v", i, m, mm)
+		}
+	}
+	if meters[19].X == 0 {
+		t.Error("Expected the body to have moved")
+	}
+
+	sys, _ := NewIMUFusionSystem(1)
+	if err := sys.SetUnitScale(0); err == nil {
+		t.Error("Expected error for a zero unit scale")
+	}
+}
+
+func TestIMUFusionSystemGroups(t *testing.T) {
+	sys, err := NewIMUFusionSystem(4)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	var samples []FusedSample
+	sys.output = func(sample FusedSample) { samples = append(samples, sample) }
+	sys.SetStationarityDetection(0, 0, 0)
+	if err := sys.SetGroups([][]int{{0, 1}, {2, 3}}); err != nil {
+		t.Fatalf("SetGroups failed: %v", err)
+	}
+
+	// The first body accelerates along +X, the second along -Y.
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	const frames = 100
+	for i := 1; i <= frames; i++ {
+		ts := base.Add(time.Duration(i) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			{IMUID: 2, DeviceTimestamp: ts, Acceleration: [3]float64{0, -1, 0}},
+			{IMUID: 3, DeviceTimestamp: ts, Acceleration: [3]float64{0, -1, 0}},
+		})
+	}
+	if len(samples) != 2*frames {
+		t.Fatalf("Expected %d samples, got %d", 2*frames, len(samples))
+	}
+
+	// After one second, each body has moved 0.5 along its own axis only.
+	first, second := samples[len(samples)-2], samples[len(samples)-1]
+	if first.Group != 0 || second.Group != 1 {
+		t.Fatalf("Expected groups 0 and 1, got %d and %d", first.Group, second.Group)
+	}
+	if !floatsClose(first.X, 0.5, 0.05) || !floatsClose(first.Y, 0, 1e-9) {
+		t.Errorf("Expected first body at (0.5, 0), got (%f, %f)", first.X, first.Y)
+	}
+	if !floatsClose(second.X, 0, 1e-9) || !floatsClose(second.Y, -0.5, 0.05) {
+		t.Errorf("Expected second body at (0, -0.5), got (%f, %f)", second.X, second.Y)
+	}
+
+	tests := []struct {
+		name   string
+		groups [][]int
+	}{
+		{name: "Overlapping", groups: [][]int{{0, 1}, {1, 2, 3}}},
+		{name: "Missing", groups: [][]int{{0, 1}, {2}}},
+		{name: "OutOfRange", groups: [][]int{{0, 1}, {2, 3, 4}}},
+		{name: "Empty", groups: [][]int{{0, 1, 2, 3}, {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sys.SetGroups(tt.groups); err == nil {
+				t.Errorf("Expected error for groups %v", tt.groups)
+			}
+		})
+	}
+}
+
+func TestIMUFusionSystemFusionRate(t *testing.T) {
+	run := func(hz float64) []FusedSample {
+		sys, err := NewIMUFusionSystem(2)
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystem failed: %v", err)
+		}
+		var samples []FusedSample
+		sys.output = func(sample FusedSample) { samples = append(samples, sample) }
+		sys.SetStationarityDetection(0, 0, 0)
+		sys.SetRefinementRadius(0) // the two rates leave point clouds of different density
+		sys.SetFusionRate(hz)
+		base := time.Unix(1, 0)
+		sys.lastTime = base
+		// One second of 1000Hz frames.
+		for i := 1; i <= 1000; i++ {
+			ts := base.Add(time.Duration(i) * time.Millisecond)
+			sys.processFrame([]IMUData{
+				{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+				{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			})
+		}
+		return samples
+	}
+
+	every, throttled := run(0), run(100)
+	if len(every) != 1000 {
+		t.Errorf("Expected 1000 fusions without throttling, got %d", len(every))
+	}
+	if n := len(throttled); n < 99 || n > 100 {
+		t.Errorf("Expected about 100 fusions at 100Hz, got %d", n)
+	}
+	for i := 1; i < len(throttled); i++ {
+		if dt := throttled[i].Timestamp.Sub(throttled[i-1].Timestamp); dt != 10*time.Millisecond {
+			t.Fatalf("Expected fusions 10ms apart, got %v at %d", dt, i)
+		}
+	}
+
+	// Averaging keeps the integrated motion of the frames in between.
+	lastThrottled := throttled[len(throttled)-1]
+	for _, sample := range every {
+		if sample.Timestamp.Equal(lastThrottled.Timestamp) && !floatsClose(lastThrottled.X, sample.X, 0.005) {
+			t.Errorf("Expected throttled position %f to match %f at %v", lastThrottled.X, sample.X, sample.Timestamp)
+		}
+	}
+}
+
+func TestIMUFusionSystemLatencyHistogram(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	const frames = 20
+	for i := 1; i <= frames; i++ {
+		ts := base.Add(time.Duration(i) * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}})
+	}
+	var total uint64
+	for _, b := range sys.LatencyHistogram() {
+		total += b.Count
+	}
+	if total != frames {
+		t.Errorf("Expected %d frames in the histogram, got %d", frames, total)
+	}
+
+	// Slow frames land in the tail buckets, which the average alone would blur. The fake clock
+	// times the frames, and the filter of IMU 0 advances it as it integrates.
+	clock := NewFakeClock(base.Add(frames * time.Millisecond))
+	sys.SetClock(clock)
+	slow := &slowFilter{Filter: sys.filters[0], clock: clock}
+	sys.filters[0] = slow
+	for i, delay := range []time.Duration{30 * time.Millisecond, 40 * time.Millisecond, time.Second} {
+		slow.delay = delay
+		ts := base.Add(time.Duration(frames+1+i) * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}})
+	}
+	histogram := sys.LatencyHistogram()
+	tail := histogram[len(histogram)-1]
+	if tail.Count != 1 || tail.UpperBound != time.Duration(math.MaxInt64) {
+		t.Errorf("Expected one frame in the unbounded tail bucket, got %+v", tail)
+	}
+	for _, b := range histogram {
+		if b.UpperBound == 50*time.Millisecond && b.Count != 2 {
+			t.Errorf("Expected 2 frames in the 25-50ms bucket, got %d", b.Count)
+		}
+	}
+}
+
+// slowFilter is a Filter whose prediction takes delay on a fake clock.
+type slowFilter struct {
+	Filter
+	clock *FakeClock
+	delay time.Duration
+}
+
+func (f *slowFilter) Predict(accel, gyro [3]float64, dt float64) {
+	f.clock.Advance(f.delay)
+	f.Filter.Predict(accel, gyro, dt)
+}
+
+// linearModel is an UncertaintyModel growing linearly from a floor, like a bias instability term.
+type linearModel struct {
+	floor, rate float64
+	calls       int
+}
+
+func (m *linearModel) Estimate(dt float64) float64 {
+	m.calls++
+	return m.floor + m.rate*dt
+}
+
+func TestIMUFusionSystemUncertaintyModel(t *testing.T) {
+	sys, err := NewIMUFusionSystem(2)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	model := &linearModel{floor: 0.3, rate: 2}
+	sys.SetUncertaintyModel(model)
+
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	// Holding the IMUs far apart keeps fusion poor, so the dead-reckoning times the radii were
+	// computed from are not reset after each frame.
+	for i := 1; i <= 2; i++ {
+		ts := base.Add(time.Duration(i) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts},
+			{IMUID: 1, DeviceTimestamp: ts},
+		})
+		sys.filters[1].SetPosition([3]float64{100, 0, 0})
+	}
+	if model.calls != 4 {
+		t.Errorf("Expected the model to be called for 2 IMUs over 2 frames, got %d calls", model.calls)
+	}
+	for i, r := range sys.Uncertainties() {
+		if want := model.Estimate(sys.deadReckoning[i]); !floatsClose(r, want, 1e-12) {
+			t.Errorf("IMU %d: Expected circle radius %f from the model, got %f", i, want, r)
+		}
+	}
+
+	sys.SetUncertaintyModel(nil)
+	if _, ok := sys.uncertainty.(WhiteNoiseModel); !ok {
+		t.Errorf("Expected nil to restore WhiteNoiseModel, got %T", sys.uncertainty)
+	}
+}
+
+func TestIMUFusionSystemStatusGoesStale(t *testing.T) {
+	const imuCount = 2
+	src := &chanSource{samples: make(chan IMUData, 64)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.SetStaleTimeout(50 * time.Millisecond)
+	sys.Start()
+	defer sys.Stop()
+
+	if s := sys.Statuses()[0]; s.State != IMUStale {
+		t.Errorf("Expected an IMU without samples to be stale, got %v", s.State)
+	}
+
+	// Both IMUs report at 1kHz device time, then IMU 1 goes silent while IMU 0 keeps reporting.
+	base := time.Unix(1, 0)
+	for i := 0; i < 10; i++ {
+		ts := base.Add(time.Duration(i) * time.Millisecond)
+		src.samples <- IMUData{IMUID: 0, DeviceTimestamp: ts}
+		src.samples <- IMUData{IMUID: 1, DeviceTimestamp: ts}
+	}
+	deadline := time.After(time.Second)
+	for statuses := sys.Statuses(); statuses[0].State != IMULive || statuses[1].State != IMULive; statuses = sys.Statuses() {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for both IMUs to be live, got %v", statuses)
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+	if rate := sys.Statuses()[1].SampleRate; !floatsClose(rate, 1000, 1e-6) {
+		t.Errorf("Expected a 1000Hz sample rate, got %f", rate)
+	}
+
+	for i := 10; i < 40; i++ {
+		src.samples <- IMUData{IMUID: 0, DeviceTimestamp: base.Add(time.Duration(i) * time.Millisecond)}
+		time.Sleep(5 * time.Millisecond)
+	}
+	statuses := sys.Statuses()
+	if statuses[0].State != IMULive {
+		t.Errorf("Expected the reporting IMU to stay live, got %v", statuses[0].State)
+	}
+	if statuses[1].State != IMUStale {
+		t.Errorf("Expected the silent IMU to go stale, got %v", statuses[1].State)
+	}
+}
+
+func TestHealthMonitorFaults(t *testing.T) {
+	h := NewHealthMonitor(time.Second)
+	h.SetFaultLimit(100)
+	now := time.Unix(10, 0)
+	h.Observe(IMUData{IMUID: 0, DeviceTimestamp: now}, now)
+	if s := h.Status(0, now); s.State != IMULive {
+		t.Fatalf("Expected live, got %v", s.State)
+	}
+	tests := []struct {
+		name  string
+		accel [3]float64
+	}{
+		{name: "NaN", accel: [3]float64{math.NaN(), 0, 0}},
+		{name: "Outlier", accel: [3]float64{0, 0, 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthMonitor(time.Second)
+			h.SetFaultLimit(100)
+			h.Observe(IMUData{IMUID: 0, DeviceTimestamp: now, Acceleration: tt.accel}, now)
+			if s := h.Status(0, now); s.State != IMUFaulted || s.Faults != 1 {
+				t.Errorf("Expected faulted with 1 fault, got %v with %d", s.State, s.Faults)
+			}
+			// A clean sample does not clear the fault until the timeout passes.
+			later := now.Add(2 * time.Second)
+			h.Observe(IMUData{IMUID: 0, DeviceTimestamp: later}, later)
+			if s := h.Status(0, later); s.State != IMULive {
+				t.Errorf("Expected live once the fault is older than the timeout, got %v", s.State)
+			}
+		})
+	}
+}
+
+// dtRecorder is a Filter recording the time step of every Predict.
+type dtRecorder struct {
+	Filter
+	mu  sync.Mutex
+	dts []float64
+}
+
+func (r *dtRecorder) Predict(accel, gyro [3]float64, dt float64) {
+	r.mu.Lock()
+	r.dts = append(r.dts, dt)
+	r.mu.Unlock()
+	r.Filter.Predict(accel, gyro, dt)
+}
+
+func (r *dtRecorder) steps() []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.dts...)
+}
+
+func TestIMUFusionSystemFakeClock(t *testing.T) {
+	const imuCount = 2
+	const frames = 5
+	src := &chanSource{samples: make(chan IMUData)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	clock := NewFakeClock(time.Unix(100, 0))
+	sys.SetClock(clock)
+	recorder := &dtRecorder{Filter: sys.filters[0]}
+	sys.filters[0] = recorder
+	sys.Start()
+	defer sys.Stop()
+
+	// Samples carry no device timestamp, so frames are timed by the receive stamps of the clock.
+	for k := 0; k < frames; k++ {
+		clock.Advance(10 * time.Millisecond)
+		for id := 0; id < imuCount; id++ {
+			src.samples <- IMUData{IMUID: id, Acceleration: [3]float64{1, 0, 0}}
+		}
+		deadline := time.After(time.Second)
+		for len(recorder.steps()) <= k {
+			select {
+			case <-deadline:
+				t.Fatalf("timed out waiting for frame %d", k)
+			case <-time.After(1 * time.Millisecond):
+			}
+		}
+	}
+
+	dts := recorder.steps()
+	if len(dts) != frames {
+		t.Fatalf("Expected %d time steps, got %d", frames, len(dts))
+	}
+	for k, dt := range dts {
+		if dt != 0.01 {
+			t.Errorf("Expected frame %d to step exactly 0.01s, got %v", k, dt)
+		}
+	}
+	if got, want := sys.Statuses()[0].LastSample, time.Unix(100, 0).Add(frames*10*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Expected last sample at %v, got %v", want, got)
+	}
+}
+
+func BenchmarkIMUFusionSystemProcessFrame(b *testing.B) {
+	const imuCount = 4
+	sys, err := NewIMUFusionSystemWithSource(imuCount, &chanSource{})
+	if err != nil {
+		b.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	start := time.Unix(1, 0)
+	sys.lastTime = start
+	frame := make([]IMUData, imuCount)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts := start.Add(time.Duration(i+1) * time.Millisecond)
+		for id := range frame {
+			frame[id] = IMUData{IMUID: id, DeviceTimestamp: ts, Acceleration: [3]float64{0.1 * float64(id+1), 0.05, 0}}
+		}
+		sys.processFrame(frame)
+	}
+}
+
+func TestFuseFrame(t *testing.T) {
+	const frames = 20
+	const dt = 0.01
+	base := time.Unix(1, 0)
+	tests := []struct {
+		name  string
+		accel [2][3]float64 // per IMU
+		stamp func(k int) time.Time
+		wantX float64
+	}{
+		{
+			name:  "AgreeingIMUs",
+			accel: [2][3]float64{{1, 0, 0}, {1, 0, 0}},
+			stamp: func(k int) time.Time { return base.Add(time.Duration(k+1) * 10 * time.Millisecond) },
+			wantX: 0.5 * 1 * (frames * dt) * (frames * dt),
+		},
+		{
+			// The time step comes from dt, not the frame timestamps.
+			name:  "FrozenTimestamps",
+			accel: [2][3]float64{{2, 0, 0}, {2, 0, 0}},
+			stamp: func(int) time.Time { return base },
+			wantX: 0.5 * 2 * (frames * dt) * (frames * dt),
+		},
+		{
+			name:  "AtRest",
+			stamp: func(k int) time.Time { return base.Add(time.Duration(k+1) * 10 * time.Millisecond) },
+			wantX: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewFusionState(2)
+			state.refinementRadius = 0
+			var got Position
+			for k := 0; k < frames; k++ {
+				ts := tt.stamp(k)
+				got = FuseFrame(state, []IMUData{
+					{IMUID: 0, DeviceTimestamp: ts, Acceleration: tt.accel[0]},
+					{IMUID: 1, DeviceTimestamp: ts, Acceleration: tt.accel[1]},
+				}, dt)
+			}
+			if !floatsClose(got.X, tt.wantX, 1e-9) || !floatsClose(got.Y, 0, 1e-9) {
+				t.Errorf("Expected (%f, 0), got (%f, %f)", tt.wantX, got.X, got.Y)
+			}
+			if p, ok := state.CurrentPosition(); !ok || p != got {
+				t.Errorf("Expected the state's current position %v to be the last result %v", p, got)
+			}
+		})
+	}
+
+	if got := FuseFrame(NewFusionState(2), nil, dt); got != (Position{}) {
+		t.Errorf("Expected the zero Position for an empty frame, got %v", got)
+	}
+}
+
+func TestIMUFusionSystemAutoFitReference(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(4, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	// The IMUs sit on a square of side 2, while the configured reference puts them all at the
+	// body reference point.
+	surveyed := []Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}
+	if err := sys.Rezero(surveyed); err != nil {
+		t.Fatalf("Rezero failed: %v", err)
+	}
+	sys.AutoFitReference()
+	start := time.Unix(1, 0)
+	sys.lastTime = start
+	for k := 1; k <= defaultStationaryWindow+autoFitFrames; k++ {
+		ts := start.Add(time.Duration(k) * time.Millisecond)
+		frame := make([]IMUData, 4)
+		for id := range frame {
+			frame[id] = IMUData{IMUID: id, DeviceTimestamp: ts}
+		}
+		sys.processFrame(frame)
+	}
+
+	if sys.referenceFit != nil {
+		t.Fatalf("Expected the fit to complete after %d stationary frames", autoFitFrames)
+	}
+	for i := range surveyed {
+		j := (i + 1) % len(surveyed)
+		a, b := sys.extrinsics[i].Offset, sys.extrinsics[j].Offset
+		if d := math.Hypot(a.X-b.X, a.Y-b.Y); !floatsClose(d, 2, 1e-3) {
+			t.Errorf("Expected reference spacing 2 between IMUs %d and %d, got %f", i, j, d)
+		}
+	}
+	if want := (Point{X: 1, Y: 1}); !floatsClose(sys.extrinsics[2].Offset.X, want.X, 1e-3) || !floatsClose(sys.extrinsics[2].Offset.Y, want.Y, 1e-3) {
+		t.Errorf("Expected IMU 2 at %v from the centroid, got %v", want, sys.extrinsics[2].Offset)
+	}
+}
+
+func TestIMUFusionSystemSingleIMU(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(1, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.refinementRadius = 0
+	var emitted []FusedSample
+	sys.output = func(s FusedSample) { emitted = append(emitted, s) }
+
+	base := time.Unix(1, 0)
+	for k := 0; k < 20; k++ {
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: base.Add(time.Duration(k) * 10 * time.Millisecond), Acceleration: [3]float64{1, 0.5, 0}}})
+		if len(emitted) != k+1 {
+			t.Fatalf("frame %d: Expected a sample per frame, got %d", k, len(emitted))
+		}
+		p := sys.filters[0].Position()
+		if got := emitted[k]; got.X != p[0] || got.Y != p[1] {
+			t.Errorf("frame %d: Expected the integrated position (%f, %f), got (%f, %f)", k, p[0], p[1], got.X, got.Y)
+		}
+	}
+	if p, _ := sys.CurrentPosition(); p.R != 1 {
+		t.Errorf("Expected alpha 1 for an unfused IMU, got %f", p.R)
+	}
+	if sys.tracker.lastEvals != 0 {
+		t.Errorf("Expected the geometric fusion to be skipped, got %d evaluations", sys.tracker.lastEvals)
+	}
+	if emitted[len(emitted)-1].X <= 0 {
+		t.Errorf("Expected the IMU to have moved, got %v", emitted[len(emitted)-1])
+	}
+}
+
+func TestIMUFusionSystemOneIMUPresent(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(3, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.refinementRadius = 0
+	var emitted []FusedSample
+	sys.output = func(s FusedSample) { emitted = append(emitted, s) }
+	if err := sys.DisableIMU(2); err != nil {
+		t.Fatalf("DisableIMU failed: %v", err)
+	}
+
+	// IMU 1 is dropped from every frame and IMU 2 is disabled, leaving IMU 0 alone.
+	base := time.Unix(1, 0)
+	for k := 0; k < 10; k++ {
+		ts := base.Add(time.Duration(k) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0.5, 0}},
+			{IMUID: 2, DeviceTimestamp: ts, Acceleration: [3]float64{-1, 0, 0}},
+		})
+		p := sys.filters[0].Position()
+		if got := emitted[len(emitted)-1]; got.X != p[0] || got.Y != p[1] {
+			t.Errorf("frame %d: Expected the position of IMU 0 (%f, %f), got (%f, %f)", k, p[0], p[1], got.X, got.Y)
+		}
+	}
+	if p, _ := sys.CurrentPosition(); p.R != 1 {
+		t.Errorf("Expected alpha 1 for an unfused IMU, got %f", p.R)
+	}
+	if sys.tracker.lastEvals != 0 {
+		t.Errorf("Expected the geometric fusion to be skipped, got %d evaluations", sys.tracker.lastEvals)
+	}
+
+	// IMU 0 is the only one through the gate: it is passed through and corrects the others.
+	sys.gatingThreshold = 9.21
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	positions, present := sys.scratch.frame(3)
+	positions[0], positions[1], positions[2] = Point{X: 0.05}, Point{X: 5}, Point{Y: 5}
+	present[0], present[1], present[2] = true, true, true
+	for i := range sys.uncertainties {
+		sys.uncertainties[i] = 0.1
+		sys.filters[i].Predict([3]float64{}, [3]float64{}, 1) // position no longer certain
+		sys.filters[i].SetPosition([3]float64{positions[i].X, positions[i].Y, 0})
+	}
+	lastFused, hasFused := Vec2{}, true
+	fused, ok := sys.fuseIMUs(sys.tracker, &lastFused, &hasFused, positions, present, 3, base)
+	if !ok || fused.position != (Vec2{X: 0.05}) || fused.alpha != 1 {
+		t.Errorf("Expected IMU 0 passed through at alpha 1, got %+v", fused)
+	}
+	if p := sys.filters[0].Position(); p[0] != 0.05 {
+		t.Errorf("Expected IMU 0 not corrected by its own position, got %v", p)
+	}
+	if p := sys.filters[1].Position(); p[0] >= 5 {
+		t.Errorf("Expected the gated IMU 1 pulled towards IMU 0, got %v", p)
+	}
+}
+
+func TestIMUFusionSystemAlphaProbes(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	frame := func(k int) []IMUData {
+		ts := time.Unix(1, 0).Add(time.Duration(k) * 10 * time.Millisecond)
+		return []IMUData{
+			{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0, 0}},
+			{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{-1, 0, 0}},
+		}
+	}
+	sys.processFrame(frame(0))
+	if probes := sys.AlphaProbes(); probes != nil {
+		t.Errorf("Expected no probes unless recording, got %v", probes)
+	}
+	sys.SetRecordAlphaProbes(true)
+	for k := 1; k < 5; k++ {
+		sys.processFrame(frame(k))
+		if len(sys.AlphaProbes()) == 0 {
+			t.Errorf("frame %d: Expected the alpha search of the frame recorded", k)
+		}
+	}
+	sys.SetRecordAlphaProbes(false)
+	if probes := sys.AlphaProbes(); probes != nil {
+		t.Errorf("Expected no probes once recording stops, got %v", probes)
+	}
+}
+
+func TestFuseFrameQualityWeighting(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality float64 // of IMU 1
+		wantX   float64
+	}{
+		// Equal radii meet midway between the IMUs.
+		{name: "Unset", quality: 0, wantX: 0.5},
+		{name: "Full", quality: 1, wantX: 0.5},
+		// A quarter-quality sample has four times the radius, so the circles meet at a fifth of
+		// the way from IMU 0.
+		{name: "Low", quality: 0.25, wantX: 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewFusionState(2)
+			state.refinementRadius = 0
+			state.uncertainty = &linearModel{floor: 0.1}
+			state.filters[1].SetPosition([3]float64{1, 0, 0})
+			ts := time.Unix(1, 0)
+			got := FuseFrame(state, []IMUData{
+				{IMUID: 0, DeviceTimestamp: ts},
+				{IMUID: 1, DeviceTimestamp: ts, Quality: tt.quality},
+			}, 0.001)
+			if !floatsClose(got.X, tt.wantX, 1e-3) || !floatsClose(got.Y, 0, 1e-3) {
+				t.Errorf("Expected (%.3f, 0), got (%f, %f)", tt.wantX, got.X, got.Y)
+			}
+		})
+	}
+}
+
+func TestIMUFusionSystemOnFusionFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		radius    float64
+		positions [3]Point
+		want      string // reason, "" for no callback
+	}{
+		{name: "Intersecting", radius: 0.1, positions: [3]Point{{X: 0}, {X: 0.15}, {X: 0.1, Y: 0.1}}, want: ""},
+		// Circles of radius 0.1 a distance 5 apart do not meet even expanded tenfold.
+		{name: "Disjoint", radius: 0.1, positions: [3]Point{{X: 0}, {X: 5}, {X: 0, Y: 5}}, want: FusionFailureDisjoint},
+		// Expanded tenfold every pair meets, but the third circle stops short of the lens of the others.
+		{name: "AlphaExceeded", radius: 0.1, positions: [3]Point{{X: 0}, {X: 1.9}, {X: 0.95, Y: 1.5}}, want: FusionFailureAlphaExceeded},
+		{name: "Degenerate", radius: 0, positions: [3]Point{{X: 0}, {X: 1}, {X: 0, Y: 1}}, want: FusionFailureDegenerate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sys, err := NewIMUFusionSystemWithSource(3, &chanSource{})
+			if err != nil {
+				t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+			}
+			var reasons []string
+			var frames [][]IMUData
+			sys.OnFusionFailure(func(frame []IMUData, reason string) {
+				reasons = append(reasons, reason)
+				frames = append(frames, frame)
+			})
+			sys.refinementRadius = 0
+			sys.uncertainty = &linearModel{floor: tt.radius}
+			for i, p := range tt.positions {
+				sys.filters[i].SetPosition([3]float64{p.X, p.Y, 0})
+			}
+			ts := time.Unix(1, 0)
+			frame := []IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}, {IMUID: 2, DeviceTimestamp: ts}}
+			FuseFrame(sys.FusionState, frame, 0.001)
+
+			if tt.want == "" {
+				if len(reasons) != 0 {
+					t.Errorf("Expected no failure, got %v", reasons)
+				}
+				return
+			}
+			if len(reasons) != 1 || reasons[0] != tt.want {
+				t.Fatalf("Expected one %s failure, got %v", tt.want, reasons)
+			}
+			if len(frames[0]) != len(frame) || frames[0][0].IMUID != 0 {
+				t.Errorf("Expected the failing frame, got %v", frames[0])
+			}
+		})
+	}
+}
+
+func TestNewIMUFusionSystemOptions(t *testing.T) {
+	defaults, err := NewIMUFusionSystemWithSource(3, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	if defaults.noiseLevel != defaultNoiseLevel || defaults.tracker.minAlpha != alphaLowerBound ||
+		defaults.tracker.maxAlpha != alphaUpperBound || defaults.tracker.mode != FusionStrict {
+		t.Errorf("Expected the defaults without options, got noise %f, alpha [%f, %f], mode %v",
+			defaults.noiseLevel, defaults.tracker.minAlpha, defaults.tracker.maxAlpha, defaults.tracker.mode)
+	}
+
+	var logged bytes.Buffer
+	sys, err := NewIMUFusionSystemWithSource(3, &chanSource{},
+		WithNoise(0.2),
+		WithAlphaBounds(1, 4),
+		WithFusionMode(FusionMedianFallback),
+		WithLogger(log.New(&logged, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	if sys.noiseLevel != 0.2 || sys.uncertainty != (WhiteNoiseModel{NoiseLevel: 0.2}) {
+		t.Errorf("Expected noise level 0.2, got %f and %v", sys.noiseLevel, sys.uncertainty)
+	}
+
+	// Circles that never meet are fused at the upper bound, at their geometric median.
+	positions := []Position{{X: 0, Y: 0, R: 0.1}, {X: 100, Y: 0, R: 0.1}, {X: 50, Y: 80, R: 0.1}}
+	alpha, fused := sys.tracker.Fuse(positions)
+	want := GeometricMedian([]Vec2{{0, 0}, {100, 0}, {50, 80}}, []float64{0.1, 0.1, 0.1})
+	if alpha != 4 || fused.X != want.X || fused.Y != want.Y {
+		t.Errorf("Expected alpha 4 at %v, got %f at %v", want, alpha, fused)
+	}
+
+	FuseFrame(sys.FusionState, []IMUData{{IMUID: 7, DeviceTimestamp: time.Unix(1, 0)}}, 0.001)
+	if !strings.Contains(logged.String(), "IMUID 7 out of bounds") {
+		t.Errorf("Expected the warning in the configured logger, got %q", logged.String())
+	}
+	sys.SetFilterKind(FilterUKF)
+	if ukf := sys.filters[0].(*UKF); ukf.logger != sys.logger {
+		t.Errorf("Expected the UKF to warn through the configured logger, got %v", ukf.logger)
+	}
+
+	for _, opt := range []Option{WithNoise(0), WithNoise(math.NaN()), WithAlphaBounds(2, 1), WithAlphaBounds(0, 1)} {
+		if _, err := NewIMUFusionSystemWithSource(3, &chanSource{}, opt); err == nil {
+			t.Errorf("Expected an error for an invalid option")
+		}
+	}
+}
+
+func TestIMUFusionSystemHeadingAcrossWrap(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	if _, ok := sys.Heading(); ok {
+		t.Errorf("Expected no heading from EKF filters")
+	}
+	sys.SetFilterKind(FilterUKF)
+	for i, heading := range []float64{179 * math.Pi / 180, -179 * math.Pi / 180} {
+		state := sys.filters[i].State()
+		state.Heading = heading
+		if err := sys.filters[i].SetState(state); err != nil {
+			t.Fatalf("SetState failed: %v", err)
+		}
+	}
+	heading, ok := sys.Heading()
+	if !ok || math.Abs(wrapAngle(heading-math.Pi)) > 1e-9 {
+		t.Errorf("Expected heading pi, got %f (ok %v)", heading, ok)
+	}
+}
+
+func TestIMUFusionSystemFusedVelocity(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	var last FusedSample
+	sys.output = func(s FusedSample) { last = s }
+	// Both IMUs coast at a constant velocity.
+	want := [3]float64{0.5, -0.2, 0}
+	for _, f := range sys.filters {
+		state := f.State()
+		state.Velocity = want
+		if err := f.SetState(state); err != nil {
+			t.Fatalf("SetState failed: %v", err)
+		}
+	}
+	start := time.Unix(1, 0)
+	sys.lastTime = start
+	// Fewer frames than the stationarity window, so the coasting is not mistaken for rest.
+	for k := 1; k < defaultStationaryWindow; k++ {
+		ts := start.Add(time.Duration(k) * 10 * time.Millisecond)
+		sys.processFrame([]IMUData{{IMUID: 0, DeviceTimestamp: ts}, {IMUID: 1, DeviceTimestamp: ts}})
+	}
+	if !floatsClose(last.VX, want[0], 1e-6) || !floatsClose(last.VY, want[1], 1e-6) {
+		t.Errorf("Expected fused velocity (%f, %f), got (%f, %f)", want[0], want[1], last.VX, last.VY)
+	}
+}
+
+func TestIMUFusionSystemOrigin(t *testing.T) {
+	origin := Point{X: 10, Y: -5}
+	run := func(setOrigin bool) []FusedSample {
+		sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+		if err != nil {
+			t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+		}
+		var out []FusedSample
+		sys.output = func(s FusedSample) { out = append(out, s) }
+		if setOrigin {
+			sys.SetOrigin(origin)
+		}
+		start := time.Unix(1, 0)
+		sys.lastTime = start
+		frame := func(k int) []IMUData {
+			ts := start.Add(time.Duration(k) * 10 * time.Millisecond)
+			return []IMUData{
+				{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0.5, 0}},
+				{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{1, 0.5, 0}},
+			}
+		}
+		for k := 1; k <= 20; k++ {
+			sys.processFrame(frame(k))
+		}
+		// Rezero anchors are internal positions; the origin applies on top of them.
+		if err := sys.RezeroAll(Point{X: 1, Y: 2}); err != nil {
+			t.Fatalf("RezeroAll failed: %v", err)
+		}
+		sys.processFrame(frame(21))
+		return out
+	}
+	plain, shifted := run(false), run(true)
+	if len(plain) != len(shifted) || len(plain) == 0 {
+		t.Fatalf("Expected matching outputs, got %d and %d samples", len(plain), len(shifted))
+	}
+	for i := range plain {
+		if !floatsClose(shifted[i].X, plain[i].X+origin.X, 1e-9) || !floatsClose(shifted[i].Y, plain[i].Y+origin.Y, 1e-9) {
+			t.Errorf("Expected sample %d at (%f, %f), got (%f, %f)", i, plain[i].X+origin.X, plain[i].Y+origin.Y, shifted[i].X, shifted[i].Y)
+		}
+	}
+	if last := shifted[len(shifted)-1]; !floatsClose(last.X, 11, 0.01) || !floatsClose(last.Y, -3, 0.01) {
+		t.Errorf("Expected the rezeroed body near (11, -3), got (%f, %f)", last.X, last.Y)
+	}
+}
+
+func TestIMUFusionSystemAdaptiveNoise(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	// A constant base radius isolates the motion scaling.
+	sys.SetUncertaintyModel(&linearModel{floor: 0.1})
+	if err := sys.SetAdaptiveNoise(AdaptiveNoise{Reference: 1, MinScale: 0.5, MaxScale: 4, Window: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("SetAdaptiveNoise failed: %v", err)
+	}
+	if err := sys.SetAdaptiveNoise(AdaptiveNoise{Reference: 1, MinScale: 2, MaxScale: 1, Window: time.Second}); err == nil {
+		t.Errorf("Expected an error for inverted scale bounds")
+	}
+
+	start := time.Unix(1, 0)
+	sys.lastTime = start
+	k := 0
+	run := func(frames int, accel float64) []float64 {
+		for i := 0; i < frames; i++ {
+			k++
+			ts := start.Add(time.Duration(k) * time.Millisecond)
+			sys.processFrame([]IMUData{
+				{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{accel, 0, 0}},
+				{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{accel, 0, 0}},
+			})
+		}
+		return sys.Uncertainties()
+	}
+
+	phases := []struct {
+		name   string
+		accel  float64
+		radius float64
+	}{
+		{name: "rest", accel: 0, radius: 0.05}, // clamped to MinScale
+		{name: "burst", accel: 3, radius: 0.3}, // 3 m/s^2 RMS against a 1 m/s^2 reference
+		{name: "rest again", accel: 0, radius: 0.05},
+	}
+	for _, p := range phases {
+		for i, r := range run(100, p.accel) {
+			if !floatsClose(r, p.radius, 1e-9) {
+				t.Errorf("Expected IMU %d radius %f at %s, got %f", i, p.radius, p.name, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of pipeline throughput and latency.
+type Metrics struct {
+	FramesProcessed    uint64        // aligned frames fused since Start
+	DroppedFrames      uint64        // frames discarded before fusion, e.g. by the pause buffer
+	DroppedSamples     uint64        // samples discarded by acquisition backpressure, see SetBackpressure
+	NonMonotonicFrames uint64        // frames whose timestamp did not advance past the previous frame
+	InvalidSamples     uint64        // samples rejected for NaN or Inf readings, at ingest or during fusion
+	NonFiniteFrames    uint64        // frames skipped because fusion produced NaN or Inf
+	AvgFusionDuration  time.Duration // mean time spent fusing and refining a frame
+	CloudPoints        int           // current number of points in the point cloud
+}
+
+// Bucket is one bin of the frame latency histogram: the frames whose fusion took longer than the
+// previous bucket's UpperBound and at most this one's.
+type Bucket struct {
+	UpperBound time.Duration // inclusive; the last bucket is unbounded, with the largest Duration
+	Count      uint64
+}
+
+// latencyBounds are the upper bounds of the latency histogram buckets, before the unbounded one.
+var latencyBounds = [...]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// pipelineCounters holds the live counters behind Metrics.
+// They are updated with atomics so readers never block processDataLoop.
+type pipelineCounters struct {
+	framesProcessed uint64
+	droppedFrames   uint64
+	nonMonotonic    uint64
+	invalidSamples  uint64
+	nonFinite       uint64
+	fusionNanos     uint64 // cumulative fusion duration
+	latency         [len(latencyBounds) + 1]uint64
+	cloudPoints     int64
+}
+
+func (c *pipelineCounters) recordFrame(d time.Duration, cloudPoints int) {
+	atomic.AddUint64(&c.framesProcessed, 1)
+	atomic.AddUint64(&c.fusionNanos, uint64(d))
+	bucket := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if d <= bound {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddUint64(&c.latency[bucket], 1)
+	atomic.StoreInt64(&c.cloudPoints, int64(cloudPoints))
+}
+
+func (c *pipelineCounters) recordDropped(n int) {
+	atomic.AddUint64(&c.droppedFrames, uint64(n))
+}
+
+func (c *pipelineCounters) recordNonMonotonic() {
+	atomic.AddUint64(&c.nonMonotonic, 1)
+}
+
+func (c *pipelineCounters) recordInvalid() {
+	atomic.AddUint64(&c.invalidSamples, 1)
+}
+
+func (c *pipelineCounters) recordNonFinite() {
+	atomic.AddUint64(&c.nonFinite, 1)
+}
+
+func (c *pipelineCounters) snapshot() Metrics {
+	m := Metrics{
+		FramesProcessed:    atomic.LoadUint64(&c.framesProcessed),
+		DroppedFrames:      atomic.LoadUint64(&c.droppedFrames),
+		NonMonotonicFrames: atomic.LoadUint64(&c.nonMonotonic),
+		InvalidSamples:     atomic.LoadUint64(&c.invalidSamples),
+		NonFiniteFrames:    atomic.LoadUint64(&c.nonFinite),
+		CloudPoints:        int(atomic.LoadInt64(&c.cloudPoints)),
+	}
+	if m.FramesProcessed > 0 {
+		m.AvgFusionDuration = time.Duration(atomic.LoadUint64(&c.fusionNanos) / m.FramesProcessed)
+	}
+	return m
+}
+
+// latencyHistogram returns the frame latency histogram.
+func (c *pipelineCounters) latencyHistogram() []Bucket {
+	buckets := make([]Bucket, len(c.latency))
+	for i := range buckets {
+		buckets[i].UpperBound = time.Duration(math.MaxInt64)
+		if i < len(latencyBounds) {
+			buckets[i].UpperBound = latencyBounds[i]
+		}
+		buckets[i].Count = atomic.LoadUint64(&c.latency[i])
+	}
+	return buckets
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"math"
+	"time"
+)
+
+// motionSample is a squared acceleration magnitude and its sample time.
+type motionSample struct {
+	at     time.Time
+	square float64
+}
+
+// MotionMonitor keeps the recent acceleration magnitudes of each IMU, so that the amount of
+// motion over a window can drive adaptive tuning, such as more process noise while moving.
+type MotionMonitor struct {
+	samples [][]motionSample // per-IMU ring buffer
+	counts  []int            // per-IMU samples in the ring buffer
+	next    []int            // per-IMU ring buffer write index
+	latest  time.Time        // latest sample time, the end of the RMS window
+}
+
+// NewMotionMonitor creates a monitor for imuCount IMUs keeping the last history samples of each.
+func NewMotionMonitor(imuCount, history int) *MotionMonitor {
+	m := &MotionMonitor{
+		samples: make([][]motionSample, imuCount),
+		counts:  make([]int, imuCount),
+		next:    make([]int, imuCount),
+	}
+	for i := range m.samples {
+		m.samples[i] = make([]motionSample, history)
+	}
+	return m
+}
+
+// Add records an acceleration of an IMU at time at.
+func (m *MotionMonitor) Add(imuID int, at time.Time, ax, ay float64) {
+	buf := m.samples[imuID]
+	if len(buf) == 0 {
+		return
+	}
+	buf[m.next[imuID]] = motionSample{at: at, square: ax*ax + ay*ay}
+	m.next[imuID] = (m.next[imuID] + 1) % len(buf)
+	if m.counts[imuID] < len(buf) {
+		m.counts[imuID]++
+	}
+	if at.After(m.latest) {
+		m.latest = at
+	}
+}
+
+// RMS returns the root mean square acceleration magnitude of each IMU over the window ending at
+// the latest sample of any IMU, or 0 for an IMU with no samples in it. The window is limited by
+// the history kept.
+func (m *MotionMonitor) RMS(window time.Duration) []float64 {
+	return m.rmsInto(make([]float64, len(m.samples)), window)
+}
+
+// rmsInto is RMS writing into rms, which must have one entry per IMU.
+func (m *MotionMonitor) rmsInto(rms []float64, window time.Duration) []float64 {
+	since := m.latest.Add(-window)
+	for i, buf := range m.samples {
+		var sum float64
+		n := 0
+		for _, s := range buf[:m.counts[i]] {
+			if s.at.After(since) {
+				sum += s.square
+				n++
+			}
+		}
+		rms[i] = 0
+		if n > 0 {
+			rms[i] = math.Sqrt(sum / float64(n))
+		}
+	}
+	return rms
+}
+
+// Reset discards all samples.
+func (m *MotionMonitor) Reset() {
+	for i := range m.samples {
+		m.counts[i] = 0
+		m.next[i] = 0
+	}
+	m.latest = time.Time{}
+}
@@ -1,55 +1,438 @@
 package internal
 
-import "sync"
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// stampedPoint is a cloud point with its insertion time.
+type stampedPoint struct {
+	Point
+	added time.Time
+}
+
+// Metric returns the distance between two points. PointCloud searches include a point when its
+// distance from the query is at most the search radius, so a radius search under a metric
+// returns that metric's ball: a disc for EuclideanDistance, a diamond for ManhattanDistance.
+type Metric func(a, b Point) float64
+
+// EuclideanDistance is the straight-line distance, the default PointCloud metric.
+func EuclideanDistance(a, b Point) float64 {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+// ManhattanDistance is the sum of the absolute axis differences.
+func ManhattanDistance(a, b Point) float64 {
+	return math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y)
+}
+
+// WeightedEuclidean returns a Euclidean metric with the X and Y differences scaled by wx and wy,
+// for axes measured at different scales.
+func WeightedEuclidean(wx, wy float64) Metric {
+	return func(a, b Point) float64 {
+		dx := wx * (a.X - b.X)
+		dy := wy * (a.Y - b.Y)
+		return math.Sqrt(dx*dx + dy*dy)
+	}
+}
 
 // PointCloud stores points for local refinement.
 type PointCloud struct {
-	points []Point
-	mu     sync.Mutex
+	points   []stampedPoint
+	capacity int          // maximum number of points kept, 0 for unbounded
+	next     int          // once full, the index of the oldest point, overwritten next
+	metric   Metric       // distance used by searches
+	mu       sync.RWMutex // searches share a read lock; only mutations take the write lock
 }
 
-// NewPointCloud initializes a new PointCloud.
+// NewPointCloud initializes a new PointCloud using EuclideanDistance.
 func NewPointCloud() *PointCloud {
 	return &PointCloud{
-		points: make([]Point, 0),
+		points: make([]stampedPoint, 0),
+		metric: EuclideanDistance,
+	}
+}
+
+// SetMetric sets the distance used by RadiusSearch, Density, and WeightedMean.
+// A nil metric restores EuclideanDistance.
+func (pc *PointCloud) SetMetric(metric Metric) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if metric == nil {
+		metric = EuclideanDistance
 	}
+	pc.metric = metric
 }
 
-// AddPoint adds a new point to the point cloud.
+// AddPoint adds a new point to the point cloud, stamped with the current time.
 func (pc *PointCloud) AddPoint(x, y float64) {
+	pc.AddPointAt(x, y, time.Now())
+}
+
+// AddPointAt adds a new point to the point cloud with the given insertion time.
+// If the cloud is at capacity, the oldest point is replaced.
+func (pc *PointCloud) AddPointAt(x, y float64, added time.Time) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
-	pc.points = append(pc.points, Point{X: x, Y: y})
+	pt := stampedPoint{Point: Point{X: x, Y: y}, added: added}
+	if pc.capacity > 0 && len(pc.points) >= pc.capacity {
+		pc.points[pc.next] = pt
+		pc.next = (pc.next + 1) % pc.capacity
+		return
+	}
+	pc.points = append(pc.points, pt)
 }
 
-// GetPoints returns a copy of the points in the point cloud.
-func (pc *PointCloud) GetPoints() []Point {
+// SetCapacity bounds the cloud to the n most recently added points, discarding the oldest
+// as new points arrive. A capacity <= 0 removes the bound.
+func (pc *PointCloud) SetCapacity(n int) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
-	pointsCopy := make([]Point, len(pc.points))
-	copy(pointsCopy, pc.points)
+	if n < 0 {
+		n = 0
+	}
+	points := pc.ordered()
+	if n > 0 && len(points) > n {
+		points = points[len(points)-n:]
+	}
+	pc.points = append(make([]stampedPoint, 0, len(points)), points...)
+	pc.capacity = n
+	pc.next = 0
+}
+
+// ordered returns the points from oldest to newest. The caller must hold mu, for reading at least.
+func (pc *PointCloud) ordered() []stampedPoint {
+	if pc.next == 0 {
+		return pc.points
+	}
+	return append(append([]stampedPoint(nil), pc.points[pc.next:]...), pc.points[:pc.next]...)
+}
+
+// GetPoints returns a copy of the points in the point cloud, oldest first.
+func (pc *PointCloud) GetPoints() []Point {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	points := pc.ordered()
+	pointsCopy := make([]Point, len(points))
+	for i, pt := range points {
+		pointsCopy[i] = pt.Point
+	}
 	return pointsCopy
 }
 
-// RadiusSearch returns all points within radius of (x, y) using a linear scan.
+// Len returns the number of points in the point cloud.
+func (pc *PointCloud) Len() int {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return len(pc.points)
+}
+
+// RadiusSearch returns all points within radius of (x, y) under the cloud metric using a linear scan.
 func (pc *PointCloud) RadiusSearch(x, y, radius float64) []Point {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
 	var result []Point
-	r2 := radius * radius
+	query := Point{X: x, Y: y}
 	for _, pt := range pc.points {
-		dx := pt.X - x
-		dy := pt.Y - y
-		if dx*dx+dy*dy <= r2 {
-			result = append(result, pt)
+		if pc.metric(pt.Point, query) <= radius {
+			result = append(result, pt.Point)
+		}
+	}
+	return result
+}
+
+// Neighbour is a point found by a search, with its distance from the query.
+type Neighbour struct {
+	Point Point
+	Dist  float64 // distance under the cloud metric, Euclidean unless set by SetMetric
+}
+
+// RadiusSearchWithDist is RadiusSearch returning each point with its distance from (x, y),
+// nearest first. Points at equal distance are returned oldest first.
+func (pc *PointCloud) RadiusSearchWithDist(x, y, radius float64) []Neighbour {
+	pc.mu.RLock()
+	var result []Neighbour
+	query := Point{X: x, Y: y}
+	for _, pt := range pc.ordered() {
+		if d := pc.metric(pt.Point, query); d <= radius {
+			result = append(result, Neighbour{Point: pt.Point, Dist: d})
 		}
 	}
+	pc.mu.RUnlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Dist < result[j].Dist
+	})
 	return result
 }
 
+// KNN returns the k points nearest to (x, y) under the cloud metric, nearest first, using a
+// linear scan. Points at equal distance are returned oldest first.
+func (pc *PointCloud) KNN(x, y float64, k int) []Point {
+	if k <= 0 {
+		return nil
+	}
+	pc.mu.RLock()
+	points := pc.ordered()
+	query := Point{X: x, Y: y}
+	candidates := make([]Point, len(points))
+	distances := make([]float64, len(points))
+	for i, pt := range points {
+		candidates[i] = pt.Point
+		distances[i] = pc.metric(pt.Point, query)
+	}
+	pc.mu.RUnlock()
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distances[order[i]] < distances[order[j]]
+	})
+	if k > len(order) {
+		k = len(order)
+	}
+	nearest := make([]Point, k)
+	for i := range nearest {
+		nearest[i] = candidates[order[i]]
+	}
+	return nearest
+}
+
+// Visit calls fn with each point and its insertion time, oldest first, until fn returns false,
+// so callers can run their own queries without copying the cloud. The cloud is read locked for
+// the traversal, so fn must not modify it.
+func (pc *PointCloud) Visit(fn func(p Point, added time.Time) bool) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	points := pc.ordered()
+	for _, pt := range points {
+		if !fn(pt.Point, pt.added) {
+			return
+		}
+	}
+}
+
+// Density returns the number of points within radius of (x, y) under the cloud metric.
+func (pc *PointCloud) Density(x, y, radius float64) int {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	count := 0
+	query := Point{X: x, Y: y}
+	for _, pt := range pc.points {
+		if pc.metric(pt.Point, query) <= radius {
+			count++
+		}
+	}
+	return count
+}
+
+// GridDensity bins the points into square cells of side cellSize and returns the count per
+// non-empty cell. Cell {i, j} covers [i*cellSize, (i+1)*cellSize) in X and likewise j in Y.
+// It returns nil if cellSize is not positive.
+func (pc *PointCloud) GridDensity(cellSize float64) map[[2]int]int {
+	if cellSize <= 0 {
+		return nil
+	}
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	grid := make(map[[2]int]int)
+	for _, pt := range pc.points {
+		cell := [2]int{int(math.Floor(pt.X / cellSize)), int(math.Floor(pt.Y / cellSize))}
+		grid[cell]++
+	}
+	return grid
+}
+
+// WeightedMean returns the mean of the points within radius of (x, y) under the cloud metric,
+// weighting each by a Gaussian in its distance from (x, y) with standard deviation distanceWidth, and by an
+// exponential decay in its age relative to now with time constant ageWidth. A width <= 0
+// disables that kernel. ok is false if there are no points within radius.
+func (pc *PointCloud) WeightedMean(x, y, radius float64, now time.Time, distanceWidth float64, ageWidth time.Duration) (Point, bool) {
+	buf := getNeighbours()
+	neighbours, _ := pc.kernelNeighbours(*buf, x, y, radius, now, distanceWidth, ageWidth)
+	defer putNeighbours(buf, neighbours)
+	return weightedMean(neighbours, nil)
+}
+
+// Huber refinement iteration limits: the maximum number of reweighting passes, and the
+// movement of the estimate below which it has converged.
+const (
+	huberMaxIterations = 20
+	huberTolerance     = 1e-9
+)
+
+// HuberMean is WeightedMean made robust to stray neighbours by iteratively reweighted least
+// squares with the Huber loss. Starting from WeightedMean, each pass additionally weights every
+// neighbour by min(1, delta/r), where r is its distance from the current estimate under the
+// cloud metric, so neighbours further than delta pull linearly rather than quadratically.
+// The neighbours are those within radius of (x, y). A delta <= 0 reduces to WeightedMean.
+func (pc *PointCloud) HuberMean(x, y, radius float64, now time.Time, distanceWidth float64, ageWidth time.Duration, delta float64) (Point, bool) {
+	buf := getNeighbours()
+	neighbours, metric := pc.kernelNeighbours(*buf, x, y, radius, now, distanceWidth, ageWidth)
+	defer putNeighbours(buf, neighbours)
+	estimate, ok := weightedMean(neighbours, nil)
+	if !ok || delta <= 0 {
+		return estimate, ok
+	}
+	huber := make([]float64, len(neighbours))
+	for iter := 0; iter < huberMaxIterations; iter++ {
+		for i, n := range neighbours {
+			huber[i] = 1
+			if r := metric(n.Point, estimate); r > delta {
+				huber[i] = delta / r
+			}
+		}
+		next, _ := weightedMean(neighbours, huber)
+		moved := EuclideanDistance(next, estimate)
+		estimate = next
+		if moved < huberTolerance {
+			break
+		}
+	}
+	return estimate, true
+}
+
+// GeometricMedian is WeightedMean with the weighted geometric median of the neighbours in
+// place of their weighted mean (see WeightedGeometricMedian), so an asymmetric spread of
+// neighbours pulls the estimate less. ok is false if there are no points within radius.
+func (pc *PointCloud) GeometricMedian(x, y, radius float64, now time.Time, distanceWidth float64, ageWidth time.Duration) (Point, bool) {
+	buf := getNeighbours()
+	neighbours, _ := pc.kernelNeighbours(*buf, x, y, radius, now, distanceWidth, ageWidth)
+	defer putNeighbours(buf, neighbours)
+	if len(neighbours) == 0 {
+		return Point{}, false
+	}
+	return weightedGeometricMedian(neighbours), true
+}
+
+// WeightedGeometricMedian returns the point minimising the weighted sum of Euclidean distances
+// to points, the L1 counterpart of the weighted mean, which a few distant points cannot drag
+// far. It is computed by Weiszfeld iteration starting from the weighted mean, stopping early if
+// an iterate lands on one of the points. nil weights weigh every point equally; with no points,
+// or no positive weight, it returns the zero Point.
+func WeightedGeometricMedian(points []Point, weights []float64) Point {
+	neighbours := make([]weightedPoint, len(points))
+	for i, p := range points {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		neighbours[i] = weightedPoint{Point: p, w: w}
+	}
+	return weightedGeometricMedian(neighbours)
+}
+
+// weightedGeometricMedian is WeightedGeometricMedian of the weighted points.
+func weightedGeometricMedian(neighbours []weightedPoint) Point {
+	median, ok := weightedMean(neighbours, nil)
+	if !ok {
+		return Point{}
+	}
+
+	for iter := 0; iter < medianMaxIterations; iter++ {
+		var next Point
+		var total float64
+		for _, n := range neighbours {
+			if n.w <= 0 {
+				continue
+			}
+			d := EuclideanDistance(median, n.Point)
+			if d < epsilon {
+				return n.Point
+			}
+			w := n.w / d
+			next.X += w * n.X
+			next.Y += w * n.Y
+			total += w
+		}
+		next.X /= total
+		next.Y /= total
+		step := EuclideanDistance(next, median)
+		median = next
+		if step < medianTolerance*math.Max(1, magnitude(median.X, median.Y)) {
+			break
+		}
+	}
+	return median
+}
+
+// weightedPoint is a cloud point with its kernel weight.
+type weightedPoint struct {
+	Point
+	w float64
+}
+
+// neighbourPool recycles the neighbour slices of kernel searches, which would otherwise allocate
+// up to the size of the cloud on every refinement.
+var neighbourPool = sync.Pool{New: func() interface{} { return new([]weightedPoint) }}
+
+// getNeighbours takes an empty neighbour slice from neighbourPool.
+func getNeighbours() *[]weightedPoint {
+	return neighbourPool.Get().(*[]weightedPoint)
+}
+
+// putNeighbours returns buf to neighbourPool, keeping the storage of neighbours, which was
+// grown from it.
+func putNeighbours(buf *[]weightedPoint, neighbours []weightedPoint) {
+	*buf = neighbours[:0]
+	neighbourPool.Put(buf)
+}
+
+// kernelNeighbours appends to dst the points within radius of (x, y) under the cloud metric,
+// weighted by the distance and age kernels of WeightedMean, and returns them with the metric.
+func (pc *PointCloud) kernelNeighbours(dst []weightedPoint, x, y, radius float64, now time.Time, distanceWidth float64, ageWidth time.Duration) ([]weightedPoint, Metric) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	neighbours := dst
+	query := Point{X: x, Y: y}
+	for _, pt := range pc.points {
+		d := pc.metric(pt.Point, query)
+		if d > radius {
+			continue
+		}
+		w := 1.0
+		if distanceWidth > 0 {
+			w *= math.Exp(-d * d / (2 * distanceWidth * distanceWidth))
+		}
+		if ageWidth > 0 {
+			if age := now.Sub(pt.added); age > 0 {
+				w *= math.Exp(-float64(age) / float64(ageWidth))
+			}
+		}
+		neighbours = append(neighbours, weightedPoint{Point: pt.Point, w: w})
+	}
+	return neighbours, pc.metric
+}
+
+// weightedMean returns the mean of points weighted by their kernel weights, each multiplied
+// by the matching extra weight if extra is non-nil. ok is false if the weights sum to zero.
+func weightedMean(points []weightedPoint, extra []float64) (Point, bool) {
+	var sumX, sumY, sumW float64
+	for i, pt := range points {
+		w := pt.w
+		if extra != nil {
+			w *= extra[i]
+		}
+		sumX += w * pt.X
+		sumY += w * pt.Y
+		sumW += w
+	}
+	if sumW == 0 {
+		return Point{}, false
+	}
+	return Point{X: sumX / sumW, Y: sumY / sumW}, true
+}
+
 // Clear clears the point cloud.
 func (pc *PointCloud) Clear() {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
-	pc.points = make([]Point, 0)
+	pc.points = make([]stampedPoint, 0)
+	pc.next = 0
 }
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// stampedPoint3D is a 3D cloud point with its insertion time.
+type stampedPoint3D struct {
+	Point3D
+	added time.Time
+}
+
+// PointCloud3D stores 3D points for local refinement. It keeps points like PointCloud, in
+// insertion order with an optional capacity, and searches them by linear scan under the
+// Euclidean distance.
+type PointCloud3D struct {
+	points   []stampedPoint3D
+	capacity int          // maximum number of points kept, 0 for unbounded
+	next     int          // once full, the index of the oldest point, overwritten next
+	mu       sync.RWMutex // searches share a read lock; only mutations take the write lock
+}
+
+// NewPointCloud3D initializes a new PointCloud3D.
+func NewPointCloud3D() *PointCloud3D {
+	return &PointCloud3D{
+		points: make([]stampedPoint3D, 0),
+	}
+}
+
+// AddPoint adds a new point to the point cloud, stamped with the current time.
+func (pc *PointCloud3D) AddPoint(x, y, z float64) {
+	pc.AddPointAt(x, y, z, time.Now())
+}
+
+// AddPointAt adds a new point to the point cloud with the given insertion time.
+// If the cloud is at capacity, the oldest point is replaced.
+func (pc *PointCloud3D) AddPointAt(x, y, z float64, added time.Time) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pt := stampedPoint3D{Point3D: Point3D{X: x, Y: y, Z: z}, added: added}
+	if pc.capacity > 0 && len(pc.points) >= pc.capacity {
+		pc.points[pc.next] = pt
+		pc.next = (pc.next + 1) % pc.capacity
+		return
+	}
+	pc.points = append(pc.points, pt)
+}
+
+// SetCapacity bounds the cloud to the n most recently added points, discarding the oldest
+// as new points arrive. A capacity <= 0 removes the bound.
+func (pc *PointCloud3D) SetCapacity(n int) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	points := pc.ordered()
+	if n > 0 && len(points) > n {
+		points = points[len(points)-n:]
+	}
+	pc.points = append(make([]stampedPoint3D, 0, len(points)), points...)
+	pc.capacity = n
+	pc.next = 0
+}
+
+// ordered returns the points from oldest to newest. The caller must hold mu, for reading at least.
+func (pc *PointCloud3D) ordered() []stampedPoint3D {
+	if pc.next == 0 {
+		return pc.points
+	}
+	return append(append([]stampedPoint3D(nil), pc.points[pc.next:]...), pc.points[:pc.next]...)
+}
+
+// GetPoints returns a copy of the points in the point cloud, oldest first.
+func (pc *PointCloud3D) GetPoints() []Point3D {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	points := pc.ordered()
+	pointsCopy := make([]Point3D, len(points))
+	for i, pt := range points {
+		pointsCopy[i] = pt.Point3D
+	}
+	return pointsCopy
+}
+
+// Len returns the number of points in the point cloud.
+func (pc *PointCloud3D) Len() int {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return len(pc.points)
+}
+
+// RadiusSearch returns all points within radius of (x, y, z) using a linear scan.
+func (pc *PointCloud3D) RadiusSearch(x, y, z, radius float64) []Point3D {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	var result []Point3D
+	query := Point3D{X: x, Y: y, Z: z}
+	for _, pt := range pc.points {
+		if pt.Distance(query) <= radius {
+			result = append(result, pt.Point3D)
+		}
+	}
+	return result
+}
+
+// KNN returns the k points nearest to (x, y, z), nearest first, or all points if there are
+// fewer than k. Points at equal distance are returned oldest first.
+func (pc *PointCloud3D) KNN(x, y, z float64, k int) []Point3D {
+	if k <= 0 {
+		return nil
+	}
+	pc.mu.RLock()
+	points := pc.ordered()
+	query := Point3D{X: x, Y: y, Z: z}
+	candidates := make([]Point3D, len(points))
+	distances := make([]float64, len(points))
+	for i, pt := range points {
+		candidates[i] = pt.Point3D
+		distances[i] = pt.Distance(query)
+	}
+	pc.mu.RUnlock()
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distances[order[i]] < distances[order[j]]
+	})
+	if k > len(order) {
+		k = len(order)
+	}
+	nearest := make([]Point3D, k)
+	for i := range nearest {
+		nearest[i] = candidates[order[i]]
+	}
+	return nearest
+}
+
+// Clear clears the point cloud.
+func (pc *PointCloud3D) Clear() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.points = make([]stampedPoint3D, 0)
+	pc.next = 0
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPointCloud3D_AddAndGet(t *testing.T) {
+	pc := NewPointCloud3D()
+	points := []Point3D{{1, 2, 3}, {3, 4, 5}, {-1, 0, 1}}
+	for _, p := range points {
+		pc.AddPoint(p.X, p.Y, p.Z)
+	}
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, points) {
+		t.Errorf("Expected points %v, got %v", points, got)
+	}
+
+	pc.AddPoint(5, 5, 5)
+	want := append(points, Point3D{5, 5, 5})
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected points %v after adding more, got %v", want, got)
+	}
+}
+
+func TestPointCloud3D_RadiusSearch(t *testing.T) {
+	pc := NewPointCloud3D()
+	points := []Point3D{
+		{0, 0, 0},
+		{1, 0, 0},
+		{0, 1, 1},    // Inside radius 1.5 from the origin
+		{1, 1, 1},    // Outside, at sqrt(3)
+		{0, 0, 2},    // Outside
+		{-1, -1, 0},  // Inside
+		{0.5, 0, -1}, // Inside
+	}
+	for _, p := range points {
+		pc.AddPoint(p.X, p.Y, p.Z)
+	}
+
+	want := []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 1}, {-1, -1, 0}, {0.5, 0, -1}}
+	if got := pc.RadiusSearch(0, 0, 0, 1.5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if found := pc.RadiusSearch(10, 10, 10, 1); len(found) != 0 {
+		t.Errorf("Expected empty result for search far away, got %v", found)
+	}
+}
+
+func TestPointCloud3D_KNN(t *testing.T) {
+	pc := NewPointCloud3D()
+	for _, p := range []Point3D{{3, 0, 0}, {0, 0, 1}, {0, 2, 0}, {0, 0, -1}, {10, 10, 10}} {
+		pc.AddPoint(p.X, p.Y, p.Z)
+	}
+
+	// Ties are broken by insertion order.
+	want := []Point3D{{0, 0, 1}, {0, 0, -1}, {0, 2, 0}}
+	if got := pc.KNN(0, 0, 0, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := pc.KNN(0, 0, 0, 10); len(got) != 5 {
+		t.Errorf("Expected all 5 points when k exceeds the cloud, got %d", len(got))
+	}
+	if got := pc.KNN(0, 0, 0, 0); got != nil {
+		t.Errorf("Expected no points for k 0, got %v", got)
+	}
+}
+
+func TestPointCloud3D_Clear(t *testing.T) {
+	pc := NewPointCloud3D()
+	pc.AddPoint(1, 1, 1)
+	pc.AddPoint(2, 2, 2)
+	pc.Clear()
+	if n := pc.Len(); n != 0 {
+		t.Errorf("Expected PointCloud3D to be empty after Clear(), got %d points", n)
+	}
+	pc.AddPoint(3, 3, 3)
+	if n := pc.Len(); n != 1 {
+		t.Errorf("Expected 1 point after adding post-Clear(), got %d", n)
+	}
+}
+
+func TestPointCloud3D_CapacityKeepsMostRecent(t *testing.T) {
+	pc := NewPointCloud3D()
+	pc.SetCapacity(3)
+	for i := 0; i < 5; i++ {
+		pc.AddPoint(float64(i), 0, 0)
+	}
+	want := []Point3D{{2, 0, 0}, {3, 0, 0}, {4, 0, 0}}
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if found := pc.KNN(0, 0, 0, 1); len(found) != 1 || found[0] != want[0] {
+		t.Errorf("Expected evicted points to be unsearchable, got %v", found)
+	}
+
+	pc.SetCapacity(2)
+	want = []Point3D{{3, 0, 0}, {4, 0, 0}}
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v after shrinking, got %v", want, got)
+	}
+}
+
+// TestPointCloud3D_ConcurrentReadsAndWrites is meant to be run with -race.
+func TestPointCloud3D_ConcurrentReadsAndWrites(t *testing.T) {
+	pc := NewPointCloud3D()
+	pc.SetCapacity(100)
+	const writes = 2000
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			pc.AddPoint(float64(i), 0, 0)
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes/10; i++ {
+				pc.RadiusSearch(float64(i), 0, 0, 50)
+				pc.KNN(float64(i), 0, 0, 5)
+				points := pc.GetPoints()
+				for j := 1; j < len(points); j++ {
+					if points[j].X != points[j-1].X+1 {
+						t.Errorf("Expected a consistent snapshot, got %v after %v", points[j], points[j-1])
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := pc.Len(); n != 100 {
+		t.Errorf("Expected 100 points after concurrent writes, got %d", n)
+	}
+}
@@ -1,8 +1,12 @@
 package internal
 
 import (
+	"math"
+	"reflect"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Helper to compare slices of Points (ignoring order)
@@ -125,3 +129,366 @@ func TestPointCloud_Clear(t *testing.T) {
 		t.Errorf("Expected 1 point after adding post-Clear(), got %d", len(pc.GetPoints()))
 	}
 }
+
+func TestPointCloud_WeightedMeanFavorsRecentPoints(t *testing.T) {
+	pc := NewPointCloud()
+	now := time.Now()
+	pc.AddPointAt(-0.5, 0, now.Add(-2*time.Second)) // stale
+	pc.AddPointAt(0.5, 0, now)                      // recent
+
+	mean, ok := pc.WeightedMean(0, 0, 1, now, 0, 500*time.Millisecond)
+	if !ok {
+		t.Fatal("Expected neighbours within radius")
+	}
+	if mean.X <= 0.4 {
+		t.Errorf("Expected mean pulled toward the recent point at x=0.5, got %v", mean)
+	}
+
+	// Without kernels the mean is unweighted.
+	mean, _ = pc.WeightedMean(0, 0, 1, now, 0, 0)
+	if !pointsClose(mean, Point{0, 0}, 1e-9) {
+		t.Errorf("Expected unweighted mean (0, 0), got %v", mean)
+	}
+}
+
+func TestPointCloud_WeightedMeanFavorsCloserCluster(t *testing.T) {
+	pc := NewPointCloud()
+	now := time.Now()
+	// A dense cluster near the query point and a sparse one near the edge of the radius.
+	for _, p := range []Point{{0.1, 0}, {0.12, 0.02}, {0.08, -0.02}} {
+		pc.AddPointAt(p.X, p.Y, now)
+	}
+	pc.AddPointAt(-0.9, 0, now)
+
+	mean, ok := pc.WeightedMean(0, 0, 1, now, 0.2, 0)
+	if !ok {
+		t.Fatal("Expected neighbours within radius")
+	}
+	if !pointsClose(mean, Point{0.1, 0}, 0.01) {
+		t.Errorf("Expected mean near the dense cluster at (0.1, 0), got %v", mean)
+	}
+
+	if _, ok := pc.WeightedMean(10, 10, 1, now, 0.2, 0); ok {
+		t.Error("Expected no neighbours far from the cloud")
+	}
+}
+
+func TestPointCloud_HuberMeanResistsOutliers(t *testing.T) {
+	pc := NewPointCloud()
+	now := time.Now()
+	// A tight cluster around (1, 1) and a few stray neighbours on one side.
+	for _, p := range []Point{{1, 1}, {1.02, 1}, {0.98, 1}, {1, 1.02}, {1, 0.98}, {1.01, 1.01}, {0.99, 0.99}} {
+		pc.AddPointAt(p.X, p.Y, now)
+	}
+	for _, p := range []Point{{2.8, 1}, {2.9, 1.1}, {2.7, 0.9}} {
+		pc.AddPointAt(p.X, p.Y, now)
+	}
+	center := Point{1, 1}
+
+	plain, ok := pc.WeightedMean(1, 1, 2, now, 0, 0)
+	if !ok {
+		t.Fatal("Expected neighbours within radius")
+	}
+	robust, ok := pc.HuberMean(1, 1, 2, now, 0, 0, 0.05)
+	if !ok {
+		t.Fatal("Expected neighbours within radius")
+	}
+	if !pointsClose(robust, center, 0.05) {
+		t.Errorf("Expected Huber mean near the cluster center %v, got %v", center, robust)
+	}
+	if EuclideanDistance(robust, center) >= EuclideanDistance(plain, center)/4 {
+		t.Errorf("Expected Huber mean %v much closer to %v than the plain mean %v", robust, center, plain)
+	}
+
+	// A non-positive delta is the plain weighted mean.
+	if same, _ := pc.HuberMean(1, 1, 2, now, 0, 0, 0); same != plain {
+		t.Errorf("Expected delta 0 to give the plain mean %v, got %v", plain, same)
+	}
+}
+
+func TestPointCloud_Density(t *testing.T) {
+	pc := NewPointCloud()
+	// A 5x5 lattice with unit spacing centered on the origin.
+	for i := -2; i <= 2; i++ {
+		for j := -2; j <= 2; j++ {
+			pc.AddPoint(float64(i), float64(j))
+		}
+	}
+
+	tests := []struct {
+		x, y, radius float64
+		expect       int
+	}{
+		{0, 0, 0.5, 1},
+		{0, 0, 1, 5},   // center and 4 neighbours
+		{0, 0, 1.5, 9}, // plus the diagonals
+		{0, 0, 10, 25}, // everything
+		{10, 10, 1, 0}, // far away
+		{2, 2, 1, 3},   // corner
+	}
+	for _, tt := range tests {
+		if got := pc.Density(tt.x, tt.y, tt.radius); got != tt.expect {
+			t.Errorf("Density(%f, %f, %f): expected %d, got %d", tt.x, tt.y, tt.radius, tt.expect, got)
+		}
+	}
+}
+
+func TestPointCloud_GridDensity(t *testing.T) {
+	pc := NewPointCloud()
+	points := []Point{
+		{0.1, 0.1}, {0.9, 0.5}, {0.5, 0.99}, // cell {0, 0}
+		{1.5, 0.5},                 // cell {1, 0}
+		{-0.5, -0.5}, {-0.1, -0.9}, // cell {-1, -1}
+	}
+	for _, p := range points {
+		pc.AddPoint(p.X, p.Y)
+	}
+
+	grid := pc.GridDensity(1.0)
+	expected := map[[2]int]int{{0, 0}: 3, {1, 0}: 1, {-1, -1}: 2}
+	if !reflect.DeepEqual(grid, expected) {
+		t.Errorf("Expected grid %v, got %v", expected, grid)
+	}
+
+	if grid := pc.GridDensity(0); grid != nil {
+		t.Errorf("Expected nil grid for zero cell size, got %v", grid)
+	}
+}
+
+func TestPointCloud_RadiusSearchWithDist(t *testing.T) {
+	pc := NewPointCloud()
+	for _, p := range []Point{{3, 4}, {1, 0}, {0, -1}, {5, 5}, {0.5, 0.5}} {
+		pc.AddPoint(p.X, p.Y)
+	}
+
+	// {1, 0} and {0, -1} tie and keep insertion order; {5, 5} is outside the radius.
+	expected := []Neighbour{
+		{Point: Point{0.5, 0.5}, Dist: math.Sqrt(0.5)},
+		{Point: Point{1, 0}, Dist: 1},
+		{Point: Point{0, -1}, Dist: 1},
+		{Point: Point{3, 4}, Dist: 5},
+	}
+	found := pc.RadiusSearchWithDist(0, 0, 5)
+	if len(found) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, found)
+	}
+	for i, want := range expected {
+		if found[i].Point != want.Point || !floatsClose(found[i].Dist, want.Dist, 1e-12) {
+			t.Errorf("Neighbour %d: Expected %v, got %v", i, want, found[i])
+		}
+	}
+	if plain := pc.RadiusSearch(0, 0, 5); len(plain) != len(found) {
+		t.Errorf("Expected as many points as RadiusSearch (%d), got %d", len(plain), len(found))
+	}
+}
+
+func TestPointCloud_ManhattanRadiusSearch(t *testing.T) {
+	pc := NewPointCloud()
+	pc.SetMetric(ManhattanDistance)
+	for x := -3; x <= 3; x++ {
+		for y := -3; y <= 3; y++ {
+			pc.AddPoint(float64(x), float64(y))
+		}
+	}
+
+	// The Manhattan ball of radius 2 is a diamond: |x|+|y| <= 2.
+	var expected []Point
+	for x := -2; x <= 2; x++ {
+		for y := -2; y <= 2; y++ {
+			if absInt(x)+absInt(y) <= 2 {
+				expected = append(expected, Point{X: float64(x), Y: float64(y)})
+			}
+		}
+	}
+
+	found := pc.RadiusSearch(0, 0, 2)
+	if !pointSlicesEqual(found, expected, 1e-9) {
+		t.Errorf("Expected diamond %v, got %v", expected, found)
+	}
+	if len(found) != 13 {
+		t.Errorf("Expected 13 points, got %d", len(found))
+	}
+	if n := pc.Density(0, 0, 2); n != len(found) {
+		t.Errorf("Expected Density %d to match RadiusSearch, got %d", len(found), n)
+	}
+}
+
+func TestPointCloud_WeightedEuclideanRadiusSearch(t *testing.T) {
+	pc := NewPointCloud()
+	pc.SetMetric(WeightedEuclidean(1, 10))
+	pc.AddPoint(0.9, 0)
+	pc.AddPoint(0, 0.09)
+	pc.AddPoint(0, 0.2)
+
+	expected := []Point{{0.9, 0}, {0, 0.09}}
+	if found := pc.RadiusSearch(0, 0, 1); !pointSlicesEqual(found, expected, 1e-9) {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+
+	pc.SetMetric(nil)
+	if n := pc.Density(0, 0, 1); n != 3 {
+		t.Errorf("Expected all 3 points under the default metric, got %d", n)
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestPointCloud_CapacityKeepsMostRecent(t *testing.T) {
+	pc := NewPointCloud()
+	pc.SetCapacity(3)
+	for i := 0; i < 5; i++ {
+		pc.AddPoint(float64(i), 0)
+	}
+	want := []Point{{2, 0}, {3, 0}, {4, 0}}
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if found := pc.RadiusSearch(0, 0, 1.5); len(found) != 0 {
+		t.Errorf("Expected evicted points to be unsearchable, got %v", found)
+	}
+
+	pc.SetCapacity(2)
+	want = []Point{{3, 0}, {4, 0}}
+	if got := pc.GetPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v after shrinking, got %v", want, got)
+	}
+}
+
+// TestPointCloud_ConcurrentReadsAndWrites is meant to be run with -race.
+func TestPointCloud_ConcurrentReadsAndWrites(t *testing.T) {
+	pc := NewPointCloud()
+	pc.SetCapacity(100)
+	const writes = 2000
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			pc.AddPoint(float64(i), 0)
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes/10; i++ {
+				pc.RadiusSearch(float64(i), 0, 50)
+				pc.Density(float64(i), 0, 50)
+				pc.WeightedMean(float64(i), 0, 50, time.Now(), 10, time.Second)
+				// A consistent snapshot is a run of consecutively added points, oldest first.
+				points := pc.GetPoints()
+				for j := 1; j < len(points); j++ {
+					if points[j].X != points[j-1].X+1 {
+						t.Errorf("Expected a consistent snapshot, got %v after %v", points[j], points[j-1])
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := pc.Len(); n != 100 {
+		t.Errorf("Expected 100 points after concurrent writes, got %d", n)
+	}
+}
+
+func BenchmarkPointCloudRadiusSearchParallel(b *testing.B) {
+	pc := NewPointCloud()
+	for i := 0; i < 10000; i++ {
+		pc.AddPoint(float64(i%100), float64(i/100))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pc.RadiusSearch(50, 50, 5)
+		}
+	})
+}
+
+func TestPointCloud_VisitMatchesKNN(t *testing.T) {
+	pc := NewPointCloud()
+	for i := 0; i < 50; i++ {
+		pc.AddPoint(float64(i%7)*0.3, float64(i%11)*0.2)
+	}
+	query := Point{X: 0.8, Y: 1.1}
+	const k = 5
+
+	// A custom KNN over the visited points: keep the k nearest seen so far.
+	var nearest []Point
+	pc.Visit(func(p Point, _ time.Time) bool {
+		nearest = append(nearest, p)
+		sort.SliceStable(nearest, func(i, j int) bool {
+			return EuclideanDistance(nearest[i], query) < EuclideanDistance(nearest[j], query)
+		})
+		if len(nearest) > k {
+			nearest = nearest[:k]
+		}
+		return true
+	})
+
+	want := pc.KNN(query.X, query.Y, k)
+	if !reflect.DeepEqual(nearest, want) {
+		t.Errorf("Expected custom KNN %v to match KNN %v", nearest, want)
+	}
+
+	visited := 0
+	pc.Visit(func(Point, time.Time) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("Expected Visit to stop after 3 points, got %d", visited)
+	}
+	if got := pc.KNN(0, 0, 100); len(got) != pc.Len() {
+		t.Errorf("Expected KNN to return all %d points, got %d", pc.Len(), len(got))
+	}
+}
+
+func TestWeightedGeometricMedianSkewedCluster(t *testing.T) {
+	// A tight cluster around the origin with a long tail to +X.
+	points := []Point{
+		{X: 0, Y: 0}, {X: 0.01, Y: 0}, {X: -0.01, Y: 0}, {X: 0, Y: 0.01}, {X: 0, Y: -0.01},
+		{X: 0.01, Y: 0.01}, {X: -0.01, Y: -0.01},
+		{X: 0.5, Y: 0}, {X: 0.8, Y: 0}, {X: 1.0, Y: 0},
+	}
+	center := Point{}
+
+	var mean Point
+	for _, p := range points {
+		mean.X += p.X / float64(len(points))
+		mean.Y += p.Y / float64(len(points))
+	}
+	median := WeightedGeometricMedian(points, nil)
+	if dm, dg := EuclideanDistance(mean, center), EuclideanDistance(median, center); dg >= dm/10 {
+		t.Errorf("Expected the median %v far more central than the mean %v, got distances %f and %f", median, mean, dg, dm)
+	}
+
+	// Weights pull the median towards the heavier points.
+	weights := make([]float64, len(points))
+	for i := range weights {
+		weights[i] = 1
+	}
+	weights[9] = 100
+	if heavy := WeightedGeometricMedian(points, weights); !floatsClose(heavy.X, 1, 1e-6) {
+		t.Errorf("Expected the heavily weighted point (1, 0), got %v", heavy)
+	}
+	if got := WeightedGeometricMedian(nil, nil); got != (Point{}) {
+		t.Errorf("Expected the zero point for no points, got %v", got)
+	}
+
+	pc := NewPointCloud()
+	now := time.Now()
+	for _, p := range points {
+		pc.AddPointAt(p.X, p.Y, now)
+	}
+	if got, ok := pc.GeometricMedian(0, 0, 2, now, 0, 0); !ok || got != median {
+		t.Errorf("Expected cloud geometric median %v, got %v (ok=%v)", median, got, ok)
+	}
+}
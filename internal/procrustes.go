@@ -2,28 +2,77 @@ package internal
 
 import (
 	"fmt"
+	"math"
 
 	"gonum.org/v1/gonum/mat"
 )
 
+// ProcrustesResult is the similarity transform found by ProcrustesFit.
+type ProcrustesResult struct {
+	Aligned  []Point       // source points after the transform
+	Centroid Point         // target centroid, the translation applied to the centered source
+	Scale    float64       // scale factor
+	Rotation [2][2]float64 // rotation applied to the centered source points
+
+	// Degenerate is set when the source points are coincident (or there is only one),
+	// so rotation and scale are undefined. The transform is then translation-only,
+	// with identity rotation and unit scale.
+	Degenerate bool
+
+	// Collinear is set when IsDegenerate reports that the source or target points lie on a line.
+	// The fit is still computed but is ill-conditioned: small perturbations of the points can
+	// change the rotation substantially, so it should not be trusted.
+	Collinear bool
+}
+
+// collinearConditionLimit is the largest ratio between the principal variances of a point set,
+// the condition number of its scatter matrix, at which the set is taken to span the plane.
+const collinearConditionLimit = 1e6
+
+// IsDegenerate reports whether points fail to span the plane: fewer than three points, all
+// coincident, or collinear, as judged by the condition number of their 2x2 scatter matrix.
+// Such sets leave a Procrustes rotation ill-conditioned.
+func IsDegenerate(points []Point) bool {
+	if len(points) < 3 {
+		return true
+	}
+	var sxx, syy, sxy float64
+	for _, p := range centerPoints(points, centroid(points)) {
+		sxx += p.X * p.X
+		syy += p.Y * p.Y
+		sxy += p.X * p.Y
+	}
+	// Eigenvalues of [[sxx, sxy], [sxy, syy]].
+	mean := (sxx + syy) / 2
+	spread := math.Hypot((sxx-syy)/2, sxy)
+	largest, smallest := mean+spread, mean-spread
+	if largest <= epsilon {
+		return true
+	}
+	return smallest*collinearConditionLimit < largest
+}
+
 // Procrustes aligns two sets of points using least squares optimization.
 // It returns the transformed source points, the target centroid, and the scale factor.
 func Procrustes(source, target []Point) ([]Point, Point, float64) {
+	r := ProcrustesFit(source, target)
+	return r.Aligned, r.Centroid, r.Scale
+}
+
+// ProcrustesFit aligns source to target like Procrustes, returning the full transform.
+func ProcrustesFit(source, target []Point) ProcrustesResult {
+	return ProcrustesFitOptions(source, target, false)
+}
+
+// ProcrustesFitOptions is ProcrustesFit with the scale optionally fixed at 1. A rigid-only fit
+// (fixScale true) suits bodies known to be rigid, where a free scale factor would absorb drift.
+// The optimal rotation does not depend on the scale, so only the scale differs.
+func ProcrustesFitOptions(source, target []Point, fixScale bool) ProcrustesResult {
 	if len(source) == 0 || len(target) == 0 || len(source) != len(target) {
 		// Handle cases with empty or mismatched input sizes
 		// Returning empty results or an error might be appropriate
 		fmt.Println("Procrustes: Warning - empty or mismatched input point sets.")
-		return []Point{}, Point{}, 0.0
-	}
-	if len(source) < 2 {
-		// Procrustes requires at least 2 points for meaningful alignment (rotation/scale)
-		// If only 1 point, can only translate. Return translated source point.
-		fmt.Println("Procrustes: Warning - only one point provided. Performing translation only.")
-		centroidSource := centroid(source)
-		centroidTarget := centroid(target)
-		translation := Point{X: centroidTarget.X - centroidSource.X, Y: centroidTarget.Y - centroidSource.Y}
-		aligned := []Point{{X: source[0].X + translation.X, Y: source[0].Y + translation.Y}}
-		return aligned, centroidTarget, 1.0 // Scale is undefined/irrelevant, return 1.0
+		return ProcrustesResult{Aligned: []Point{}}
 	}
 
 	// Calculate centroids of both point sets
@@ -34,11 +83,34 @@ func Procrustes(source, target []Point) ([]Point, Point, float64) {
 	centeredSource := centerPoints(source, centroidSource)
 	centeredTarget := centerPoints(target, centroidTarget)
 
+	var varSource float64
+	for _, p := range centeredSource {
+		varSource += p.X*p.X + p.Y*p.Y // sum(||centeredSource_i||^2)
+	}
+	if len(source) < 2 || varSource <= epsilon {
+		// With a single point or coincident points, rotation and scale are undefined and
+		// the SVD below would return an arbitrary rotation. Only translate.
+		fmt.Println("Procrustes: Warning - source points are coincident. Performing translation only.")
+		identity := [][]float64{{1, 0}, {0, 1}}
+		return ProcrustesResult{
+			Aligned:    applyTransformation(centeredSource, 1.0, identity, centroidTarget),
+			Centroid:   centroidTarget,
+			Scale:      1.0,
+			Rotation:   [2][2]float64{{1, 0}, {0, 1}},
+			Degenerate: true,
+		}
+	}
+
+	collinear := IsDegenerate(source) || IsDegenerate(target)
+	if collinear {
+		fmt.Println("Procrustes: Warning - points are collinear, the rotation is ill-conditioned.")
+	}
+
 	// Compute the covariance matrix H = X * Y^T
 	H := computeCovarianceMatrix(centeredSource, centeredTarget)
 	if H == nil { // Check if computeCovarianceMatrix returned nil (error case)
 		fmt.Println("Procrustes: Error computing covariance matrix.")
-		return []Point{}, Point{}, 0.0
+		return ProcrustesResult{Aligned: []Point{}}
 	}
 
 	// Singular Value Decomposition (SVD) of H
@@ -46,7 +118,7 @@ func Procrustes(source, target []Point) ([]Point, Point, float64) {
 	ok := svd.Factorize(H, mat.SVDThin)
 	if !ok {
 		fmt.Println("Procrustes: SVD factorization failed.")
-		return []Point{}, Point{}, 0.0 // Or handle error appropriately
+		return ProcrustesResult{Aligned: []Point{}} // Or handle error appropriately
 	}
 	var U, V mat.Dense
 	svd.UTo(&U)
@@ -91,21 +163,14 @@ func Procrustes(source, target []Point) ([]Point, Point, float64) {
 	for _, val := range S {
 		sumS += val
 	}
-	var varSource float64
-	for _, p := range centeredSource {
-		varSource += p.X*p.X + p.Y*p.Y // sum(||centeredSource_i||^2)
-	}
 
-	scale := 1.0 // Default scale
-	if varSource > epsilon { // Avoid division by zero or near-zero
-		// If reflection was detected and corrected in R, the scale should use the corrected singular values conceptually.
-		// However, the standard approach often uses sum(S) directly after ensuring det(R)=1.
-		// Let's stick to sum(S) / varSource after R correction.
-		scale = sumS / varSource
-	} else {
-		fmt.Println("Procrustes: Warning - Variance of source points is near zero. Scale calculation might be unstable.")
-		// If variance is zero, points are likely coincident. Scale is ambiguous.
-		// Keep scale = 1.0 or handle as an error/special case.
+	// varSource is non-zero here, coincident points were handled above.
+	// If reflection was detected and corrected in R, the scale should use the corrected singular values conceptually.
+	// However, the standard approach often uses sum(S) directly after ensuring det(R)=1.
+	// Let's stick to sum(S) / varSource after R correction.
+	scale := sumS / varSource
+	if fixScale {
+		scale = 1.0
 	}
 
 	// Convert R (gonum matrix) to [][]float64 for applyTransformation
@@ -113,7 +178,7 @@ func Procrustes(source, target []Point) ([]Point, Point, float64) {
 	rRows, rCols := R.Dims()
 	if rRows != 2 || rCols != 2 {
 		fmt.Println("Procrustes: Error - Rotation matrix is not 2x2.")
-		return []Point{}, Point{}, 0.0
+		return ProcrustesResult{Aligned: []Point{}}
 	}
 	rotationMatrix := [][]float64{
 		{R.At(0, 0), R.At(0, 1)},
@@ -124,7 +189,163 @@ func Procrustes(source, target []Point) ([]Point, Point, float64) {
 	// Transformation: p' = scale * R * p_centered + centroidTarget
 	aligned := applyTransformation(centeredSource, scale, rotationMatrix, centroidTarget)
 
-	return aligned, centroidTarget, scale
+	return ProcrustesResult{
+		Aligned:  aligned,
+		Centroid: centroidTarget,
+		Scale:    scale,
+		Rotation: [2][2]float64{
+			{rotationMatrix[0][0], rotationMatrix[0][1]},
+			{rotationMatrix[1][0], rotationMatrix[1][1]},
+		},
+		Collinear: collinear,
+	}
+}
+
+// PointWeight is the confidence in one correspondence of a weighted Procrustes fit, a symmetric
+// positive semi-definite 2x2 matrix such as the inverse covariance of the target point.
+type PointWeight [2][2]float64
+
+// AxisWeight returns a PointWeight trusting a correspondence wx along X and wy along Y.
+func AxisWeight(wx, wy float64) PointWeight {
+	return PointWeight{{wx, 0}, {0, wy}}
+}
+
+// IsotropicWeights turns scalar per-point weights into PointWeights that trust both axes equally.
+func IsotropicWeights(w []float64) []PointWeight {
+	weights := make([]PointWeight, len(w))
+	for i, wi := range w {
+		weights[i] = AxisWeight(wi, wi)
+	}
+	return weights
+}
+
+// ProcrustesFitWeighted aligns source to target like ProcrustesFit, but minimises the weighted
+// sum of r_i^T W_i r_i over the residuals r_i, so a point can be trusted more along one axis than
+// the other. weights[i] applies to the i-th correspondence; nil weighs every point equally.
+// Anisotropic weights have no SVD solution, but in 2D a similarity transform is linear in
+// (s cos θ, s sin θ) and the translation, so the fit is a 4x4 weighted least squares solve.
+// Like the SVD fit it never returns a reflection.
+func ProcrustesFitWeighted(source, target []Point, weights []PointWeight) ProcrustesResult {
+	return ProcrustesFitWeightedOptions(source, target, weights, false)
+}
+
+// ProcrustesFitWeightedOptions is ProcrustesFitWeighted with the scale optionally fixed at 1.
+// The rigid fit keeps the rotation of the free fit, which is exact for isotropic weights, and
+// re-solves the translation for it.
+func ProcrustesFitWeightedOptions(source, target []Point, weights []PointWeight, fixScale bool) ProcrustesResult {
+	if len(source) == 0 || len(source) != len(target) || (weights != nil && len(weights) != len(source)) {
+		fmt.Println("Procrustes: Warning - empty or mismatched input point sets or weights.")
+		return ProcrustesResult{Aligned: []Point{}}
+	}
+	if weights == nil {
+		weights = make([]PointWeight, len(source))
+		for i := range weights {
+			weights[i] = AxisWeight(1, 1)
+		}
+	}
+
+	// Fit target_i = s R (source_i - c) + t with c the source centroid. Writing a = s cos θ and
+	// b = s sin θ, the model is J_i u with u = (a, b, tx, ty).
+	centeredSource := centerPoints(source, centroid(source))
+	var normal [4][4]float64
+	var rhs [4]float64
+	for i, p := range centeredSource {
+		J := [2][4]float64{{p.X, -p.Y, 1, 0}, {p.Y, p.X, 0, 1}}
+		W := weights[i]
+		for r := 0; r < 4; r++ {
+			// Row r of J^T W.
+			jw := [2]float64{
+				J[0][r]*W[0][0] + J[1][r]*W[1][0],
+				J[0][r]*W[0][1] + J[1][r]*W[1][1],
+			}
+			for c := 0; c < 4; c++ {
+				normal[r][c] += jw[0]*J[0][c] + jw[1]*J[1][c]
+			}
+			rhs[r] += jw[0]*target[i].X + jw[1]*target[i].Y
+		}
+	}
+
+	var varSource float64
+	for _, p := range centeredSource {
+		varSource += p.X*p.X + p.Y*p.Y
+	}
+	if len(source) < 2 || varSource <= epsilon {
+		// Only the translation is determined: the weighted mean of the targets.
+		fmt.Println("Procrustes: Warning - source points are coincident. Performing translation only.")
+		var translation mat.VecDense
+		N := mat.NewDense(2, 2, []float64{normal[2][2], normal[2][3], normal[3][2], normal[3][3]})
+		if err := translation.SolveVec(N, mat.NewVecDense(2, rhs[2:])); err != nil {
+			fmt.Println("Procrustes: Error - weights do not constrain the translation.")
+			return ProcrustesResult{Aligned: []Point{}}
+		}
+		t := Point{X: translation.AtVec(0), Y: translation.AtVec(1)}
+		aligned := make([]Point, len(source))
+		for i := range aligned {
+			aligned[i] = t
+		}
+		return ProcrustesResult{
+			Aligned:    aligned,
+			Centroid:   t,
+			Scale:      1.0,
+			Rotation:   [2][2]float64{{1, 0}, {0, 1}},
+			Degenerate: true,
+		}
+	}
+
+	collinear := IsDegenerate(source) || IsDegenerate(target)
+	if collinear {
+		fmt.Println("Procrustes: Warning - points are collinear, the rotation is ill-conditioned.")
+	}
+
+	data := make([]float64, 0, 16)
+	for r := range normal {
+		data = append(data, normal[r][:]...)
+	}
+	var chol mat.Cholesky
+	if !chol.Factorize(mat.NewSymDense(4, data)) {
+		fmt.Println("Procrustes: Error - weights do not constrain the transform.")
+		return ProcrustesResult{Aligned: []Point{}}
+	}
+	var u mat.VecDense
+	if err := chol.SolveVecTo(&u, mat.NewVecDense(4, rhs[:])); err != nil {
+		fmt.Println("Procrustes: Error - weights do not constrain the transform.")
+		return ProcrustesResult{Aligned: []Point{}}
+	}
+	a, b := u.AtVec(0), u.AtVec(1)
+	translation := Point{X: u.AtVec(2), Y: u.AtVec(3)}
+
+	scale := math.Hypot(a, b)
+	rotation := [2][2]float64{{1, 0}, {0, 1}}
+	if scale > epsilon {
+		rotation = [2][2]float64{{a / scale, -b / scale}, {b / scale, a / scale}}
+	}
+	R := [][]float64{rotation[0][:], rotation[1][:]}
+	if fixScale {
+		scale = 1.0
+		// The best translation for the rotation solves sum(W_i) t = sum(W_i (target_i - R p_i)).
+		r := []float64{rhs[2], rhs[3]}
+		for i, p := range centeredSource {
+			rx, ry := R[0][0]*p.X+R[0][1]*p.Y, R[1][0]*p.X+R[1][1]*p.Y
+			W := weights[i]
+			r[0] -= W[0][0]*rx + W[0][1]*ry
+			r[1] -= W[1][0]*rx + W[1][1]*ry
+		}
+		var t mat.VecDense
+		N := mat.NewDense(2, 2, []float64{normal[2][2], normal[2][3], normal[3][2], normal[3][3]})
+		if err := t.SolveVec(N, mat.NewVecDense(2, r)); err != nil {
+			fmt.Println("Procrustes: Error - weights do not constrain the translation.")
+			return ProcrustesResult{Aligned: []Point{}}
+		}
+		translation = Point{X: t.AtVec(0), Y: t.AtVec(1)}
+	}
+
+	return ProcrustesResult{
+		Aligned:   applyTransformation(centeredSource, scale, R, translation),
+		Centroid:  translation,
+		Scale:     scale,
+		Rotation:  rotation,
+		Collinear: collinear,
+	}
 }
 
 func centroid(points []Point) Point {
@@ -191,7 +412,7 @@ func applyTransformation(centeredPoints []Point, scale float64, R [][]float64, t
 		fmt.Println("applyTransformation: Error - Rotation matrix must be 2x2.")
 		// Return original centered points or handle error
 		copy(aligned, centeredPoints) // Or return nil/error
-		return aligned // Return something to avoid panic, maybe untransformed points
+		return aligned                // Return something to avoid panic, maybe untransformed points
 	}
 
 	for i, p := range centeredPoints {
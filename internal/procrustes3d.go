@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Procrustes3DResult is the similarity transform found by ProcrustesFit3D.
+type Procrustes3DResult struct {
+	Aligned  []Point3D     // source points after the transform
+	Centroid Point3D       // target centroid, the translation applied to the centered source
+	Scale    float64       // scale factor
+	Rotation [3][3]float64 // rotation applied to the centered source points
+
+	// Degenerate is set when the source points are coincident (or there is only one),
+	// so rotation and scale are undefined. The transform is then translation-only,
+	// with identity rotation and unit scale.
+	Degenerate bool
+}
+
+// Procrustes3D aligns two sets of 3D points using least squares optimization.
+// It returns the transformed source points, the target centroid, and the scale factor.
+func Procrustes3D(source, target []Point3D) ([]Point3D, Point3D, float64) {
+	r := ProcrustesFit3D(source, target)
+	return r.Aligned, r.Centroid, r.Scale
+}
+
+// ProcrustesFit3D aligns source to target like Procrustes3D, returning the full transform.
+// The rotation is found by the Kabsch algorithm: R = V U^T from the SVD U S V^T of the 3x3
+// covariance of the centered points, with the last column of V negated when det(R) < 0 so that
+// a reflection is never returned. The scale is then the trace of the corrected S over the
+// variance of the source.
+func ProcrustesFit3D(source, target []Point3D) Procrustes3DResult {
+	if len(source) == 0 || len(source) != len(target) {
+		fmt.Println("Procrustes3D: Warning - empty or mismatched input point sets.")
+		return Procrustes3DResult{Aligned: []Point3D{}}
+	}
+
+	centroidSource := centroid3D(source)
+	centroidTarget := centroid3D(target)
+	centeredSource := centerPoints3D(source, centroidSource)
+	centeredTarget := centerPoints3D(target, centroidTarget)
+
+	var varSource float64
+	for _, p := range centeredSource {
+		varSource += p.X*p.X + p.Y*p.Y + p.Z*p.Z
+	}
+	identity := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if len(source) < 2 || varSource <= epsilon {
+		fmt.Println("Procrustes3D: Warning - source points are coincident. Performing translation only.")
+		return Procrustes3DResult{
+			Aligned:    applyTransformation3D(centeredSource, 1.0, identity, centroidTarget),
+			Centroid:   centroidTarget,
+			Scale:      1.0,
+			Rotation:   identity,
+			Degenerate: true,
+		}
+	}
+
+	// H = X Y^T, with the centered source and target points as the columns of X and Y.
+	H := mat.NewDense(3, 3, nil)
+	for i, p := range centeredSource {
+		s := [3]float64{p.X, p.Y, p.Z}
+		q := centeredTarget[i]
+		d := [3]float64{q.X, q.Y, q.Z}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				H.Set(r, c, H.At(r, c)+s[r]*d[c])
+			}
+		}
+	}
+
+	var svd mat.SVD
+	if !svd.Factorize(H, mat.SVDFull) {
+		fmt.Println("Procrustes3D: SVD factorization failed.")
+		return Procrustes3DResult{Aligned: []Point3D{}}
+	}
+	var U, V mat.Dense
+	svd.UTo(&U)
+	svd.VTo(&V)
+	S := svd.Values(nil)
+
+	var R mat.Dense
+	R.Mul(&V, U.T())
+	if mat.Det(&R) < 0 {
+		// Singular values are in descending order, so the last column of V belongs to the
+		// smallest; flipping it gives the nearest proper rotation.
+		for r := 0; r < 3; r++ {
+			V.Set(r, 2, -V.At(r, 2))
+		}
+		R.Mul(&V, U.T())
+		S[2] = -S[2]
+	}
+
+	scale := (S[0] + S[1] + S[2]) / varSource
+	var rotation [3][3]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			rotation[r][c] = R.At(r, c)
+		}
+	}
+
+	return Procrustes3DResult{
+		Aligned:  applyTransformation3D(centeredSource, scale, rotation, centroidTarget),
+		Centroid: centroidTarget,
+		Scale:    scale,
+		Rotation: rotation,
+	}
+}
+
+func centroid3D(points []Point3D) Point3D {
+	var c Point3D
+	if len(points) == 0 {
+		return c
+	}
+	for _, p := range points {
+		c.X += p.X
+		c.Y += p.Y
+		c.Z += p.Z
+	}
+	n := float64(len(points))
+	return Point3D{X: c.X / n, Y: c.Y / n, Z: c.Z / n}
+}
+
+func centerPoints3D(points []Point3D, centroid Point3D) []Point3D {
+	centered := make([]Point3D, len(points))
+	for i, p := range points {
+		centered[i] = Point3D{X: p.X - centroid.X, Y: p.Y - centroid.Y, Z: p.Z - centroid.Z}
+	}
+	return centered
+}
+
+// applyTransformation3D returns scale * R * p + translation for each centered point p.
+func applyTransformation3D(centeredPoints []Point3D, scale float64, R [3][3]float64, translation Point3D) []Point3D {
+	aligned := make([]Point3D, len(centeredPoints))
+	for i, p := range centeredPoints {
+		aligned[i] = Point3D{
+			X: scale*(R[0][0]*p.X+R[0][1]*p.Y+R[0][2]*p.Z) + translation.X,
+			Y: scale*(R[1][0]*p.X+R[1][1]*p.Y+R[1][2]*p.Z) + translation.Y,
+			Z: scale*(R[2][0]*p.X+R[2][1]*p.Y+R[2][2]*p.Z) + translation.Z,
+		}
+	}
+	return aligned
+}
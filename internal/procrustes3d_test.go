@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+// det3 returns the determinant of a 3x3 matrix.
+func det3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+func TestProcrustesFit3DRecoversTransform(t *testing.T) {
+	// R = Rz(30°) Rx(45°), a rotation about no coordinate axis.
+	cz, sz := math.Cos(math.Pi/6), math.Sin(math.Pi/6)
+	cx, sx := math.Cos(math.Pi/4), math.Sin(math.Pi/4)
+	rotation := [3][3]float64{
+		{cz, -sz * cx, sz * sx},
+		{sz, cz * cx, -cz * sx},
+		{0, sx, cx},
+	}
+	const scale = 1.5
+	translation := Point3D{X: 1, Y: -2, Z: 3}
+
+	source := []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}, {0, 0, 3}, {1, 1, 1}}
+	target := applyTransformation3D(source, scale, rotation, translation)
+
+	r := ProcrustesFit3D(source, target)
+	if r.Degenerate {
+		t.Fatal("Expected a full fit, got a degenerate one")
+	}
+	if !floatsClose(r.Scale, scale, 1e-9) {
+		t.Errorf("Expected scale %f, got %f", scale, r.Scale)
+	}
+	for i := range rotation {
+		for j := range rotation[i] {
+			if !floatsClose(r.Rotation[i][j], rotation[i][j], 1e-9) {
+				t.Errorf("Expected rotation %v, got %v", rotation, r.Rotation)
+			}
+		}
+	}
+	for i, p := range r.Aligned {
+		if p.Distance(target[i]) > 1e-9 {
+			t.Errorf("Point %d: Expected %v, got %v", i, target[i], p)
+		}
+	}
+	if want := centroid3D(target); r.Centroid.Distance(want) > 1e-9 {
+		t.Errorf("Expected centroid %v, got %v", want, r.Centroid)
+	}
+
+	aligned, centroid, s := Procrustes3D(source, target)
+	if len(aligned) != len(target) || centroid != r.Centroid || s != r.Scale {
+		t.Errorf("Expected Procrustes3D to match ProcrustesFit3D, got %v, %v, %f", aligned, centroid, s)
+	}
+}
+
+func TestProcrustesFit3DNeverReflects(t *testing.T) {
+	// The mirror image of a chiral point set is best matched by a reflection, which the fit
+	// must replace with a proper rotation.
+	source := []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}, {0, 0, 3}}
+	target := make([]Point3D, len(source))
+	for i, p := range source {
+		target[i] = Point3D{X: p.X, Y: p.Y, Z: -p.Z}
+	}
+
+	r := ProcrustesFit3D(source, target)
+	if d := det3(r.Rotation); !floatsClose(d, 1, 1e-9) {
+		t.Errorf("Expected a proper rotation with determinant 1, got %f", d)
+	}
+	if r.Scale <= 0 || r.Scale >= 1 {
+		t.Errorf("Expected a scale in (0, 1) for a mirrored set, got %f", r.Scale)
+	}
+}
+
+func TestProcrustesFit3DCoincidentSource(t *testing.T) {
+	source := []Point3D{{1, 1, 1}, {1, 1, 1}}
+	target := []Point3D{{0, 0, 0}, {2, 2, 2}}
+	r := ProcrustesFit3D(source, target)
+	if !r.Degenerate || r.Scale != 1 {
+		t.Errorf("Expected a translation-only fit, got %+v", r)
+	}
+	for _, p := range r.Aligned {
+		if p.Distance(Point3D{X: 1, Y: 1, Z: 1}) > 1e-12 {
+			t.Errorf("Expected points moved to the target centroid, got %v", p)
+		}
+	}
+}
@@ -75,3 +75,206 @@ func TestProcrustes(t *testing.T) {
 		}
 	}
 }
+
+func TestProcrustesCoincidentSource(t *testing.T) {
+	source := []Point{{2, 3}, {2, 3}, {2, 3}}
+	target := []Point{{0, 0}, {1, 0}, {0, 1}}
+
+	result := ProcrustesFit(source, target)
+	if !result.Degenerate {
+		t.Error("Expected coincident source to be flagged degenerate")
+	}
+	if result.Scale != 1.0 {
+		t.Errorf("Expected scale 1.0, got %f", result.Scale)
+	}
+	if result.Rotation != [2][2]float64{{1, 0}, {0, 1}} {
+		t.Errorf("Expected identity rotation, got %v", result.Rotation)
+	}
+	// Translation-only: every source point lands on the target centroid.
+	wantCentroid := Point{1.0 / 3, 1.0 / 3}
+	for i, p := range result.Aligned {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || !pointsClose(p, wantCentroid, 1e-9) {
+			t.Errorf("Expected aligned point %d at %v, got %v", i, wantCentroid, p)
+		}
+	}
+
+	// The legacy API agrees.
+	_, _, scale := Procrustes(source, target)
+	if scale != 1.0 {
+		t.Errorf("Expected Procrustes scale 1.0, got %f", scale)
+	}
+}
+
+func TestProcrustesCollinearSource(t *testing.T) {
+	target := []Point{{0, 0}, {1, 0}, {2, 0}}
+	// Target rotated by 90 degrees, scaled by 2, and translated by (3, 4).
+	source := []Point{{3, 4}, {3, 6}, {3, 8}}
+
+	result := ProcrustesFit(source, target)
+	if result.Degenerate {
+		t.Error("Expected collinear source not to be flagged degenerate")
+	}
+	if !result.Collinear {
+		t.Error("Expected collinear source to be flagged collinear")
+	}
+	if !floatsClose(result.Scale, 0.5, 1e-9) {
+		t.Errorf("Expected scale 0.5, got %f", result.Scale)
+	}
+	for i := range target {
+		if !pointsClose(result.Aligned[i], target[i], 1e-9) {
+			t.Errorf("Expected aligned point %d close to %v, got %v", i, target[i], result.Aligned[i])
+		}
+	}
+}
+
+func TestIsDegenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Point
+		expect bool
+	}{
+		{"Well Spread", []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, false},
+		{"Triangle", []Point{{0, 0}, {2, 0}, {1, 0.5}}, false},
+		{"Collinear", []Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}}, true},
+		{"Nearly Collinear", []Point{{0, 0}, {1, 1e-5}, {2, 0}}, true},
+		{"Coincident", []Point{{1, 1}, {1, 1}, {1, 1}}, true},
+		{"Two Points", []Point{{0, 0}, {1, 1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDegenerate(tt.points); got != tt.expect {
+				t.Errorf("Expected IsDegenerate %v, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	spread := ProcrustesFit([]Point{{0, 0}, {1, 0}, {0, 1}}, []Point{{1, 1}, {2, 1}, {1, 2}})
+	if spread.Collinear {
+		t.Error("Expected well-spread fit not to be flagged collinear")
+	}
+}
+
+func TestProcrustesRigidOnlyIgnoresScale(t *testing.T) {
+	source := []Point{{0, 0}, {1, 0}, {1, 2}, {-1, 1}}
+	theta := math.Pi / 6
+	c, s := math.Cos(theta), math.Sin(theta)
+	target := make([]Point, len(source))
+	for i, p := range source {
+		// Rotate by theta, scale by 2, translate by (3, -1).
+		target[i] = Point{X: 2*(c*p.X-s*p.Y) + 3, Y: 2*(s*p.X+c*p.Y) - 1}
+	}
+
+	scaled := ProcrustesFit(source, target)
+	if !floatsClose(scaled.Scale, 2, 1e-9) {
+		t.Errorf("Expected default fit to recover scale 2, got %f", scaled.Scale)
+	}
+
+	rigid := ProcrustesFitOptions(source, target, true)
+	if rigid.Scale != 1 {
+		t.Errorf("Expected rigid fit scale 1, got %f", rigid.Scale)
+	}
+	if !floatsClose(rigid.Rotation[0][0], c, 1e-9) || !floatsClose(rigid.Rotation[1][0], s, 1e-9) {
+		t.Errorf("Expected rotation by %f, got %v", theta, rigid.Rotation)
+	}
+
+	// The aligned points keep the source shape: pairwise distances are unscaled.
+	for i := 1; i < len(source); i++ {
+		want := math.Hypot(source[i].X-source[0].X, source[i].Y-source[0].Y)
+		got := math.Hypot(rigid.Aligned[i].X-rigid.Aligned[0].X, rigid.Aligned[i].Y-rigid.Aligned[0].Y)
+		if !floatsClose(got, want, 1e-9) {
+			t.Errorf("point %d: Expected distance %f from point 0, got %f", i, want, got)
+		}
+	}
+	if !pointsClose(centroid(rigid.Aligned), centroid(target), 1e-9) {
+		t.Errorf("Expected aligned centroid %v, got %v", centroid(target), centroid(rigid.Aligned))
+	}
+}
+
+// similarity maps p by rotation theta, scale s and translation t.
+func similarity(p Point, theta, s float64, t Point) Point {
+	c, sn := math.Cos(theta), math.Sin(theta)
+	return Point{X: s*(c*p.X-sn*p.Y) + t.X, Y: s*(sn*p.X+c*p.Y) + t.Y}
+}
+
+func TestProcrustesFitWeightedIsotropicMatchesSVD(t *testing.T) {
+	source := []Point{{0, 0}, {1, 0}, {1, 2}, {-1, 1}}
+	target := []Point{{3, -1}, {4.5, -0.5}, {2.2, 2.4}, {1.9, 0.3}}
+	weights := []float64{1, 2, 0.5, 3}
+
+	// Scalar weights of 1 are the unweighted fit.
+	want := ProcrustesFit(source, target)
+	got := ProcrustesFitWeighted(source, target, IsotropicWeights([]float64{1, 1, 1, 1}))
+	if !floatsClose(got.Scale, want.Scale, 1e-9) || !floatsClose(got.Rotation[1][0], want.Rotation[1][0], 1e-9) {
+		t.Errorf("Expected scale %f and rotation %v, got %f and %v", want.Scale, want.Rotation, got.Scale, got.Rotation)
+	}
+	for i := range want.Aligned {
+		if !pointsClose(got.Aligned[i], want.Aligned[i], 1e-9) {
+			t.Errorf("point %d: Expected %v, got %v", i, want.Aligned[i], got.Aligned[i])
+		}
+	}
+
+	// Repeating a point is the same as doubling its scalar weight.
+	repeated := ProcrustesFitWeighted(append(source, source[1]), append(target, target[1]), IsotropicWeights([]float64{1, 1, 0.5, 3, 1}))
+	weighted := ProcrustesFitWeighted(source, target, IsotropicWeights(weights))
+	for i := range source {
+		if !pointsClose(weighted.Aligned[i], repeated.Aligned[i], 1e-9) {
+			t.Errorf("point %d: Expected weight 2 to match a repeated point, got %v and %v", i, weighted.Aligned[i], repeated.Aligned[i])
+		}
+	}
+}
+
+func TestProcrustesFitWeightedAnisotropic(t *testing.T) {
+	theta, scale, shift := math.Pi/6, 1.5, Point{X: 3, Y: -1}
+	source := []Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {1, 3}, {-1, 1}}
+	target := make([]Point, len(source))
+	weights := make([]PointWeight, len(source))
+	for i, p := range source {
+		target[i] = similarity(p, theta, scale, shift)
+		// Alternate IMUs are accurate in only one axis and badly biased in the other.
+		if i%2 == 0 {
+			target[i].Y += 0.8
+			weights[i] = AxisWeight(1, 1e-6)
+		} else {
+			target[i].X += 0.8
+			weights[i] = AxisWeight(1e-6, 1)
+		}
+	}
+
+	fit := ProcrustesFitWeighted(source, target, weights)
+	if !floatsClose(fit.Scale, scale, 1e-3) {
+		t.Errorf("Expected scale %f, got %f", scale, fit.Scale)
+	}
+	if got := math.Atan2(fit.Rotation[1][0], fit.Rotation[0][0]); !floatsClose(got, theta, 1e-3) {
+		t.Errorf("Expected rotation %f, got %f", theta, got)
+	}
+	for i, p := range source {
+		if want := similarity(p, theta, scale, shift); !pointsClose(fit.Aligned[i], want, 1e-2) {
+			t.Errorf("point %d: Expected %v, got %v", i, want, fit.Aligned[i])
+		}
+	}
+
+	// Trusting every axis equally absorbs the bias into the transform.
+	plain := ProcrustesFitWeighted(source, target, nil)
+	if want := similarity(source[0], theta, scale, shift); pointsClose(plain.Aligned[0], want, 0.1) {
+		t.Errorf("Expected the unweighted fit to be biased, got %v", plain.Aligned[0])
+	}
+
+	// The rigid fit keeps the rotation and the unit scale.
+	rigidTarget := make([]Point, len(source))
+	for i, p := range source {
+		rigidTarget[i] = similarity(p, theta, 1, shift)
+	}
+	rigid := ProcrustesFitWeightedOptions(source, rigidTarget, IsotropicWeights([]float64{1, 2, 1, 3, 1, 1}), true)
+	for i, p := range rigidTarget {
+		if !pointsClose(rigid.Aligned[i], p, 1e-9) {
+			t.Errorf("point %d: Expected rigid fit %v, got %v", i, p, rigid.Aligned[i])
+		}
+	}
+
+	// Weights that leave an axis unconstrained cannot fit.
+	xOnly := []PointWeight{AxisWeight(1, 0), AxisWeight(1, 0), AxisWeight(1, 0), AxisWeight(1, 0), AxisWeight(1, 0), AxisWeight(1, 0)}
+	if fit := ProcrustesFitWeighted(source, target, xOnly); len(fit.Aligned) != 0 {
+		t.Errorf("Expected no fit with X-only weights, got %v", fit.Aligned)
+	}
+}
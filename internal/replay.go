@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording formats understood by FileReplaySource.
+//
+// CSV files (".csv") have one sample per row, with an optional header row:
+//
+//	imu_id,timestamp_ns,ax,ay,az,gx,gy,gz
+//
+// Any other extension is read as JSON lines, one object per line:
+//
+//	{"imu_id":0,"timestamp_ns":1000000,"accel":[0,0,9.81],"gyro":[0,0,0]}
+//
+// timestamp_ns is the device sample time in nanoseconds since the Unix epoch.
+type replayRecord struct {
+	IMUID       int        `json:"imu_id"`
+	TimestampNs int64      `json:"timestamp_ns"`
+	Accel       [3]float64 `json:"accel"`
+	Gyro        [3]float64 `json:"gyro"`
+}
+
+// validate rejects records that cannot belong to any IMU.
+func (r replayRecord) validate() error {
+	if r.IMUID < 0 {
+		return fmt.Errorf("negative imu_id %d", r.IMUID)
+	}
+	return nil
+}
+
+// FileReplaySource is a Source that replays a recorded session from a file,
+// honoring the recorded inter-sample timing.
+type FileReplaySource struct {
+	file     *os.File
+	isCSV    bool
+	speedup  float64 // playback rate multiplier, <= 0 to replay as fast as possible
+	logger   Logger  // receives the error that ends the replay, see SetLogger
+	stopChan chan struct{}
+	stopWg   sync.WaitGroup
+	started  bool
+	err      error // first read or parse error, valid once the sample channel is closed
+}
+
+// NewFileReplaySource opens a recording for replay. A speedup of 1 replays in real time,
+// 2 at double speed, and so on; a speedup <= 0 replays as fast as the consumer accepts samples.
+func NewFileReplaySource(path string, speedup float64) (*FileReplaySource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileReplaySource{
+		file:     file,
+		isCSV:    strings.EqualFold(filepath.Ext(path), ".csv"),
+		speedup:  speedup,
+		logger:   stdoutLogger{},
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// SetLogger sends the error that ends the replay early to logger instead of stdout. A nil
+// logger restores stdout. It should be called before Start.
+func (s *FileReplaySource) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = stdoutLogger{}
+	}
+	s.logger = logger
+}
+
+// Start begins replaying. The channel is closed at end of file, on a read error, or on Stop.
+func (s *FileReplaySource) Start() <-chan IMUData {
+	out := make(chan IMUData)
+	s.started = true
+	s.stopWg.Add(1)
+	go func() {
+		defer s.stopWg.Done()
+		defer close(out)
+		defer s.file.Close()
+
+		next := s.jsonReader()
+		if s.isCSV {
+			next = s.csvReader()
+		}
+
+		var first time.Time
+		var wallStart time.Time
+		for {
+			rec, err := next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				s.err = err
+				s.logger.Printf("FileReplaySource: %v\n", err)
+				return
+			}
+
+			ts := time.Unix(0, rec.TimestampNs)
+			if first.IsZero() {
+				first, wallStart = ts, time.Now()
+			}
+			if s.speedup > 0 {
+				due := wallStart.Add(time.Duration(float64(ts.Sub(first)) / s.speedup))
+				if wait := time.Until(due); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-s.stopChan:
+						return
+					}
+				}
+			}
+
+			data := IMUData{
+				IMUID:           rec.IMUID,
+				DeviceTimestamp: ts,
+				Acceleration:    rec.Accel,
+				AngularVelocity: rec.Gyro,
+			}
+			select {
+			case out <- data:
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Stop halts the replay and closes the file.
+func (s *FileReplaySource) Stop() {
+	close(s.stopChan)
+	s.stopWg.Wait()
+	if !s.started {
+		s.file.Close()
+	}
+}
+
+// Err returns the error that ended the replay early, if any.
+// It should only be called after the sample channel has been closed.
+func (s *FileReplaySource) Err() error {
+	return s.err
+}
+
+func (s *FileReplaySource) jsonReader() func() (replayRecord, error) {
+	scanner := bufio.NewScanner(s.file)
+	line := 0
+	return func() (replayRecord, error) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var rec replayRecord
+			if err := json.Unmarshal([]byte(text), &rec); err != nil {
+				return replayRecord{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			if err := rec.validate(); err != nil {
+				return replayRecord{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			return rec, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return replayRecord{}, err
+		}
+		return replayRecord{}, io.EOF
+	}
+}
+
+func (s *FileReplaySource) csvReader() func() (replayRecord, error) {
+	reader := csv.NewReader(s.file)
+	reader.FieldsPerRecord = 8
+	reader.TrimLeadingSpace = true
+	line := 0
+	return func() (replayRecord, error) {
+		for {
+			fields, err := reader.Read()
+			if err != nil {
+				return replayRecord{}, err
+			}
+			line++
+			if line == 1 && fields[0] == "imu_id" {
+				continue // header
+			}
+			rec, err := parseCSVRecord(fields)
+			if err != nil {
+				return replayRecord{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			return rec, nil
+		}
+	}
+}
+
+func parseCSVRecord(fields []string) (replayRecord, error) {
+	var rec replayRecord
+	var err error
+	if rec.IMUID, err = strconv.Atoi(fields[0]); err != nil {
+		return rec, err
+	}
+	if rec.TimestampNs, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return rec, err
+	}
+	for i := 0; i < 3; i++ {
+		if rec.Accel[i], err = strconv.ParseFloat(fields[2+i], 64); err != nil {
+			return rec, err
+		}
+		if rec.Gyro[i], err = strconv.ParseFloat(fields[5+i], 64); err != nil {
+			return rec, err
+		}
+	}
+	return rec, rec.validate()
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRecording(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write recording: %v", err)
+	}
+	return path
+}
+
+func drain(t *testing.T, samples <-chan IMUData) ([]IMUData, []time.Time) {
+	t.Helper()
+	var data []IMUData
+	var arrivals []time.Time
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case d, ok := <-samples:
+			if !ok {
+				return data, arrivals
+			}
+			data = append(data, d)
+			arrivals = append(arrivals, time.Now())
+		case <-timeout:
+			t.Fatal("timed out waiting for replay to finish")
+		}
+	}
+}
+
+func TestFileReplaySourceCSVTiming(t *testing.T) {
+	path := writeRecording(t, "session.csv", `imu_id,timestamp_ns,ax,ay,az,gx,gy,gz
+0,1000000000,0.1,0,9.81,0,0,0
+1,1000000000,0.2,0,9.81,0,0,0
+0,1040000000,0.3,0,9.81,0,0,0.5
+1,1040000000,0.4,0,9.81,0,0,0.5
+`)
+	src, err := NewFileReplaySource(path, 2)
+	if err != nil {
+		t.Fatalf("NewFileReplaySource failed: %v", err)
+	}
+	defer src.Stop()
+
+	data, arrivals := drain(t, src.Start())
+	if err := src.Err(); err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if len(data) != 4 {
+		t.Fatalf("Expected 4 samples, got %d", len(data))
+	}
+	for i, want := range []float64{0.1, 0.2, 0.3, 0.4} {
+		if data[i].Acceleration[0] != want {
+			t.Errorf("sample %d: expected ax %f, got %f (out of order?)", i, want, data[i].Acceleration[0])
+		}
+	}
+	if want := time.Unix(0, 1040000000); !data[3].DeviceTimestamp.Equal(want) {
+		t.Errorf("Expected device timestamp %v, got %v", want, data[3].DeviceTimestamp)
+	}
+	if data[2].AngularVelocity[2] != 0.5 {
+		t.Errorf("Expected gyro z 0.5, got %f", data[2].AngularVelocity[2])
+	}
+
+	// 40ms of recording at 2x speed should take about 20ms to replay.
+	if gap := arrivals[2].Sub(arrivals[0]); gap < 15*time.Millisecond || gap > 60*time.Millisecond {
+		t.Errorf("Expected about 20ms between recorded frames, got %v", gap)
+	}
+}
+
+func TestFileReplaySourceJSONLinesAsFastAsPossible(t *testing.T) {
+	path := writeRecording(t, "session.jsonl", `{"imu_id":0,"timestamp_ns":0,"accel":[1,0,0],"gyro":[0,0,0]}
+
+{"imu_id":0,"timestamp_ns":10000000000,"accel":[2,0,0],"gyro":[0,0,0]}
+`)
+	src, err := NewFileReplaySource(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileReplaySource failed: %v", err)
+	}
+	defer src.Stop()
+
+	start := time.Now()
+	data, _ := drain(t, src.Start())
+	if len(data) != 2 || data[0].Acceleration[0] != 1 || data[1].Acceleration[0] != 2 {
+		t.Fatalf("Expected samples with ax 1 then 2, got %+v", data)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected as-fast-as-possible replay of a 10s recording, took %v", elapsed)
+	}
+}
+
+func TestFileReplaySourceParseError(t *testing.T) {
+	path := writeRecording(t, "broken.jsonl", `{"imu_id":0,"timestamp_ns":0}
+not json
+`)
+	src, err := NewFileReplaySource(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileReplaySource failed: %v", err)
+	}
+	defer src.Stop()
+	var logged bytes.Buffer
+	src.SetLogger(log.New(&logged, "", 0))
+
+	data, _ := drain(t, src.Start())
+	if len(data) != 1 {
+		t.Errorf("Expected 1 sample before the parse error, got %d", len(data))
+	}
+	if src.Err() == nil {
+		t.Error("Expected a parse error")
+	}
+	if !strings.Contains(logged.String(), "line 2") {
+		t.Errorf("Expected the parse error in the configured logger, got %q", logged.String())
+	}
+}
+
+func TestFileReplaySourceRejectsNegativeIMUID(t *testing.T) {
+	recordings := map[string]string{
+		"negative.csv":   "imu_id,timestamp_ns,ax,ay,az,gx,gy,gz\n0,0,0,0,9.81,0,0,0\n-1,0,0,0,9.81,0,0,0\n",
+		"negative.jsonl": `{"imu_id":0,"timestamp_ns":0}` + "\n" + `{"imu_id":-1,"timestamp_ns":0}` + "\n",
+	}
+	for name, contents := range recordings {
+		t.Run(name, func(t *testing.T) {
+			src, err := NewFileReplaySource(writeRecording(t, name, contents), 0)
+			if err != nil {
+				t.Fatalf("NewFileReplaySource failed: %v", err)
+			}
+			defer src.Stop()
+
+			data, _ := drain(t, src.Start())
+			if len(data) != 1 {
+				t.Errorf("Expected 1 sample before the invalid IMU ID, got %d", len(data))
+			}
+			if src.Err() == nil {
+				t.Error("Expected an error for the negative IMU ID")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// FusedSample is a fused and refined position emitted by the pipeline.
+type FusedSample struct {
+	Timestamp  time.Time // sample time of the frame the position was fused from
+	X, Y       float64
+	VX, VY     float64 // fused velocity, or the smoothed velocity when output smoothing is enabled
+	Residual   float64 // FusionResidual of the geometric fusion, before refinement
+	Confidence float64 // FusionConfidence of the geometric fusion, in [0, 1]
+	Stale      bool    // set by the resampler when no new frame arrived since the last emission
+	Group      int     // index of the IMU group the position was fused from, see SetGroups
+}
+
+// outputResampler holds the most recent fused sample so it can be emitted at a fixed rate,
+// independent of the input frame rate.
+type outputResampler struct {
+	mu        sync.Mutex
+	latest    FusedSample
+	hasLatest bool
+	fresh     bool // latest has not been emitted yet
+}
+
+// update records a new fused sample.
+func (r *outputResampler) update(sample FusedSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latest = sample
+	r.hasLatest = true
+	r.fresh = true
+}
+
+// next returns the sample to emit on a tick, holding the last value and flagging it stale
+// if nothing new has arrived. ok is false until the first sample has been recorded.
+func (r *outputResampler) next() (FusedSample, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.hasLatest {
+		return FusedSample{}, false
+	}
+	sample := r.latest
+	sample.Stale = !r.fresh
+	r.fresh = false
+	return sample, true
+}
+
+// run emits a sample to output every period until stop is closed.
+func (r *outputResampler) run(period time.Duration, output func(FusedSample), stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if sample, ok := r.next(); ok {
+				output(sample)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
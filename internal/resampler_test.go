@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutputResamplerHoldsStaleValue(t *testing.T) {
+	var r outputResampler
+	if _, ok := r.next(); ok {
+		t.Fatal("Expected no output before the first sample")
+	}
+
+	r.update(FusedSample{X: 1, Y: 2})
+	sample, ok := r.next()
+	if !ok || sample.Stale || sample.X != 1 || sample.Y != 2 {
+		t.Errorf("Expected fresh sample (1, 2), got %+v (ok=%v)", sample, ok)
+	}
+
+	sample, ok = r.next()
+	if !ok || !sample.Stale || sample.X != 1 || sample.Y != 2 {
+		t.Errorf("Expected stale repeat of (1, 2), got %+v (ok=%v)", sample, ok)
+	}
+
+	r.update(FusedSample{X: 3, Y: 4})
+	if sample, _ = r.next(); sample.Stale || sample.X != 3 {
+		t.Errorf("Expected fresh sample (3, 4), got %+v", sample)
+	}
+}
+
+func TestIMUFusionSystemOutputRate(t *testing.T) {
+	const imuCount = 1
+	src := &chanSource{samples: make(chan IMUData, 1)}
+	sys, err := NewIMUFusionSystemWithSource(imuCount, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	outputs := make(chan time.Time, 64)
+	stale := make(chan bool, 64)
+	sys.output = func(sample FusedSample) {
+		outputs <- time.Now()
+		stale <- sample.Stale
+	}
+	sys.SetOutputRate(100)
+	sys.Start()
+	defer sys.Stop()
+
+	// A single input frame: the resampler must keep emitting it at 100Hz.
+	src.samples <- IMUData{IMUID: 0, DeviceTimestamp: time.Unix(1, 0)}
+
+	const n = 6
+	var ticks []time.Time
+	for len(ticks) < n {
+		select {
+		case ts := <-outputs:
+			ticks = append(ticks, ts)
+		case <-time.After(200 * time.Millisecond):
+			t.Fatalf("timed out after %d outputs", len(ticks))
+		}
+	}
+
+	if <-stale {
+		t.Error("Expected the first output to be fresh")
+	}
+	for i := 1; i < n; i++ {
+		if !<-stale {
+			t.Errorf("Expected output %d to be flagged stale", i)
+		}
+	}
+
+	avg := ticks[n-1].Sub(ticks[0]) / (n - 1)
+	if avg < 5*time.Millisecond || avg > 20*time.Millisecond {
+		t.Errorf("Expected output period near 10ms, got %v", avg)
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailingSource is a Source that can fail permanently, such as a RetryingSource that has run out
+// of reconnect attempts. IMUFusionSystem.Statuses reports every IMU as IMUFailed once Err is
+// not nil.
+type FailingSource interface {
+	Source
+	// Err returns the permanent failure of the source, or nil while it is healthy.
+	Err() error
+}
+
+// Backoff is the reconnect schedule of a RetryingSource: the delay before a reconnect starts at
+// Initial and is multiplied by Multiplier after each reconnect that delivers no sample, up to Max.
+type Backoff struct {
+	Initial     time.Duration
+	Max         time.Duration // 0 for no cap
+	Multiplier  float64       // values below 1 are treated as 1
+	MaxAttempts int           // consecutive reconnects without a sample before giving up, 0 for no limit
+}
+
+// DefaultBackoff returns a Backoff doubling from 10ms up to 5s, giving up after 10 reconnects
+// in a row deliver nothing.
+func DefaultBackoff() Backoff {
+	return Backoff{Initial: 10 * time.Millisecond, Max: 5 * time.Second, Multiplier: 2, MaxAttempts: 10}
+}
+
+// delay returns the wait before a reconnect following failures failed reconnects in a row.
+func (b Backoff) delay(failures int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < failures; i++ {
+		if b.Multiplier > 1 {
+			d *= b.Multiplier
+		}
+		if b.Max > 0 && d >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	return time.Duration(d)
+}
+
+// RetryingSource restarts a streaming Source whose sample channel closes while it is in use,
+// treating the close as a disconnect, so a dropped network or serial link does not end
+// acquisition. The inner source must support Start being called again once its channel has
+// closed, and should only close it on a disconnect: an exhausted source, such as a finished
+// replay, is restarted too.
+type RetryingSource struct {
+	inner    Source
+	backoff  Backoff
+	logger   Logger // receives disconnects and give-ups, see SetLogger
+	stopChan chan struct{}
+	stopWg   sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // permanent failure, see Err
+}
+
+// RetrySource wraps inner so that it is reconnected with the given backoff whenever its channel
+// closes. Once backoff.MaxAttempts reconnects in a row deliver no sample, it gives up: its own
+// channel is closed and Err reports the failure.
+func RetrySource(inner Source, backoff Backoff) *RetryingSource {
+	return &RetryingSource{inner: inner, backoff: backoff, logger: stdoutLogger{}, stopChan: make(chan struct{})}
+}
+
+// SetLogger sends the source's disconnect and give-up messages to logger instead of stdout.
+// A nil logger restores stdout. It should be called before Start.
+func (s *RetryingSource) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = stdoutLogger{}
+	}
+	s.logger = logger
+}
+
+// Start starts the inner source and forwards its samples, reconnecting it as needed.
+func (s *RetryingSource) Start() <-chan IMUData {
+	out := make(chan IMUData)
+	s.stopWg.Add(1)
+	go func() {
+		defer s.stopWg.Done()
+		defer close(out)
+		failures := 0
+		for {
+			received := false
+			in := s.inner.Start()
+		forward:
+			for {
+				select {
+				case data, ok := <-in:
+					if !ok {
+						break forward
+					}
+					received, failures = true, 0
+					select {
+					case out <- data:
+					case <-s.stopChan:
+						return
+					}
+				case <-s.stopChan:
+					return
+				}
+			}
+			if !received {
+				failures++
+			}
+			if s.backoff.MaxAttempts > 0 && failures >= s.backoff.MaxAttempts {
+				s.logger.Printf("RetrySource: Error - giving up after %d failed reconnect attempts\n", failures)
+				s.mu.Lock()
+				s.err = fmt.Errorf("source failed: %d reconnect attempts in a row delivered no samples", failures)
+				s.mu.Unlock()
+				return
+			}
+			delay := s.backoff.delay(failures)
+			s.logger.Printf("RetrySource: Warning - source disconnected, reconnecting in %v\n", delay)
+			select {
+			case <-time.After(delay):
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Stop halts the inner source and any pending reconnect.
+func (s *RetryingSource) Stop() {
+	close(s.stopChan)
+	s.inner.Stop()
+	s.stopWg.Wait()
+}
+
+// Err returns the permanent failure once the source has given up reconnecting, or nil.
+func (s *RetryingSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySource fails its first failures connections outright, then delivers batches of
+// samples, disconnecting after each batch.
+type flakySource struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]IMUData
+	starts   int
+}
+
+func (s *flakySource) Start() <-chan IMUData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.starts++
+	ch := make(chan IMUData, 16)
+	if s.failures > 0 {
+		s.failures--
+	} else if len(s.batches) > 0 {
+		for _, d := range s.batches[0] {
+			ch <- d
+		}
+		s.batches = s.batches[1:]
+	} else {
+		return ch // connected but idle
+	}
+	close(ch)
+	return ch
+}
+
+func (s *flakySource) Stop() {}
+
+func TestRetrySourceResumesAfterFailures(t *testing.T) {
+	inner := &flakySource{
+		failures: 3,
+		batches: [][]IMUData{
+			{{IMUID: 0}, {IMUID: 1}},
+			{{IMUID: 2}},
+		},
+	}
+	src := RetrySource(inner, Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2, MaxAttempts: 5})
+	out := src.Start()
+	defer src.Stop()
+
+	for want := 0; want < 3; want++ {
+		select {
+		case data := <-out:
+			if data.IMUID != want {
+				t.Errorf("Expected sample from IMU %d, got %d", want, data.IMUID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for sample %d", want)
+		}
+	}
+	if err := src.Err(); err != nil {
+		t.Errorf("Expected no failure, got %v", err)
+	}
+	inner.mu.Lock()
+	defer inner.mu.Unlock()
+	// Three failed connections and one per batch.
+	if inner.starts < 5 {
+		t.Errorf("Expected at least 5 connections, got %d", inner.starts)
+	}
+}
+
+func TestRetrySourceGivesUp(t *testing.T) {
+	inner := &flakySource{failures: 100}
+	src := RetrySource(inner, Backoff{Initial: time.Millisecond, MaxAttempts: 3})
+	var logged bytes.Buffer
+	src.SetLogger(log.New(&logged, "", 0))
+	sys, err := NewIMUFusionSystemWithSource(2, src)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.Start()
+	defer sys.Stop()
+
+	deadline := time.After(time.Second)
+	for src.Err() == nil {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for the source to give up")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	for _, s := range sys.Statuses() {
+		if s.State != IMUFailed {
+			t.Errorf("Expected IMU %d failed, got %v", s.IMUID, s.State)
+		}
+	}
+	// The give-up is logged before Err reports it.
+	if got := logged.String(); strings.Count(got, "reconnecting in") != 2 || !strings.Contains(got, "giving up after 3") {
+		t.Errorf("Expected two reconnects and a give-up logged, got %q", got)
+	}
+	if got := (Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2}).delay(5); got != 5*time.Millisecond {
+		t.Errorf("Expected the delay capped at 5ms, got %v", got)
+	}
+}
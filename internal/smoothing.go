@@ -0,0 +1,80 @@
+package internal
+
+import "math"
+
+// AlphaBetaFilter smooths a stream of 2D positions with a constant-velocity alpha-beta tracker.
+// Each measurement corrects the predicted position by alpha times the residual and the velocity
+// by beta/dt times the residual. It is a lightweight alternative to a Kalman filter with fixed
+// gains: smaller gains smooth more at the cost of a slower response to manoeuvres.
+type AlphaBetaFilter struct {
+	alpha, beta float64
+	pos, vel    Point
+	initialized bool
+}
+
+// NewAlphaBetaFilter creates an AlphaBetaFilter with position gain alpha and velocity gain beta,
+// typically with 0 < beta < alpha <= 1.
+func NewAlphaBetaFilter(alpha, beta float64) *AlphaBetaFilter {
+	return &AlphaBetaFilter{alpha: alpha, beta: beta}
+}
+
+// Update advances the tracker by dt seconds and corrects it with the measured position z,
+// returning the smoothed position and velocity. The first measurement initializes the
+// position with zero velocity.
+func (f *AlphaBetaFilter) Update(z Point, dt float64) (Point, Point) {
+	if !f.initialized || dt <= 0 {
+		if !f.initialized {
+			f.pos, f.vel = z, Point{}
+			f.initialized = true
+		}
+		return f.pos, f.vel
+	}
+	predicted := Point{X: f.pos.X + f.vel.X*dt, Y: f.pos.Y + f.vel.Y*dt}
+	rx, ry := z.X-predicted.X, z.Y-predicted.Y
+	f.pos = Point{X: predicted.X + f.alpha*rx, Y: predicted.Y + f.alpha*ry}
+	f.vel = Point{X: f.vel.X + f.beta/dt*rx, Y: f.vel.Y + f.beta/dt*ry}
+	return f.pos, f.vel
+}
+
+// Reset discards the tracked position and velocity; the next Update reinitializes them.
+func (f *AlphaBetaFilter) Reset() {
+	f.pos, f.vel = Point{}, Point{}
+	f.initialized = false
+}
+
+// LowPassFilter is a first-order low-pass filter on a 2D signal, the discrete form of an RC
+// filter: each sample moves the output towards it by dt/(RC+dt), with RC = 1/(2*pi*cutoff).
+// Components well below the cutoff frequency pass almost unchanged; those above it are
+// attenuated by about cutoff/f.
+type LowPassFilter struct {
+	rc          float64 // time constant in seconds
+	out         Point
+	initialized bool
+}
+
+// NewLowPassFilter creates a LowPassFilter with the given cutoff frequency in Hz.
+func NewLowPassFilter(cutoffHz float64) *LowPassFilter {
+	return &LowPassFilter{rc: 1 / (2 * math.Pi * cutoffHz)}
+}
+
+// Update advances the filter by dt seconds with the sample z and returns the filtered value.
+// The first sample initializes the output, so a constant signal passes without a transient.
+func (f *LowPassFilter) Update(z Point, dt float64) Point {
+	if !f.initialized {
+		f.out = z
+		f.initialized = true
+		return f.out
+	}
+	if dt <= 0 {
+		return f.out
+	}
+	k := dt / (f.rc + dt)
+	f.out = Point{X: f.out.X + k*(z.X-f.out.X), Y: f.out.Y + k*(z.Y-f.out.Y)}
+	return f.out
+}
+
+// Reset discards the filtered value; the next Update reinitializes it.
+func (f *LowPassFilter) Reset() {
+	f.out = Point{}
+	f.initialized = false
+}
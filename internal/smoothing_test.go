@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAlphaBetaFilterReducesNoiseWithBoundedLag(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	f := NewAlphaBetaFilter(0.2, 0.02)
+	const (
+		dt    = 0.01
+		speed = 0.5 // constant velocity along X
+		noise = 0.05
+		steps = 2000
+		warm  = 200 // frames ignored while the tracker converges
+	)
+
+	var rawSq, smoothSq, smoothSum, velSum float64
+	for i := 0; i < steps; i++ {
+		truth := Point{X: speed * dt * float64(i), Y: 1}
+		z := Point{X: truth.X + noise*rng.NormFloat64(), Y: truth.Y + noise*rng.NormFloat64()}
+		pos, vel := f.Update(z, dt)
+		if i < warm {
+			continue
+		}
+		rawSq += (z.X-truth.X)*(z.X-truth.X) + (z.Y-truth.Y)*(z.Y-truth.Y)
+		smoothSq += (pos.X-truth.X)*(pos.X-truth.X) + (pos.Y-truth.Y)*(pos.Y-truth.Y)
+		smoothSum += pos.X - truth.X
+		velSum += vel.X
+	}
+	n := float64(steps - warm)
+
+	if smoothSq >= 0.5*rawSq {
+		t.Errorf("Expected smoothing to at least halve the error variance, got %f vs raw %f", smoothSq/n, rawSq/n)
+	}
+	if lag := math.Abs(smoothSum / n); lag > noise/2 {
+		t.Errorf("Expected mean lag below %f, got %f", noise/2, lag)
+	}
+	if v := velSum / n; !floatsClose(v, speed, 0.05) {
+		t.Errorf("Expected mean velocity close to %f, got %f", speed, v)
+	}
+}
+
+func TestAlphaBetaFilterInitializesAndResets(t *testing.T) {
+	f := NewAlphaBetaFilter(0.5, 0.1)
+	pos, vel := f.Update(Point{X: 3, Y: 4}, 0.01)
+	if pos != (Point{X: 3, Y: 4}) || vel != (Point{}) {
+		t.Errorf("Expected first update to initialize at (3, 4) at rest, got %v and %v", pos, vel)
+	}
+	f.Reset()
+	if pos, _ = f.Update(Point{X: -1, Y: 0}, 0.01); pos != (Point{X: -1, Y: 0}) {
+		t.Errorf("Expected update after Reset to reinitialize at (-1, 0), got %v", pos)
+	}
+}
+
+func TestLowPassFilterAttenuatesNoise(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	f := NewLowPassFilter(5)
+	const (
+		dt      = 0.001
+		steps   = 10000 // five periods of the 0.5Hz motion
+		settle  = 2000  // leaves four whole periods
+		noiseSD = 0.5
+	)
+	var inErr, outErr, outMean float64
+	for i := 0; i < steps; i++ {
+		tt := float64(i) * dt
+		clean := 1 + math.Sin(2*math.Pi*0.5*tt)
+		z := clean + rng.NormFloat64()*noiseSD
+		out := f.Update(Point{X: z, Y: -z}, dt)
+		if out.Y != -out.X {
+			t.Fatalf("Expected the axes to be filtered alike, got %v", out)
+		}
+		if i < settle {
+			continue
+		}
+		inErr += (z - clean) * (z - clean)
+		outErr += (out.X - clean) * (out.X - clean)
+		outMean += out.X / (steps - settle)
+	}
+	inRMS := math.Sqrt(inErr / (steps - settle))
+	outRMS := math.Sqrt(outErr / (steps - settle))
+	if outRMS > 0.25*inRMS {
+		t.Errorf("Expected the filter to remove most of the noise, got RMS error %f from %f", outRMS, inRMS)
+	}
+	if math.Abs(outMean-1) > 0.02 {
+		t.Errorf("Expected the DC level 1 to pass, got mean %f", outMean)
+	}
+
+	f.Reset()
+	if got := f.Update(Point{X: 3, Y: 4}, dt); got != (Point{X: 3, Y: 4}) {
+		t.Errorf("Expected the first sample after Reset to pass through, got %v", got)
+	}
+}
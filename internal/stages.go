@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a point in the fusion pipeline that can be observed with Subscribe.
+type Stage int
+
+const (
+	StageRawSample        Stage = iota // IMUData of each sample fused, as aligned
+	StageCalibratedSample              // CalibratedSample of each sample fused
+	StagePerIMUPosition                // IMUPosition of each IMU integrated in a frame
+	StageFusedPosition                 // FusedSample of each emitted position, before resampling
+	stageCount
+)
+
+// CalibratedSample is an IMU sample after leveling, calibration and any acceleration low-pass,
+// still in the IMU's own frame.
+type CalibratedSample struct {
+	IMUID           int
+	Timestamp       time.Time  // frame time
+	Acceleration    Point      // planar acceleration
+	AngularVelocity [3]float64 // gyro-calibrated angular velocity, in rad/s
+}
+
+// IMUPosition is the body reference position integrated from one IMU in a frame, before fusion.
+type IMUPosition struct {
+	IMUID     int
+	Timestamp time.Time // frame time
+	Position  Point
+}
+
+// subscriberBuffer is the number of values a subscription holds before further values are dropped.
+const subscriberBuffer = 256
+
+// stageBus fans values out to the subscribers of each stage without blocking the pipeline.
+type stageBus struct {
+	mu          sync.RWMutex
+	subscribers [stageCount][]chan any
+	counts      [stageCount]int32 // subscribers per stage, read atomically on the hot path
+}
+
+// Subscribe returns a channel receiving the values of stage, of the type documented on the
+// stage, until Unsubscribe. Values are sent without blocking: a subscriber that falls more than
+// subscriberBuffer values behind misses the rest until it catches up. Publishing to no
+// subscribers costs nothing beyond a counter check. It returns nil for an unknown stage.
+func (fs *FusionState) Subscribe(stage Stage) <-chan any {
+	if stage < 0 || stage >= stageCount {
+		return nil
+	}
+	ch := make(chan any, subscriberBuffer)
+	b := &fs.stages
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[stage] = append(b.subscribers[stage], ch)
+	atomic.AddInt32(&b.counts[stage], 1)
+	return ch
+}
+
+// Unsubscribe stops and closes a channel returned by Subscribe, reporting whether it was found.
+func (fs *FusionState) Unsubscribe(ch <-chan any) bool {
+	b := &fs.stages
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for stage, subs := range b.subscribers {
+		for i, sub := range subs {
+			if sub == ch {
+				b.subscribers[stage] = append(subs[:i:i], subs[i+1:]...)
+				atomic.AddInt32(&b.counts[stage], -1)
+				close(sub)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// active reports whether stage has subscribers, so callers can skip building its values.
+func (b *stageBus) active(stage Stage) bool {
+	return atomic.LoadInt32(&b.counts[stage]) > 0
+}
+
+// publish sends v to the subscribers of stage that have room for it.
+func (b *stageBus) publish(stage Stage, v any) {
+	if !b.active(stage) {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subscribers[stage] {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeCalibratedSample(t *testing.T) {
+	sys, err := NewIMUFusionSystemWithSource(2, &chanSource{})
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystemWithSource failed: %v", err)
+	}
+	sys.output = func(FusedSample) {}
+	sys.calib[0].OffsetX = 0.5
+	sys.calib[0].ScaleY = 2
+	sys.calib[0].GyroBias = [3]float64{0.05, 0, 0}
+
+	raw := sys.Subscribe(StageRawSample)
+	calibrated := sys.Subscribe(StageCalibratedSample)
+	positions := sys.Subscribe(StagePerIMUPosition)
+	fused := sys.Subscribe(StageFusedPosition)
+	if sys.Subscribe(stageCount) != nil {
+		t.Error("Expected no channel for an unknown stage")
+	}
+
+	ts := time.Unix(1, 0)
+	sys.processFrame([]IMUData{
+		{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{1.5, 1, 0}, AngularVelocity: [3]float64{0.1, 0, 0}},
+		{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{1.5, 1, 0}, AngularVelocity: [3]float64{0.1, 0, 0}},
+	})
+
+	for i := 0; i < 2; i++ {
+		r := (<-raw).(IMUData)
+		if r.Acceleration != [3]float64{1.5, 1, 0} {
+			t.Errorf("IMU %d: Expected the raw acceleration, got %v", r.IMUID, r.Acceleration)
+		}
+		c := (<-calibrated).(CalibratedSample)
+		want := CalibratedSample{IMUID: c.IMUID, Timestamp: ts, Acceleration: Point{X: 1.5, Y: 1}, AngularVelocity: [3]float64{0.1, 0, 0}}
+		if c.IMUID == 0 {
+			// (1.5 - 0.5) * 1 and (1 - 0) * 2, less the gyro bias.
+			want.Acceleration = Point{X: 1, Y: 2}
+			want.AngularVelocity = [3]float64{0.05, 0, 0}
+		}
+		if c != want {
+			t.Errorf("IMU %d: Expected %+v, got %+v", c.IMUID, want, c)
+		}
+		if p := (<-positions).(IMUPosition); !p.Timestamp.Equal(ts) {
+			t.Errorf("IMU %d: Expected a position at %v, got %+v", p.IMUID, ts, p)
+		}
+	}
+	if s := (<-fused).(FusedSample); !s.Timestamp.Equal(ts) {
+		t.Errorf("Expected the fused sample at %v, got %+v", ts, s)
+	}
+
+	if !sys.Unsubscribe(calibrated) || sys.Unsubscribe(calibrated) {
+		t.Error("Expected Unsubscribe to find the channel once")
+	}
+	if _, open := <-calibrated; open {
+		t.Error("Expected the unsubscribed channel to be closed")
+	}
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRunning is returned by Restore while the processing loop is running and not paused.
+var ErrRunning = errors.New("fusion system is running; pause or stop it first")
+
+// State is a checkpoint of the estimator, produced by Snapshot and applied by Restore.
+// All fields are exported so it can be encoded with encoding/gob or encoding/json.
+//
+// Orientation is captured only as the UKF heading. Configuration (calibration,
+// extrinsics, tuning), the point cloud, the output smoother, and the bias drift and motion monitors are not
+// part of the state; a restored system refines against an empty cloud until it refills, and
+// restarts output smoothing from its first frame.
+type State struct {
+	LastTime      time.Time         // latest sample time of a processed frame, if HaveFrame
+	HaveFrame     bool              // whether a frame had been processed, so LastTime is a frame time
+	Filters       []FilterState     // per-IMU position, velocity, bias, and covariances
+	DeadReckoning []float64         // per-IMU seconds since the last good fusion
+	Uncertainties []float64         // per-IMU uncertainty radii from the last frame
+	Alpha         float64           // last fused alpha, the warm start for the next frame, 0 if none
+	LastFused     Vec2              // previous fused position, the reference for gating
+	HasFused      bool              // whether LastFused is valid
+	Stationary    bool              // whether the last frame was stationary
+	Held          Point             // output position held while stationary
+	Stationarity  StationarityState // stationarity detector window
+}
+
+// Snapshot captures the estimator state. It may be called at any time; a frame in progress
+// completes first.
+func (sys *IMUFusionSystem) Snapshot() State {
+	sys.frameMu.Lock()
+	defer sys.frameMu.Unlock()
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+
+	s := State{
+		LastTime:      sys.lastTime,
+		HaveFrame:     sys.haveFrame,
+		Filters:       make([]FilterState, len(sys.filters)),
+		DeadReckoning: append([]float64(nil), sys.deadReckoning...),
+		Uncertainties: append([]float64(nil), sys.uncertainties...),
+		Alpha:         sys.tracker.lastAlpha,
+		LastFused:     sys.lastFused,
+		HasFused:      sys.hasFused,
+		Stationary:    sys.stationary,
+		Held:          sys.held,
+		Stationarity:  sys.stationarity.state(),
+	}
+	for i, f := range sys.filters {
+		s.Filters[i] = f.State()
+	}
+	return s
+}
+
+// Restore replaces the estimator state with s, which must come from a system with the same
+// IMU count, stationarity window, and FilterKind. It returns ErrRunning unless the system is paused,
+// stopped, or not yet started. A rejected state leaves the system unchanged. Frames buffered while
+// paused are discarded, since they precede the restored checkpoint.
+func (sys *IMUFusionSystem) Restore(s State) error {
+	sys.frameMu.Lock()
+	defer sys.frameMu.Unlock()
+	if sys.isRunning() {
+		return ErrRunning
+	}
+	if len(s.Filters) != len(sys.filters) || len(s.DeadReckoning) != len(sys.filters) || len(s.Uncertainties) != len(sys.filters) {
+		return fmt.Errorf("state has %d filters, system has %d IMUs", len(s.Filters), len(sys.filters))
+	}
+
+	sys.filterMu.Lock()
+	defer sys.filterMu.Unlock()
+	// Validate everything before applying anything, so a rejected state leaves the system intact.
+	if err := sys.stationarity.checkState(s.Stationarity); err != nil {
+		return err
+	}
+	for i, f := range sys.filters {
+		if err := checkFilterState(f.State().Kind, s.Filters[i]); err != nil {
+			return fmt.Errorf("filter %d: %w", i, err)
+		}
+	}
+	if err := sys.stationarity.setState(s.Stationarity); err != nil {
+		return err
+	}
+	for i, f := range sys.filters {
+		if err := f.SetState(s.Filters[i]); err != nil {
+			return err
+		}
+	}
+	// Frames buffered while paused predate the checkpoint.
+	sys.pending = nil
+	if sys.averager != nil {
+		sys.averager.reset()
+	}
+	sys.lastTime = s.LastTime
+	copy(sys.deadReckoning, s.DeadReckoning)
+	copy(sys.uncertainties, s.Uncertainties)
+	sys.haveFrame = s.HaveFrame
+	sys.tracker.lastAlpha = s.Alpha
+	sys.lastFused = s.LastFused
+	sys.hasFused = s.HasFused
+	sys.stationary = s.Stationary
+	sys.held = s.Held
+	if sys.smoother != nil {
+		sys.smoother.Reset()
+	}
+	for _, f := range sys.accelFilters {
+		f.Reset()
+	}
+	sys.drift.Reset()
+	sys.motion.Reset()
+	sys.resetGroups(nil)
+	sys.haveBodyRate = false
+	return nil
+}
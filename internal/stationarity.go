@@ -0,0 +1,127 @@
+package internal
+
+import "fmt"
+
+// StationarityDetector decides whether the rigid body is at rest from the variance of
+// acceleration and angular velocity over a sliding window of frames.
+type StationarityDetector struct {
+	window     int
+	accelLimit float64 // summed per-axis acceleration variance threshold, (m/s^2)^2
+	gyroLimit  float64 // summed per-axis angular velocity variance threshold, (rad/s)^2
+
+	accel [][3]float64 // ring buffer of frame-mean accelerations
+	gyro  [][3]float64 // ring buffer of frame-mean angular velocities
+	next  int
+	full  bool
+}
+
+// NewStationarityDetector creates a detector over the given number of frames.
+// A window <= 0 disables detection.
+func NewStationarityDetector(window int, accelLimit, gyroLimit float64) *StationarityDetector {
+	if window < 0 {
+		window = 0
+	}
+	return &StationarityDetector{
+		window:     window,
+		accelLimit: accelLimit,
+		gyroLimit:  gyroLimit,
+		accel:      make([][3]float64, window),
+		gyro:       make([][3]float64, window),
+	}
+}
+
+// Update adds a frame, averaging its samples across IMUs, and reports whether the body is stationary.
+// The detector reports motion until the window has filled.
+func (d *StationarityDetector) Update(frame []IMUData) bool {
+	if d.window == 0 || len(frame) == 0 {
+		return false
+	}
+	var accel, gyro [3]float64
+	for _, data := range frame {
+		for i := 0; i < 3; i++ {
+			accel[i] += data.Acceleration[i]
+			gyro[i] += data.AngularVelocity[i]
+		}
+	}
+	n := float64(len(frame))
+	for i := 0; i < 3; i++ {
+		accel[i] /= n
+		gyro[i] /= n
+	}
+
+	d.accel[d.next] = accel
+	d.gyro[d.next] = gyro
+	d.next = (d.next + 1) % d.window
+	if d.next == 0 {
+		d.full = true
+	}
+	return d.IsStationary()
+}
+
+// IsStationary reports whether the variance over the current window is below both limits.
+func (d *StationarityDetector) IsStationary() bool {
+	if d.window == 0 || !d.full {
+		return false
+	}
+	return windowVariance(d.accel) < d.accelLimit && windowVariance(d.gyro) < d.gyroLimit
+}
+
+// Reset discards the window.
+func (d *StationarityDetector) Reset() {
+	d.next = 0
+	d.full = false
+}
+
+// StationarityState is a serializable copy of a StationarityDetector's window.
+type StationarityState struct {
+	Accel [][3]float64
+	Gyro  [][3]float64
+	Next  int
+	Full  bool
+}
+
+// state returns a copy of the detector window.
+func (d *StationarityDetector) state() StationarityState {
+	return StationarityState{
+		Accel: append([][3]float64(nil), d.accel...),
+		Gyro:  append([][3]float64(nil), d.gyro...),
+		Next:  d.next,
+		Full:  d.full,
+	}
+}
+
+// checkState reports why s does not fit the detector window, or nil.
+func (d *StationarityDetector) checkState(s StationarityState) error {
+	if len(s.Accel) != d.window || len(s.Gyro) != d.window || s.Next < 0 || (d.window > 0 && s.Next >= d.window) {
+		return fmt.Errorf("StationarityDetector: state window %d does not match detector window %d", len(s.Accel), d.window)
+	}
+	return nil
+}
+
+// setState overwrites the detector window, which must have the detector's length.
+func (d *StationarityDetector) setState(s StationarityState) error {
+	if err := d.checkState(s); err != nil {
+		return err
+	}
+	copy(d.accel, s.Accel)
+	copy(d.gyro, s.Gyro)
+	d.next = s.Next
+	d.full = s.Full
+	return nil
+}
+
+// windowVariance returns the sum of the per-axis variances of samples.
+func windowVariance(samples [][3]float64) float64 {
+	n := float64(len(samples))
+	var total float64
+	for i := 0; i < 3; i++ {
+		var sum, sumSq float64
+		for _, s := range samples {
+			sum += s[i]
+			sumSq += s[i] * s[i]
+		}
+		mean := sum / n
+		total += sumSq/n - mean*mean
+	}
+	return total
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func noisyFrame(rng *rand.Rand, imuCount int, ts time.Time, noise float64, accelX float64) []IMUData {
+	frame := make([]IMUData, imuCount)
+	for id := range frame {
+		frame[id] = IMUData{
+			IMUID:           id,
+			DeviceTimestamp: ts,
+			Acceleration:    [3]float64{accelX + noise*rng.NormFloat64(), noise * rng.NormFloat64(), 9.81},
+			AngularVelocity: [3]float64{0, 0, noise * rng.NormFloat64()},
+		}
+	}
+	return frame
+}
+
+func TestStationarityDetector(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d := NewStationarityDetector(20, 0.05, 0.01)
+	ts := time.Unix(1, 0)
+
+	for i := 0; i < 19; i++ {
+		if d.Update(noisyFrame(rng, 2, ts, 0.05, 0)) {
+			t.Fatalf("frame %d: expected no decision before the window fills", i)
+		}
+	}
+	if !d.Update(noisyFrame(rng, 2, ts, 0.05, 0)) {
+		t.Error("Expected low-noise static input to be stationary")
+	}
+
+	// Vigorous shaking is motion.
+	for i := 0; i < 20; i++ {
+		d.Update(noisyFrame(rng, 2, ts, 0, float64(i%2)*2))
+	}
+	if d.IsStationary() {
+		t.Error("Expected alternating acceleration to be detected as motion")
+	}
+
+	if NewStationarityDetector(0, 1, 1).Update(noisyFrame(rng, 2, ts, 0, 0)) {
+		t.Error("Expected a disabled detector never to report stationary")
+	}
+}
+
+func TestIMUFusionSystemFreezesWhenStationary(t *testing.T) {
+	const imuCount = 2
+	sys, err := NewIMUFusionSystem(imuCount)
+	if err != nil {
+		t.Fatalf("NewIMUFusionSystem failed: %v", err)
+	}
+	sys.SetStationarityDetection(20, 0.05, 0.01)
+	var outputs []FusedSample
+	sys.output = func(sample FusedSample) { outputs = append(outputs, sample) }
+
+	rng := rand.New(rand.NewSource(2))
+	base := time.Unix(1, 0)
+	sys.lastTime = base
+	for step := 1; step <= 200; step++ {
+		sys.processFrame(noisyFrame(rng, imuCount, base.Add(time.Duration(step)*time.Millisecond), 0.05, 0))
+	}
+
+	if !sys.IsStationary() {
+		t.Fatal("Expected noisy static input to be detected as stationary")
+	}
+	held := outputs[len(outputs)-1]
+	for _, sample := range outputs[len(outputs)-100:] {
+		if sample.X != held.X || sample.Y != held.Y {
+			t.Fatalf("Expected output frozen at (%f, %f), got (%f, %f)", held.X, held.Y, sample.X, sample.Y)
+		}
+	}
+	for id := 0; id < imuCount; id++ {
+		v := sys.filters[id].Velocity()
+		if !floatsClose(v[0], 0, 1e-2) || !floatsClose(v[1], 0, 1e-2) {
+			t.Errorf("IMU %d: expected velocity near zero while stationary, got %v", id, v)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -10,44 +12,324 @@ import (
 type Synchronizer struct {
 	mu      sync.Mutex
 	dataMap map[time.Time][]IMUData
+
+	periods map[int]time.Duration // expected sample period per IMU, set via SetRate
+	fastest time.Duration         // shortest configured period, 0 if none
+	held    map[int][]IMUData     // pending samples of upsampled IMUs, oldest first
+
+	lateness time.Duration     // maximum age behind the newest sample, 0 to accept everything
+	newest   time.Time         // latest sample time seen
+	rejected uint64            // samples dropped as late
+	invalid  uint64            // samples dropped for NaN or Inf readings
+	missing  map[time.Time]int // per frame, the number of its samples dropped as invalid
+
+	clockOffsets map[int]time.Duration   // per-IMU clock offset subtracted from sample times, see SetClockOffset
+	skew         map[int]*skewController // per-IMU drift tracking, see SetSkewCorrection
+	reference    time.Time               // latest sample time of an IMU without skew correction
+
+	disabled  map[int]bool      // IMUs excluded from frames, see SetEnabled
+	enabledAt map[int]time.Time // newest sample time when a disabled IMU was re-enabled
+
+	health *HealthMonitor // sees every sample of enabled IMUs, including rejected ones
+	clock  Clock          // time samples are observed by health
 }
 
+// defaultStaleTimeout is how long an IMU may go without a sample before it is reported stale.
+const defaultStaleTimeout = 100 * time.Millisecond
+
 // NewSynchronizer creates a new instance of Synchronizer.
 func NewSynchronizer() *Synchronizer {
 	return &Synchronizer{
-		dataMap: make(map[time.Time][]IMUData),
+		dataMap:   make(map[time.Time][]IMUData),
+		periods:   make(map[int]time.Duration),
+		held:      make(map[int][]IMUData),
+		disabled:  make(map[int]bool),
+		enabledAt: make(map[int]time.Time),
+		missing:   make(map[time.Time]int),
+
+		clockOffsets: make(map[int]time.Duration),
+		skew:         make(map[int]*skewController),
+		health:       NewHealthMonitor(defaultStaleTimeout),
+		clock:        RealClock{},
+	}
+}
+
+// SetClock sets the clock used to time samples without a receive timestamp for health monitoring.
+func (s *Synchronizer) SetClock(clock Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock
+}
+
+// Health returns the monitor tracking the health of each IMU from the samples passed to AddData.
+func (s *Synchronizer) Health() *HealthMonitor {
+	return s.health
+}
+
+// SetEnabled includes or excludes an IMU from frames. While disabled, its samples are refused
+// by AddData, any it has pending are discarded, and GetAlignedData expects one sample fewer per frame.
+// Once re-enabled it is expected only in frames later than the newest sample seen at that point,
+// so frames buffered while it was disabled still complete without it.
+func (s *Synchronizer) SetEnabled(imuID int, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if enabled {
+		if s.disabled[imuID] {
+			delete(s.disabled, imuID)
+			s.enabledAt[imuID] = s.newest
+		}
+		return
 	}
+	s.disabled[imuID] = true
+	delete(s.enabledAt, imuID)
+	delete(s.held, imuID)
+	for ts, data := range s.dataMap {
+		kept := data[:0]
+		for _, d := range data {
+			if d.IMUID != imuID {
+				kept = append(kept, d)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.dataMap, ts)
+		} else {
+			s.dataMap[ts] = kept
+		}
+	}
+}
+
+// SetClockOffset corrects for an IMU whose clock runs offset ahead of the common clock, as
+// estimated by EstimateClockOffset: AddData subtracts it from the sample time of each of its
+// samples, restamping DeviceTimestamp. Frames align on exact equality, so the corrected times
+// must land on those of the other IMUs, for example by rounding the estimate to the sample
+// period. It should be called before data is added.
+func (s *Synchronizer) SetClockOffset(imuID int, offset time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset == 0 {
+		delete(s.clockOffsets, imuID)
+		return
+	}
+	s.clockOffsets[imuID] = offset
+}
+
+// SetSkewCorrection makes AddData track a slowly drifting clock on an IMU sampling every
+// period, on top of any fixed SetClockOffset. Each of its samples is snapped to the nearest
+// frame time on the period grid of the IMUs without skew correction, and the residual between
+// the two drives a PI controller with gains kp and ki whose output is the skew subtracted from
+// the next sample, so the skew follows the drift and the residual stays well inside half a
+// period. At least one IMU must be left without skew correction to define the frame times. A
+// period <= 0 removes the controller. It should be called before data is added.
+func (s *Synchronizer) SetSkewCorrection(imuID int, period time.Duration, kp, ki float64) error {
+	if kp < 0 || ki < 0 || math.IsNaN(kp) || math.IsNaN(ki) || math.IsInf(kp, 0) || math.IsInf(ki, 0) {
+		return fmt.Errorf("skew controller gains must be non-negative and finite, got kp=%f ki=%f", kp, ki)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if period <= 0 {
+		delete(s.skew, imuID)
+		return nil
+	}
+	s.skew[imuID] = &skewController{period: period, kp: kp, ki: ki}
+	return nil
+}
+
+// Skew returns the skew currently subtracted from the sample times of an IMU by its skew
+// controller, or 0 if it has none; see SetSkewCorrection.
+func (s *Synchronizer) Skew(imuID int) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.skew[imuID]; ok {
+		return time.Duration(c.output)
+	}
+	return 0
+}
+
+// skewController is a PI controller estimating the drift of an IMU's clock from the residual
+// between its corrected sample times and the frame grid.
+type skewController struct {
+	period   time.Duration
+	kp, ki   float64
+	integral float64 // sum of residuals, in nanoseconds
+	output   float64 // skew subtracted from sample times, in nanoseconds
+}
+
+// correct returns the frame time nearest t less the current skew, on the period grid through
+// reference, and updates the skew from the residual. Without a reference t is only corrected.
+func (c *skewController) correct(t, reference time.Time) time.Time {
+	corrected := t.Add(-time.Duration(c.output))
+	if reference.IsZero() {
+		return corrected
+	}
+	steps := math.Round(float64(corrected.Sub(reference)) / float64(c.period))
+	frame := reference.Add(time.Duration(steps) * c.period)
+	residual := float64(corrected.Sub(frame))
+	c.integral += residual
+	c.output = c.kp*residual + c.ki*c.integral
+	return frame
+}
+
+// SetRate declares the expected sample rate of an IMU in Hz; a rate <= 0 clears it.
+// Frames are formed at the sample times of the fastest IMUs, and IMUs whose configured rate
+// is lower than the fastest configured rate are upsampled into them by holding their most
+// recent sample. IMUs without a configured rate are assumed to run at the fastest rate.
+// It should be called before data is added.
+func (s *Synchronizer) SetRate(imuID int, hz float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hz <= 0 {
+		delete(s.periods, imuID)
+	} else {
+		s.periods[imuID] = time.Duration(float64(time.Second) / hz)
+	}
+	s.fastest = 0
+	for _, period := range s.periods {
+		if s.fastest == 0 || period < s.fastest {
+			s.fastest = period
+		}
+	}
+}
+
+// upsampled reports whether samples from imuID are held to fill faster frames.
+func (s *Synchronizer) upsampled(imuID int) bool {
+	period, ok := s.periods[imuID]
+	return ok && period > s.fastest
+}
+
+// SetLatenessThreshold makes AddData reject samples whose sample time is more than d older
+// than the newest sample seen, so a sample delayed by buffering cannot revive a frame that
+// has already been processed. A threshold <= 0 accepts every sample.
+func (s *Synchronizer) SetLatenessThreshold(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lateness = d
+}
+
+// Rejected returns the number of samples AddData has rejected as late.
+func (s *Synchronizer) Rejected() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rejected
+}
+
+// Invalid returns the number of samples AddData has rejected for NaN or Inf readings.
+func (s *Synchronizer) Invalid() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.invalid
 }
 
-// AddData adds IMU data to the synchronizer.
-func (s *Synchronizer) AddData(data IMUData) {
+// AddData adds IMU data to the synchronizer, keyed by its sample time, and reports whether it
+// was accepted. The device timestamp is preferred when present so that transport jitter does
+// not split frames. Samples older than the lateness threshold or with NaN or Inf readings are
+// rejected and counted, and samples from disabled IMUs are refused.
+func (s *Synchronizer) AddData(data IMUData) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.dataMap[data.Timestamp] = append(s.dataMap[data.Timestamp], data)
+	if s.disabled[data.IMUID] {
+		return false
+	}
+	if offset, ok := s.clockOffsets[data.IMUID]; ok {
+		data.DeviceTimestamp = data.SampleTime().Add(-offset)
+	}
+	if c, ok := s.skew[data.IMUID]; ok {
+		data.DeviceTimestamp = c.correct(data.SampleTime(), s.reference)
+	}
+	s.health.Observe(data, s.clock.Now())
+	ts := data.SampleTime()
+	if s.lateness > 0 && ts.Before(s.newest.Add(-s.lateness)) {
+		s.rejected++
+		return false
+	}
+	if !data.Finite() {
+		// A single bad read would otherwise poison the filters permanently. Its frame is
+		// completed without it; an upsampled IMU simply keeps holding its previous sample.
+		s.invalid++
+		if !s.upsampled(data.IMUID) {
+			s.missing[ts]++
+			s.dataMap[ts] = s.dataMap[ts]
+		}
+		return false
+	}
+	if ts.After(s.newest) {
+		s.newest = ts
+	}
+	if _, ok := s.skew[data.IMUID]; !ok && ts.After(s.reference) {
+		s.reference = ts
+	}
+	if s.upsampled(data.IMUID) {
+		samples := append(s.held[data.IMUID], data)
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].SampleTime().Before(samples[j].SampleTime())
+		})
+		s.held[data.IMUID] = samples
+		return true
+	}
+	s.dataMap[ts] = append(s.dataMap[ts], data)
+	return true
+}
+
+// Pending returns the number of samples held for frames that have not yet been returned.
+func (s *Synchronizer) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, data := range s.dataMap {
+		n += len(data)
+	}
+	for _, samples := range s.held {
+		n += len(samples)
+	}
+	return n
 }
 
 // GetSynchronizedData retrieves synchronized IMU data.
+//
+// Deprecated: the returned map is the live buffer, which AddData and GetAlignedData keep
+// modifying after the lock is released. Use SnapshotData instead.
 func (s *Synchronizer) GetSynchronizedData() map[time.Time][]IMUData {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.dataMap
 }
 
+// SnapshotData returns a copy of the buffered samples not yet emitted as aligned frames, keyed
+// by sample time, that is safe to read while samples keep arriving.
+func (s *Synchronizer) SnapshotData() map[time.Time][]IMUData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make(map[time.Time][]IMUData, len(s.dataMap))
+	for ts, samples := range s.dataMap {
+		snapshot[ts] = append([]IMUData(nil), samples...)
+	}
+	return snapshot
+}
+
 // ClearData clears the stored IMU data.
 func (s *Synchronizer) ClearData() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.dataMap = make(map[time.Time][]IMUData)
+	s.held = make(map[int][]IMUData)
+	s.enabledAt = make(map[int]time.Time)
+	s.missing = make(map[time.Time]int)
 }
 
 // GetAlignedData returns a slice of IMUData slices, each containing one data point per IMU for timestamps where all IMUs have data.
 // It processes timestamps chronologically and returns all completed frames up to the first incomplete one.
+// Disabled IMUs (see SetEnabled) are not expected, so frames are complete with imuCount less their number,
+// nor are re-enabled IMUs in frames up to the time they were enabled, unless they delivered a sample for them,
+// nor samples AddData rejected for NaN or Inf readings.
+// Upsampled IMUs (see SetRate) contribute their most recent sample, restamped to the frame time;
+// frames earlier than the first sample of an upsampled IMU are discarded.
 func (s *Synchronizer) GetAlignedData(imuCount int) [][]IMUData {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	aligned := [][]IMUData{}
+	imuCount -= len(s.disabled)
 
 	// Get sorted timestamps
 	timestamps := make([]time.Time, 0, len(s.dataMap))
@@ -60,11 +342,22 @@ func (s *Synchronizer) GetAlignedData(imuCount int) [][]IMUData {
 
 	// Process timestamps in order
 	for _, ts := range timestamps {
-		data := s.dataMap[ts]
-		if len(data) == imuCount {
+		data, ready, stale := s.holdSamples(s.dataMap[ts], ts)
+		if stale {
+			delete(s.dataMap, ts)
+			delete(s.missing, ts)
+			continue
+		}
+		if ready && len(data) == imuCount-s.missing[ts]-s.notYetExpected(data, ts) {
 			// Frame is complete, add it to the result and remove from map
 			aligned = append(aligned, data)
 			delete(s.dataMap, ts)
+			delete(s.missing, ts)
+			for id, at := range s.enabledAt {
+				if ts.After(at) {
+					delete(s.enabledAt, id)
+				}
+			}
 		} else {
 			// Found an incomplete frame, stop processing further timestamps
 			break
@@ -73,3 +366,185 @@ func (s *Synchronizer) GetAlignedData(imuCount int) [][]IMUData {
 
 	return aligned
 }
+
+// notYetExpected counts the re-enabled IMUs that are missing from the frame at ts because it
+// was buffered before they were enabled.
+func (s *Synchronizer) notYetExpected(data []IMUData, ts time.Time) int {
+	n := 0
+	for id, at := range s.enabledAt {
+		if ts.After(at) {
+			continue
+		}
+		missing := true
+		for _, d := range data {
+			if d.IMUID == id {
+				missing = false
+				break
+			}
+		}
+		if missing {
+			n++
+		}
+	}
+	return n
+}
+
+// holdSamples extends data with the most recent sample at or before ts of each upsampled IMU,
+// restamped to ts. ready is false while an upsampled IMU may still deliver a sample for ts;
+// stale is true if ts precedes the first sample of an upsampled IMU, so it can never be filled.
+func (s *Synchronizer) holdSamples(data []IMUData, ts time.Time) (frame []IMUData, ready, stale bool) {
+	ids := make([]int, 0, len(s.periods))
+	for id := range s.periods {
+		if s.upsampled(id) && !s.disabled[id] {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return data, true, false
+	}
+	sort.Ints(ids)
+
+	frame = append([]IMUData(nil), data...)
+	for _, id := range ids {
+		samples := s.held[id]
+		k := sort.Search(len(samples), func(i int) bool {
+			return samples[i].SampleTime().After(ts)
+		}) - 1
+		if k < 0 {
+			return nil, false, len(samples) > 0
+		}
+		// Without a later sample, hold only within one period of the last one.
+		if k == len(samples)-1 && ts.Sub(samples[k].SampleTime()) >= s.periods[id] {
+			return nil, false, false
+		}
+		// Frames are processed in order, so older samples are no longer needed.
+		s.held[id] = samples[k:]
+
+		held := samples[k]
+		held.DeviceTimestamp = ts
+		frame = append(frame, held)
+	}
+	return frame, true, false
+}
+
+// EstimateClockOffset estimates how far b's clock runs ahead of a's from two recordings of the
+// same motion, such as two IMUs on one rigid body: an event a stamps at t, b stamps at about
+// t + offset. It cross-correlates the acceleration magnitudes, which do not depend on how each
+// IMU is mounted, after resampling both at a's median sample period, and refines the best lag
+// to a fraction of a period by fitting a parabola through the correlation peak. Offsets of up
+// to half of a's duration are searched. It returns 0 if the recordings are too short or do not
+// overlap enough to compare.
+func EstimateClockOffset(a, b []IMUData) time.Duration {
+	ta, va := motionSignal(a)
+	tb, vb := motionSignal(b)
+	if len(ta) < 4 || len(tb) < 2 {
+		fmt.Println("EstimateClockOffset: Warning - not enough samples to estimate an offset.")
+		return 0
+	}
+	periods := make([]float64, len(ta)-1)
+	for i := range periods {
+		periods[i] = ta[i+1] - ta[i]
+	}
+	sort.Float64s(periods)
+	step := periods[len(periods)/2]
+	if step <= 0 {
+		fmt.Println("EstimateClockOffset: Warning - samples of a share one timestamp.")
+		return 0
+	}
+
+	// Resample a on a uniform grid, then compare it with b shifted by each lag.
+	n := int((ta[len(ta)-1]-ta[0])/step) + 1
+	grid := make([]float64, n)
+	for i := range grid {
+		grid[i], _ = interpolate(ta, va, ta[0]+float64(i)*step)
+	}
+	maxLag := n / 2
+	minOverlap := n / 4
+	if minOverlap < 2 {
+		minOverlap = 2
+	}
+	correlations := make([]float64, 2*maxLag+1)
+	best := -1
+	for k := range correlations {
+		lag := float64(k-maxLag) * step
+		var xs, ys []float64
+		for i, x := range grid {
+			if y, ok := interpolate(tb, vb, ta[0]+float64(i)*step+lag); ok {
+				xs = append(xs, x)
+				ys = append(ys, y)
+			}
+		}
+		correlations[k] = math.NaN()
+		if len(xs) < minOverlap {
+			continue
+		}
+		correlations[k] = pearson(xs, ys)
+		if best < 0 || correlations[k] > correlations[best] {
+			best = k
+		}
+	}
+	if best < 0 || math.IsNaN(correlations[best]) {
+		fmt.Println("EstimateClockOffset: Warning - recordings do not overlap enough to compare.")
+		return 0
+	}
+
+	shift := float64(best - maxLag)
+	if best > 0 && best < len(correlations)-1 {
+		left, mid, right := correlations[best-1], correlations[best], correlations[best+1]
+		if denom := left - 2*mid + right; !math.IsNaN(denom) && denom < 0 {
+			shift += (left - right) / (2 * denom)
+		}
+	}
+	return time.Duration(shift * step * float64(time.Second))
+}
+
+// motionSignal returns the sample times of data in seconds, sorted, and its acceleration magnitudes.
+func motionSignal(data []IMUData) ([]float64, []float64) {
+	sorted := append([]IMUData(nil), data...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SampleTime().Before(sorted[j].SampleTime())
+	})
+	times := make([]float64, len(sorted))
+	values := make([]float64, len(sorted))
+	for i, d := range sorted {
+		times[i] = float64(d.SampleTime().UnixNano()) / float64(time.Second)
+		a := d.Acceleration
+		values[i] = math.Sqrt(a[0]*a[0] + a[1]*a[1] + a[2]*a[2])
+	}
+	return times, values
+}
+
+// interpolate linearly interpolates values, sampled at the sorted times, at t. ok is false
+// outside the sampled range.
+func interpolate(times, values []float64, t float64) (float64, bool) {
+	if len(times) == 0 || t < times[0] || t > times[len(times)-1] {
+		return 0, false
+	}
+	k := sort.SearchFloat64s(times, t)
+	if times[k] == t {
+		return values[k], true
+	}
+	f := (t - times[k-1]) / (times[k] - times[k-1])
+	return values[k-1] + f*(values[k]-values[k-1]), true
+}
+
+// pearson returns the correlation coefficient of xs and ys, or 0 if either is constant.
+func pearson(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i] / n
+		my += ys[i] / n
+	}
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx <= 0 || syy <= 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
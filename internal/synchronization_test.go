@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"math"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 )
@@ -135,3 +137,325 @@ func TestSynchronizer_ClearData(t *testing.T) {
 		t.Errorf("Expected 0 aligned frames after clear, got %d", len(aligned))
 	}
 }
+
+func TestSynchronizer_PrefersDeviceTimestamp(t *testing.T) {
+	sync := NewSynchronizer()
+	imuCount := 2
+
+	device := time.Unix(0, 1_000_000_123)
+	received := time.Now()
+
+	// Both samples were taken at the same device instant but arrived with transport jitter.
+	sample0 := IMUData{IMUID: 0, Timestamp: received, DeviceTimestamp: device}
+	sample1 := IMUData{IMUID: 1, Timestamp: received.Add(300 * time.Microsecond), DeviceTimestamp: device}
+
+	sync.AddData(sample0)
+	sync.AddData(sample1)
+
+	aligned := sync.GetAlignedData(imuCount)
+	expected := [][]IMUData{{sample0, sample1}}
+	if !framesEqual(aligned, expected) {
+		t.Fatalf("Expected a single frame aligned on the device timestamp %v, got %v", expected, aligned)
+	}
+	if got := aligned[0][0].SampleTime(); !got.Equal(device) {
+		t.Errorf("Expected frame sample time %v, got %v", device, got)
+	}
+}
+
+func TestSynchronizerUpsamplesSlowIMU(t *testing.T) {
+	sync := NewSynchronizer()
+	sync.SetRate(0, 1000)
+	sync.SetRate(1, 100)
+
+	start := time.Unix(0, 0)
+	for i := 0; i < 50; i++ {
+		ts := start.Add(time.Duration(i) * time.Millisecond)
+		sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: ts, Acceleration: [3]float64{float64(i)}})
+		if i%10 == 0 {
+			sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: ts, Acceleration: [3]float64{float64(i)}})
+		}
+	}
+
+	frames := sync.GetAlignedData(2)
+	if len(frames) != 50 {
+		t.Fatalf("Expected 50 frames at 1000Hz, got %d", len(frames))
+	}
+	for i, frame := range frames {
+		if len(frame) != 2 {
+			t.Fatalf("Expected 2 samples in frame %d, got %d", i, len(frame))
+		}
+		sortFrame(frame)
+		want := start.Add(time.Duration(i) * time.Millisecond)
+		for _, data := range frame {
+			if !data.SampleTime().Equal(want) {
+				t.Errorf("Expected IMU %d sample time %v in frame %d, got %v", data.IMUID, want, i, data.SampleTime())
+			}
+		}
+		if held := frame[1].Acceleration[0]; held != float64(i/10*10) {
+			t.Errorf("Expected frame %d to hold slow sample %d, got %v", i, i/10*10, held)
+		}
+	}
+}
+
+func TestSynchronizerWaitsForNextSlowSample(t *testing.T) {
+	sync := NewSynchronizer()
+	sync.SetRate(0, 1000)
+	sync.SetRate(1, 100)
+
+	start := time.Unix(0, 0)
+	sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start})
+	for i := 0; i <= 10; i++ {
+		sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start.Add(time.Duration(i) * time.Millisecond)})
+	}
+
+	if frames := sync.GetAlignedData(2); len(frames) != 10 {
+		t.Fatalf("Expected 10 frames before the next slow sample is due, got %d", len(frames))
+	}
+	sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start.Add(10 * time.Millisecond)})
+	if frames := sync.GetAlignedData(2); len(frames) != 1 {
+		t.Errorf("Expected the pending frame once the slow sample arrives, got %d", len(frames))
+	}
+}
+
+func TestSynchronizerRejectsLateData(t *testing.T) {
+	sync := NewSynchronizer()
+	sync.SetLatenessThreshold(5 * time.Millisecond)
+
+	start := time.Unix(0, 0)
+	for i := 0; i < 10; i++ {
+		ts := start.Add(time.Duration(i) * time.Millisecond)
+		if !sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: ts}) {
+			t.Fatalf("Expected in-order sample %d to be accepted", i)
+		}
+	}
+	if frames := sync.GetAlignedData(1); len(frames) != 10 {
+		t.Fatalf("Expected 10 frames, got %d", len(frames))
+	}
+
+	// A sample from the already-processed window arrives late.
+	if sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start.Add(2 * time.Millisecond)}) {
+		t.Error("Expected stale sample to be rejected")
+	}
+	if got := sync.Rejected(); got != 1 {
+		t.Errorf("Expected 1 rejected sample, got %d", got)
+	}
+	if frames := sync.GetAlignedData(1); len(frames) != 0 {
+		t.Errorf("Expected no revived frames, got %d", len(frames))
+	}
+
+	// Slightly out-of-order samples within the threshold are still accepted.
+	if !sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start.Add(7 * time.Millisecond)}) {
+		t.Error("Expected sample within the threshold to be accepted")
+	}
+}
+
+func TestSynchronizerDisabledIMU(t *testing.T) {
+	sync := NewSynchronizer()
+	start := time.Unix(0, 0)
+	sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start})
+	sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start})
+
+	// Disabling IMU 1 discards its pending sample and completes the frame without it.
+	sync.SetEnabled(1, false)
+	frames := sync.GetAlignedData(2)
+	if len(frames) != 1 || len(frames[0]) != 1 || frames[0][0].IMUID != 0 {
+		t.Fatalf("Expected one frame with only IMU 0, got %v", frames)
+	}
+	if sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start.Add(time.Millisecond)}) {
+		t.Error("Expected samples from a disabled IMU to be refused")
+	}
+
+	sync.SetEnabled(1, true)
+	sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start.Add(2 * time.Millisecond)})
+	if frames := sync.GetAlignedData(2); len(frames) != 0 {
+		t.Errorf("Expected re-enabled IMU to be waited for again, got %d frames", len(frames))
+	}
+	sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start.Add(2 * time.Millisecond)})
+	if frames := sync.GetAlignedData(2); len(frames) != 1 || len(frames[0]) != 2 {
+		t.Errorf("Expected one frame with both IMUs, got %v", frames)
+	}
+}
+
+func TestSynchronizerReenabledIMUDoesNotStallBufferedFrames(t *testing.T) {
+	sync := NewSynchronizer()
+	start := time.Unix(0, 0)
+	sync.SetEnabled(3, false)
+	for id := 0; id < 3; id++ {
+		sync.AddData(IMUData{IMUID: id, DeviceTimestamp: start})
+	}
+
+	// The frame at start was buffered while IMU 3 was disabled and must not wait for it.
+	sync.SetEnabled(3, true)
+	for i := 1; i <= 8; i++ {
+		for id := 0; id < 4; id++ {
+			sync.AddData(IMUData{IMUID: id, DeviceTimestamp: start.Add(time.Duration(i) * time.Millisecond)})
+		}
+	}
+
+	frames := sync.GetAlignedData(4)
+	if len(frames) != 9 {
+		t.Fatalf("Expected 9 frames, got %d", len(frames))
+	}
+	if len(frames[0]) != 3 {
+		t.Errorf("Expected the buffered frame without IMU 3, got %d samples", len(frames[0]))
+	}
+	for i, frame := range frames[1:] {
+		if len(frame) != 4 {
+			t.Errorf("frame %d: Expected 4 samples, got %d", i+1, len(frame))
+		}
+	}
+}
+
+func TestSynchronizerRejectsNonFiniteData(t *testing.T) {
+	sync := NewSynchronizer()
+	start := time.Unix(0, 0)
+	bad := []IMUData{
+		{IMUID: 0, DeviceTimestamp: start, Acceleration: [3]float64{math.NaN(), 0, 0}},
+		{IMUID: 2, DeviceTimestamp: start, AngularVelocity: [3]float64{0, 0, math.Inf(1)}},
+	}
+	for i, d := range bad {
+		if sync.AddData(d) {
+			t.Errorf("sample %d: Expected non-finite sample to be rejected", i)
+		}
+	}
+	if got := sync.Invalid(); got != 2 {
+		t.Errorf("Expected 2 invalid samples, got %d", got)
+	}
+
+	// The frame completes without the rejected samples rather than waiting for them.
+	if !sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start}) {
+		t.Error("Expected finite sample to be accepted")
+	}
+	frames := sync.GetAlignedData(3)
+	if len(frames) != 1 || len(frames[0]) != 1 || frames[0][0].IMUID != 1 {
+		t.Errorf("Expected one frame with only IMU 1, got %v", frames)
+	}
+}
+
+func TestEstimateClockOffset(t *testing.T) {
+	// A non-periodic motion, so that only one lag lines the recordings up.
+	motion := func(t float64) float64 {
+		return 9.81 + math.Sin(2.1*t) + 0.5*math.Sin(5.3*t+1) + 2*math.Exp(-(t-4)*(t-4))
+	}
+	const offset = 137 * time.Millisecond
+	start := time.Unix(100, 0)
+	var a, b []IMUData
+	for i := 0; i < 1000; i++ {
+		ta := start.Add(time.Duration(i) * 10 * time.Millisecond)
+		a = append(a, IMUData{IMUID: 0, DeviceTimestamp: ta, Acceleration: [3]float64{0, 0, motion(ta.Sub(start).Seconds())}})
+
+		// b samples at its own phase, and its clock runs offset ahead; it is also mounted on its
+		// side, which the acceleration magnitude does not see.
+		tb := start.Add(time.Duration(i)*10*time.Millisecond + 3*time.Millisecond)
+		b = append(b, IMUData{IMUID: 1, DeviceTimestamp: tb.Add(offset), Acceleration: [3]float64{motion(tb.Sub(start).Seconds()), 0, 0}})
+	}
+
+	if got := EstimateClockOffset(a, b); got < offset-time.Millisecond || got > offset+time.Millisecond {
+		t.Errorf("Expected offset %v, got %v", offset, got)
+	}
+	if got := EstimateClockOffset(b, a); got > -offset+time.Millisecond || got < -offset-time.Millisecond {
+		t.Errorf("Expected offset %v with the recordings swapped, got %v", -offset, got)
+	}
+	if got := EstimateClockOffset(a[:2], b); got != 0 {
+		t.Errorf("Expected 0 for too short a recording, got %v", got)
+	}
+}
+
+func TestSynchronizerClockOffset(t *testing.T) {
+	sync := NewSynchronizer()
+	start := time.Unix(0, 0)
+	sync.SetClockOffset(1, 140*time.Millisecond)
+	sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: start})
+	sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: start.Add(140 * time.Millisecond)})
+
+	frames := sync.GetAlignedData(2)
+	if len(frames) != 1 || len(frames[0]) != 2 {
+		t.Fatalf("Expected one frame with both IMUs, got %v", frames)
+	}
+	for _, d := range frames[0] {
+		if !d.DeviceTimestamp.Equal(start) {
+			t.Errorf("IMU %d: Expected corrected timestamp %v, got %v", d.IMUID, start, d.DeviceTimestamp)
+		}
+	}
+}
+
+func TestSynchronizerSkewCorrectionTracksDrift(t *testing.T) {
+	// IMU 1's clock starts 200µs ahead and gains 100ns per 1ms sample, drifting past half a
+	// period after 3000 samples.
+	const (
+		period  = time.Millisecond
+		samples = 5000
+	)
+	drift := func(k int) time.Duration { return 200*time.Microsecond + time.Duration(k)*100*time.Nanosecond }
+	sync := NewSynchronizer()
+	if err := sync.SetSkewCorrection(1, period, 0.2, 0.05); err != nil {
+		t.Fatal(err)
+	}
+	if err := sync.SetSkewCorrection(1, period, -1, 0); err == nil {
+		t.Error("Expected an error for a negative gain")
+	}
+
+	start := time.Unix(0, 0)
+	formed := 0
+	for k := 0; k < samples; k++ {
+		frameTime := start.Add(time.Duration(k) * period)
+		sync.AddData(IMUData{IMUID: 0, DeviceTimestamp: frameTime})
+		sync.AddData(IMUData{IMUID: 1, DeviceTimestamp: frameTime.Add(drift(k))})
+		for _, frame := range sync.GetAlignedData(2) {
+			if len(frame) != 2 || !frame[0].DeviceTimestamp.Equal(frame[1].DeviceTimestamp) {
+				t.Fatalf("Expected aligned frames of both IMUs, got %v", frame)
+			}
+			formed++
+		}
+	}
+	if formed != samples {
+		t.Errorf("Expected %d frames, got %d", samples, formed)
+	}
+	if got, want := sync.Skew(1), drift(samples-1); got < want-5*time.Microsecond || got > want+5*time.Microsecond {
+		t.Errorf("Expected skew near %v, got %v", want, got)
+	}
+	if got := sync.Skew(0); got != 0 {
+		t.Errorf("Expected no skew for the reference IMU, got %v", got)
+	}
+}
+
+func TestSynchronizerSnapshotDataWhileAdding(t *testing.T) {
+	s := NewSynchronizer()
+	base := time.Unix(1, 0)
+	const samples = 500
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < samples; i++ {
+			s.AddData(IMUData{IMUID: i % 2, DeviceTimestamp: base.Add(time.Duration(i/2) * time.Millisecond)})
+		}
+	}()
+
+	// Reading and modifying the copies must not race the writer.
+	for done := false; !done; {
+		snapshot := s.SnapshotData()
+		for ts, frame := range snapshot {
+			for i := range frame {
+				if !frame[i].SampleTime().Equal(ts) {
+					t.Fatalf("Expected sample at %v, got %v", ts, frame[i].SampleTime())
+				}
+				frame[i].Acceleration[0] = 1
+			}
+		}
+		done = len(snapshot) == samples/2
+	}
+	wg.Wait()
+
+	for ts, frame := range s.SnapshotData() {
+		if len(frame) != 2 {
+			t.Errorf("Expected 2 samples at %v, got %d", ts, len(frame))
+		}
+		for _, data := range frame {
+			if data.Acceleration[0] != 0 {
+				t.Fatalf("Expected the buffer to be unaffected by changes to a snapshot, got %v", data.Acceleration)
+			}
+		}
+	}
+}
@@ -1,15 +1,86 @@
 package internal
 
 import (
+	"math"
 	"time"
 )
 
 // IMUData represents the data structure for storing IMU measurements.
 type IMUData struct {
-	IMUID           int // ID of the originating IMU
-	Timestamp       time.Time
-	Acceleration    [3]float64 // x, y, z acceleration
-	AngularVelocity [3]float64 // roll, pitch, yaw
+	IMUID           int        // ID of the originating IMU
+	Timestamp       time.Time  // Host receive time, stamped by DataAcquisition
+	Acceleration    [3]float64 // x, y, z acceleration, in m/s^2 for positions in meters (see SetUnitScale)
+	AngularVelocity [3]float64 // roll, pitch, yaw rates in rad/s once ingested by DataAcquisition
+
+	// DeviceTimestamp is the optional sampling time reported by the IMU hardware.
+	// It should be populated at nanosecond precision from the device clock, and
+	// samples from different IMUs belonging to the same frame must carry identical
+	// values, since the Synchronizer aligns frames on exact timestamp equality.
+	// The zero value means the source did not provide one.
+	DeviceTimestamp time.Time
+
+	// Quality is the source's confidence in the sample, for example below 1 for a saturated or
+	// interpolated reading. Fusion divides the IMU's uncertainty radius by it, so a sample of
+	// quality 0.5 counts as if its IMU were twice as uncertain. The zero value, like any value
+	// that is not positive and finite, means full quality 1.
+	Quality float64
+}
+
+// SampleTime returns the device timestamp when present, otherwise the receive timestamp.
+func (d IMUData) SampleTime() time.Time {
+	if !d.DeviceTimestamp.IsZero() {
+		return d.DeviceTimestamp
+	}
+	return d.Timestamp
+}
+
+// weight returns the sample's Quality, or 1 if it is unset or invalid.
+func (d IMUData) weight() float64 {
+	if d.Quality > 0 && !math.IsInf(d.Quality, 0) {
+		return d.Quality
+	}
+	return 1
+}
+
+// Finite reports whether the acceleration and angular velocity are free of NaN and Inf.
+func (d IMUData) Finite() bool {
+	for i := 0; i < 3; i++ {
+		if math.IsNaN(d.Acceleration[i]) || math.IsInf(d.Acceleration[i], 0) ||
+			math.IsNaN(d.AngularVelocity[i]) || math.IsInf(d.AngularVelocity[i], 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// AngularUnit is the unit a Source reports angular velocity in.
+type AngularUnit int
+
+const (
+	RadiansPerSecond AngularUnit = iota // the internal unit, and the default
+	DegreesPerSecond
+)
+
+// String returns the unit symbol.
+func (u AngularUnit) String() string {
+	switch u {
+	case RadiansPerSecond:
+		return "rad/s"
+	case DegreesPerSecond:
+		return "deg/s"
+	default:
+		return "unknown"
+	}
+}
+
+// ToRadians converts an angular velocity in unit u to rad/s.
+func (u AngularUnit) ToRadians(v [3]float64) [3]float64 {
+	if u == DegreesPerSecond {
+		for i := range v {
+			v[i] *= math.Pi / 180
+		}
+	}
+	return v
 }
 
 // IMU represents an individual Inertial Measurement Unit with calibration.
@@ -19,6 +90,8 @@ type IMU struct {
 	OffsetY float64 // Bias in the Y direction
 	ScaleX  float64 // Scale factor in the X direction
 	ScaleY  float64 // Scale factor in the Y direction
+
+	GyroBias [3]float64 // roll, pitch, yaw rate bias in rad/s
 }
 
 // NewIMU creates a new IMU with default calibration parameters.
@@ -36,3 +109,16 @@ type Point struct {
 	X float64
 	Y float64
 }
+
+// Point3D represents a 3D point in space.
+type Point3D struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// Distance returns the Euclidean distance between p and q.
+func (p Point3D) Distance(q Point3D) float64 {
+	dx, dy, dz := p.X-q.X, p.Y-q.Y, p.Z-q.Z
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
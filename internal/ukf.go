@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// UKFParams are the sigma-point spread parameters of the unscented transform.
+type UKFParams struct {
+	Alpha float64 // spread of the sigma points around the mean, in (0, 1]
+	Beta  float64 // prior knowledge of the distribution, 2 is optimal for Gaussians
+	Kappa float64 // secondary scaling, usually 0
+}
+
+// DefaultUKFParams returns the sigma-point parameters used by the fusion system.
+func DefaultUKFParams() UKFParams {
+	return UKFParams{Alpha: 0.5, Beta: 2, Kappa: 0}
+}
+
+// Indices into the UKF state vector [position(3), velocity(3), bias(3), yaw].
+const (
+	ukfPos  = 0
+	ukfVel  = 3
+	ukfBias = 6
+	ukfYaw  = 9
+	ukfDim  = 10
+)
+
+// ukfJitter is added to the covariance diagonal before factorization, since components that
+// are known exactly (such as the initial position) have zero variance.
+const ukfJitter = 1e-12
+
+// UKF estimates the position, velocity, accelerometer bias, and heading of a single IMU.
+// Unlike EKF, it treats accelerations as body-frame: the bias-corrected acceleration is
+// rotated by the heading, which the gyro yaw rate drives. This couples the planar axes
+// nonlinearly, so the state is propagated through the unscented transform instead of a
+// linearization.
+type UKF struct {
+	x          [ukfDim]float64
+	P          *mat.SymDense
+	accelNoise float64 // accelerometer white noise standard deviation
+	biasNoise  float64 // bias random walk standard deviation per sqrt(second)
+	gyroNoise  float64 // gyro white noise standard deviation, rad/s
+	logger     Logger  // receives skipped predictions, see SetLogger
+
+	lambda float64
+	wm, wc []float64 // sigma-point mean and covariance weights
+}
+
+// NewUKF creates a UKF at rest at the origin with zero bias and heading.
+// initialBiasStd is the prior standard deviation of the accelerometer bias.
+func NewUKF(accelNoise, biasNoise, initialBiasStd, gyroNoise float64, params UKFParams) *UKF {
+	f := &UKF{
+		P:          mat.NewSymDense(ukfDim, nil),
+		accelNoise: accelNoise,
+		biasNoise:  biasNoise,
+		gyroNoise:  gyroNoise,
+		logger:     stdoutLogger{},
+	}
+	for i := ukfBias; i < ukfBias+3; i++ {
+		f.P.SetSym(i, i, initialBiasStd*initialBiasStd)
+	}
+
+	n := float64(ukfDim)
+	f.lambda = params.Alpha*params.Alpha*(n+params.Kappa) - n
+	f.wm = make([]float64, 2*ukfDim+1)
+	f.wc = make([]float64, 2*ukfDim+1)
+	f.wm[0] = f.lambda / (n + f.lambda)
+	f.wc[0] = f.wm[0] + 1 - params.Alpha*params.Alpha + params.Beta
+	for i := 1; i < len(f.wm); i++ {
+		f.wm[i] = 1 / (2 * (n + f.lambda))
+		f.wc[i] = f.wm[i]
+	}
+	return f
+}
+
+// SetLogger sends the warnings of the filter to logger instead of stdout. A nil logger
+// restores stdout.
+func (f *UKF) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = stdoutLogger{}
+	}
+	f.logger = logger
+}
+
+// Predict propagates the state by dt seconds through the unscented transform.
+func (f *UKF) Predict(accel, gyro [3]float64, dt float64) {
+	sigma, err := f.sigmaPoints()
+	if err != nil {
+		f.logger.Printf("UKF: %v, skipping prediction\n", err)
+		return
+	}
+	for i := range sigma {
+		sigma[i] = ukfMotion(sigma[i], accel, gyro, dt)
+	}
+
+	// Weighted mean, averaging the heading as offsets from the central point so it does not wrap.
+	var mean [ukfDim]float64
+	for i, s := range sigma {
+		for j := 0; j < ukfDim; j++ {
+			if j == ukfYaw {
+				mean[j] += f.wm[i] * wrapAngle(s[j]-sigma[0][j])
+			} else {
+				mean[j] += f.wm[i] * s[j]
+			}
+		}
+	}
+	mean[ukfYaw] = wrapAngle(mean[ukfYaw] + sigma[0][ukfYaw])
+
+	P := mat.NewSymDense(ukfDim, nil)
+	d := mat.NewVecDense(ukfDim, nil)
+	for i, s := range sigma {
+		for j := 0; j < ukfDim; j++ {
+			d.SetVec(j, s[j]-mean[j])
+		}
+		d.SetVec(ukfYaw, wrapAngle(s[ukfYaw]-mean[ukfYaw]))
+		P.SymRankOne(P, f.wc[i], d)
+	}
+	P.AddSym(P, f.processNoise(dt))
+
+	f.x = mean
+	f.P = P
+}
+
+// sigmaPoints returns the 2n+1 sigma points of the current state distribution.
+func (f *UKF) sigmaPoints() ([][ukfDim]float64, error) {
+	scaled := mat.NewSymDense(ukfDim, nil)
+	scaled.ScaleSym(float64(ukfDim)+f.lambda, f.P)
+	for i := 0; i < ukfDim; i++ {
+		scaled.SetSym(i, i, scaled.At(i, i)+ukfJitter)
+	}
+	var chol mat.Cholesky
+	if ok := chol.Factorize(scaled); !ok {
+		return nil, fmt.Errorf("covariance is not positive definite")
+	}
+	var L mat.TriDense
+	chol.LTo(&L)
+
+	sigma := make([][ukfDim]float64, 2*ukfDim+1)
+	sigma[0] = f.x
+	for k := 0; k < ukfDim; k++ {
+		for j := 0; j < ukfDim; j++ {
+			sigma[1+k][j] = f.x[j] + L.At(j, k)
+			sigma[1+ukfDim+k][j] = f.x[j] - L.At(j, k)
+		}
+	}
+	return sigma, nil
+}
+
+// processNoise returns the covariance added by one prediction step: accelerometer noise enters
+// like an acceleration on each axis, bias follows a random walk, and gyro noise integrates into heading.
+func (f *UKF) processNoise(dt float64) *mat.SymDense {
+	Q := mat.NewSymDense(ukfDim, nil)
+	half := 0.5 * dt * dt
+	qa := f.accelNoise * f.accelNoise
+	for axis := 0; axis < 3; axis++ {
+		p, v := ukfPos+axis, ukfVel+axis
+		Q.SetSym(p, p, qa*half*half)
+		Q.SetSym(p, v, qa*half*dt)
+		Q.SetSym(v, v, qa*dt*dt)
+		Q.SetSym(ukfBias+axis, ukfBias+axis, f.biasNoise*f.biasNoise*dt)
+	}
+	Q.SetSym(ukfYaw, ukfYaw, f.gyroNoise*f.gyroNoise*dt*dt)
+	return Q
+}
+
+// ukfMotion propagates a single state by dt seconds.
+func ukfMotion(x [ukfDim]float64, accel, gyro [3]float64, dt float64) [ukfDim]float64 {
+	bx := accel[0] - x[ukfBias]
+	by := accel[1] - x[ukfBias+1]
+	c, s := math.Cos(x[ukfYaw]), math.Sin(x[ukfYaw])
+	a := [3]float64{c*bx - s*by, s*bx + c*by, accel[2] - x[ukfBias+2]}
+
+	half := 0.5 * dt * dt
+	for i := 0; i < 3; i++ {
+		x[ukfPos+i] += x[ukfVel+i]*dt + a[i]*half
+		x[ukfVel+i] += a[i] * dt
+	}
+	x[ukfYaw] = wrapAngle(x[ukfYaw] + gyro[2]*dt)
+	return x
+}
+
+// wrapAngle maps an angle to (-π, π].
+func wrapAngle(a float64) float64 {
+	a = math.Mod(a+math.Pi, 2*math.Pi)
+	if a <= 0 {
+		a += 2 * math.Pi
+	}
+	return a - math.Pi
+}
+
+// MeanAngle returns the weighted circular mean of angles in radians, in (-π, π]: the direction of
+// the weighted sum of their unit vectors, so angles either side of the ±π wrap average to about
+// π rather than 0. nil weights weigh every angle equally. With no angles, or when the vectors
+// cancel, as for two opposite angles, the mean is undefined and 0 is returned.
+func MeanAngle(angles []float64, weights []float64) float64 {
+	var sumSin, sumCos float64
+	for i, a := range angles {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		sumSin += w * math.Sin(a)
+		sumCos += w * math.Cos(a)
+	}
+	if math.Hypot(sumSin, sumCos) < epsilon {
+		return 0
+	}
+	return wrapAngle(math.Atan2(sumSin, sumCos))
+}
+
+// UpdatePosition corrects the state along axis with a position measurement z of the given variance.
+func (f *UKF) UpdatePosition(axis int, z, variance float64) {
+	f.update(ukfPos+axis, z, variance)
+}
+
+// UpdateVelocity corrects the state along axis with a velocity measurement z of the given variance,
+// e.g. a zero-velocity update while the body is stationary.
+func (f *UKF) UpdateVelocity(axis int, z, variance float64) {
+	f.update(ukfVel+axis, z, variance)
+}
+
+// update applies a direct measurement z of state component idx. The measurement is linear in
+// the state, where the unscented update reduces exactly to the Kalman update, so it is applied directly.
+func (f *UKF) update(idx int, z, variance float64) {
+	if variance < minMeasurementVariance {
+		variance = minMeasurementVariance
+	}
+	PHt := mat.NewVecDense(ukfDim, nil)
+	for i := 0; i < ukfDim; i++ {
+		PHt.SetVec(i, f.P.At(i, idx))
+	}
+	S := f.P.At(idx, idx) + variance
+
+	innovation := z - f.x[idx]
+	for i := 0; i < ukfDim; i++ {
+		f.x[i] += PHt.AtVec(i) / S * innovation
+	}
+	f.x[ukfYaw] = wrapAngle(f.x[ukfYaw])
+
+	// P = P - K * S * K^T = P - PHt * PHt^T / S.
+	f.P.SymRankOne(f.P, -1/S, PHt)
+}
+
+// SetPosition overwrites the position estimate along each axis, leaving the rest of the state untouched.
+func (f *UKF) SetPosition(pos [3]float64) {
+	copy(f.x[ukfPos:ukfPos+3], pos[:])
+}
+
+// Position returns the estimated position along each axis.
+func (f *UKF) Position() [3]float64 {
+	return f.component(ukfPos)
+}
+
+// Velocity returns the estimated velocity along each axis.
+func (f *UKF) Velocity() [3]float64 {
+	return f.component(ukfVel)
+}
+
+// Bias returns the estimated accelerometer bias along each body axis.
+func (f *UKF) Bias() [3]float64 {
+	return f.component(ukfBias)
+}
+
+// Heading returns the estimated yaw in radians.
+func (f *UKF) Heading() float64 {
+	return f.x[ukfYaw]
+}
+
+func (f *UKF) component(start int) [3]float64 {
+	var out [3]float64
+	copy(out[:], f.x[start:start+3])
+	return out
+}
+
+// State returns a copy of the filter state.
+func (f *UKF) State() FilterState {
+	s := FilterState{
+		Kind:       FilterUKF,
+		Position:   f.Position(),
+		Velocity:   f.Velocity(),
+		Bias:       f.Bias(),
+		Heading:    f.Heading(),
+		Covariance: make([]float64, 0, ukfDim*ukfDim),
+	}
+	for i := 0; i < ukfDim; i++ {
+		for j := 0; j < ukfDim; j++ {
+			s.Covariance = append(s.Covariance, f.P.At(i, j))
+		}
+	}
+	return s
+}
+
+// SetState overwrites the filter state. The noise and sigma-point parameters are left unchanged.
+func (f *UKF) SetState(s FilterState) error {
+	if err := checkFilterState(FilterUKF, s); err != nil {
+		return err
+	}
+	copy(f.x[ukfPos:], s.Position[:])
+	copy(f.x[ukfVel:], s.Velocity[:])
+	copy(f.x[ukfBias:], s.Bias[:])
+	f.x[ukfYaw] = s.Heading
+	f.P = mat.NewSymDense(ukfDim, append([]float64(nil), s.Covariance...))
+	return nil
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestUKFMatchesEKFWithoutRotation(t *testing.T) {
+	ekf := NewEKF(0.1, 1e-3, 0.5)
+	ukf := NewUKF(0.1, 1e-3, 0.5, 0.01, DefaultUKFParams())
+	for step := 0; step < 200; step++ {
+		accel := [3]float64{math.Sin(0.05 * float64(step)), 0.3, -1}
+		ekf.Predict(accel, [3]float64{}, 0.01)
+		ukf.Predict(accel, [3]float64{}, 0.01)
+		if step%10 == 0 {
+			for axis := 0; axis < 2; axis++ {
+				ekf.UpdatePosition(axis, 0.01*float64(step), 1e-3)
+				ukf.UpdatePosition(axis, 0.01*float64(step), 1e-3)
+			}
+		}
+	}
+
+	ep, up := ekf.Position(), ukf.Position()
+	eb, ub := ekf.Bias(), ukf.Bias()
+	for axis := 0; axis < 3; axis++ {
+		// Heading noise couples the axes slightly through the bias, so the match is not exact.
+		if !floatsClose(ep[axis], up[axis], 1e-3) || !floatsClose(eb[axis], ub[axis], 1e-3) {
+			t.Errorf("axis %d: expected UKF position %f bias %f to match EKF, got %f and %f", axis, ep[axis], eb[axis], up[axis], ub[axis])
+		}
+	}
+}
+
+func TestUKFTracksTurningTrajectoryBetterThanEKF(t *testing.T) {
+	const (
+		dt         = 0.01
+		steps      = 1000
+		yawRate    = 0.5 // rad/s
+		accelNoise = 0.05
+		gyroNoise  = 0.01
+		posNoise   = 0.05
+	)
+	rng := rand.New(rand.NewSource(1))
+	filters := []Filter{
+		NewEKF(accelNoise, 1e-4, 0.1),
+		NewUKF(accelNoise, 1e-4, 0.1, gyroNoise, DefaultUKFParams()),
+	}
+	sumSq := make([]float64, len(filters))
+
+	// The body accelerates forward at 1 m/s^2 while turning at a constant rate, so the
+	// world-frame acceleration rotates: a curved, nonlinear trajectory.
+	var pos, vel [2]float64
+	yaw := 0.0
+	for step := 1; step <= steps; step++ {
+		a := [2]float64{math.Cos(yaw), math.Sin(yaw)}
+		for i := 0; i < 2; i++ {
+			pos[i] += vel[i]*dt + 0.5*a[i]*dt*dt
+			vel[i] += a[i] * dt
+		}
+		yaw += yawRate * dt
+
+		accel := [3]float64{1 + accelNoise*rng.NormFloat64(), accelNoise * rng.NormFloat64(), 0}
+		gyro := [3]float64{0, 0, yawRate + gyroNoise*rng.NormFloat64()}
+		var z [2]float64
+		for i := range z {
+			z[i] = pos[i] + posNoise*rng.NormFloat64()
+		}
+		for k, f := range filters {
+			f.Predict(accel, gyro, dt)
+			if step%10 == 0 {
+				f.UpdatePosition(0, z[0], posNoise*posNoise)
+				f.UpdatePosition(1, z[1], posNoise*posNoise)
+			}
+			p := f.Position()
+			sumSq[k] += (p[0]-pos[0])*(p[0]-pos[0]) + (p[1]-pos[1])*(p[1]-pos[1])
+		}
+	}
+
+	ekfRMS := math.Sqrt(sumSq[0] / steps)
+	ukfRMS := math.Sqrt(sumSq[1] / steps)
+	if ukfRMS >= ekfRMS {
+		t.Errorf("Expected UKF RMS error %f below EKF RMS error %f", ukfRMS, ekfRMS)
+	}
+	if ukfRMS > posNoise {
+		t.Errorf("Expected UKF RMS error within the measurement noise %f, got %f", posNoise, ukfRMS)
+	}
+}
+
+func TestUKFStateRoundTrip(t *testing.T) {
+	f := NewUKF(0.1, 1e-3, 0.5, 0.01, DefaultUKFParams())
+	for step := 0; step < 20; step++ {
+		f.Predict([3]float64{1, 0, 0}, [3]float64{0, 0, 0.2}, 0.01)
+	}
+	g := NewUKF(0.1, 1e-3, 0.5, 0.01, DefaultUKFParams())
+	if err := g.SetState(f.State()); err != nil {
+		t.Fatalf("SetState failed: %v", err)
+	}
+	if g.Heading() != f.Heading() || g.Position() != f.Position() {
+		t.Errorf("Expected restored heading %f position %v, got %f and %v", f.Heading(), f.Position(), g.Heading(), g.Position())
+	}
+	if err := g.SetState(NewEKF(0.1, 1e-3, 0.5).State()); err == nil {
+		t.Error("Expected error restoring an EKF state into a UKF")
+	}
+}
+
+func TestMeanAngle(t *testing.T) {
+	deg := math.Pi / 180
+	tests := []struct {
+		name    string
+		angles  []float64
+		weights []float64
+		want    float64
+	}{
+		{name: "Plain", angles: []float64{10 * deg, 30 * deg}, want: 20 * deg},
+		{name: "AcrossZero", angles: []float64{-10 * deg, 10 * deg}, want: 0},
+		{name: "AcrossWrap", angles: []float64{179 * deg, -179 * deg}, want: math.Pi},
+		{name: "AcrossWrapUnwrapped", angles: []float64{179 * deg, 181 * deg}, want: math.Pi},
+		{name: "WeightedAcrossWrap", angles: []float64{170 * deg, -170 * deg}, weights: []float64{3, 1}, want: 175.0 * deg},
+		{name: "Opposite", angles: []float64{0, math.Pi}, want: 0},
+		{name: "Empty", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MeanAngle(tt.angles, tt.weights)
+			if math.Abs(wrapAngle(got-tt.want)) > 0.1*deg {
+				t.Errorf("Expected %f, got %f", tt.want, got)
+			}
+			if got <= -math.Pi || got > math.Pi {
+				t.Errorf("Expected a mean in (-pi, pi], got %f", got)
+			}
+		})
+	}
+}
@@ -2,18 +2,21 @@ package internal
 
 import (
 	"math"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
 )
 
 // Uncertainty represents the uncertainty estimation for an IMU measurement.
 type Uncertainty struct {
-	NoiseLevel float64 // Noise level of the IMU
+	NoiseLevel      float64 // Noise level of the IMU
 	IntegrationTime float64 // Time over which the acceleration is integrated
 }
 
 // NewUncertainty creates a new Uncertainty instance.
 func NewUncertainty(noiseLevel, integrationTime float64) *Uncertainty {
 	return &Uncertainty{
-		NoiseLevel:     noiseLevel,
+		NoiseLevel:      noiseLevel,
 		IntegrationTime: integrationTime,
 	}
 }
@@ -22,4 +25,76 @@ func NewUncertainty(noiseLevel, integrationTime float64) *Uncertainty {
 func (u *Uncertainty) Estimate() float64 {
 	// Basic model for uncertainty estimation
 	return u.NoiseLevel * math.Sqrt(u.IntegrationTime)
-}
\ No newline at end of file
+}
+
+// Accumulate extends the integration time by a dead-reckoning step of dt seconds, so that
+// Estimate grows with the total time since the last correction. Non-positive steps are ignored.
+func (u *Uncertainty) Accumulate(dt float64) {
+	if dt > 0 {
+		u.IntegrationTime += dt
+	}
+}
+
+// Reset restarts the integration time after a correction, such as a good fusion.
+func (u *Uncertainty) Reset() {
+	u.IntegrationTime = 0
+}
+
+// UncertaintyModel maps the time in seconds since an IMU's position was last confirmed by a good
+// fusion to the radius of its position uncertainty, for example from a datasheet's noise density,
+// bias instability, and random walk terms.
+type UncertaintyModel interface {
+	Estimate(dt float64) float64
+}
+
+// WhiteNoiseModel is the default UncertaintyModel, the NoiseLevel * sqrt(dt) growth of
+// Uncertainty.Estimate.
+type WhiteNoiseModel struct {
+	NoiseLevel float64
+}
+
+// Estimate returns NoiseLevel * sqrt(dt).
+func (m WhiteNoiseModel) Estimate(dt float64) float64 {
+	return NewUncertainty(m.NoiseLevel, dt).Estimate()
+}
+
+// AdaptiveNoise scales each IMU's uncertainty radius by how hard it has been accelerating, so
+// circles widen during dynamic phases, when integration errors grow fastest, and tighten at rest.
+// The scale is the IMU's RMS body-frame acceleration over Window divided by Reference, clamped to
+// [MinScale, MaxScale].
+type AdaptiveNoise struct {
+	Reference float64       // RMS acceleration at which the scale is 1, in m/s^2
+	MinScale  float64       // scale at rest
+	MaxScale  float64       // scale under the most aggressive motion
+	Window    time.Duration // RMS window, limited by the defaultMotionHistory samples kept
+}
+
+// scale returns the radius scale for an RMS acceleration.
+func (a AdaptiveNoise) scale(rms float64) float64 {
+	return math.Min(a.MaxScale, math.Max(a.MinScale, rms/a.Reference))
+}
+
+// UncertaintyEllipse returns the one-sigma semi-axes of the ellipse described by a 2x2 position
+// covariance, along with the orientation of the major axis in radians from +X, in (-pi/2, pi/2].
+// Negative eigenvalues from numerical error are treated as zero. If the decomposition fails,
+// all results are zero.
+func UncertaintyEllipse(cov mat.Symmetric) (majorAxis, minorAxis, angle float64) {
+	var eig mat.EigenSym
+	if ok := eig.Factorize(cov, true); !ok {
+		return 0, 0, 0
+	}
+	values := eig.Values(nil) // ascending
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+
+	majorAxis = math.Sqrt(math.Max(0, values[1]))
+	minorAxis = math.Sqrt(math.Max(0, values[0]))
+	angle = math.Atan2(vectors.At(1, 1), vectors.At(0, 1))
+	// An axis has no direction, so fold the angle into (-pi/2, pi/2].
+	if angle > math.Pi/2 {
+		angle -= math.Pi
+	} else if angle <= -math.Pi/2 {
+		angle += math.Pi
+	}
+	return majorAxis, minorAxis, angle
+}
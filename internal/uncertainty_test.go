@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestUncertaintyEllipse(t *testing.T) {
+	// Rotate diag(9, 1) by 30 degrees: cov = R * diag(9, 1) * R^T.
+	theta := math.Pi / 6
+	c, s := math.Cos(theta), math.Sin(theta)
+	rotated := mat.NewSymDense(2, []float64{
+		9*c*c + s*s, 8 * c * s,
+		8 * c * s, 9*s*s + c*c,
+	})
+
+	tests := []struct {
+		name        string
+		cov         mat.Symmetric
+		expectMajor float64
+		expectMinor float64
+		expectAngle float64
+	}{
+		{"Diagonal Along X", mat.NewSymDense(2, []float64{4, 0, 0, 1}), 2, 1, 0},
+		{"Diagonal Along Y", mat.NewSymDense(2, []float64{1, 0, 0, 4}), 2, 1, math.Pi / 2},
+		{"Rotated 30 Degrees", rotated, 3, 1, theta},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			major, minor, angle := UncertaintyEllipse(tt.cov)
+			if !floatsClose(major, tt.expectMajor, 1e-9) || !floatsClose(minor, tt.expectMinor, 1e-9) {
+				t.Errorf("Expected semi-axes (%f, %f), got (%f, %f)", tt.expectMajor, tt.expectMinor, major, minor)
+			}
+			if !floatsClose(angle, tt.expectAngle, 1e-9) {
+				t.Errorf("Expected angle %f, got %f", tt.expectAngle, angle)
+			}
+		})
+	}
+}
+
+func TestUncertaintyAccumulate(t *testing.T) {
+	const noise = 0.2
+	u := NewUncertainty(noise, 0)
+	steps := []float64{0.01, 0.02, 0.5, -0.1, 0.03}
+	total := 0.0
+	for _, dt := range steps {
+		u.Accumulate(dt)
+		if dt > 0 {
+			total += dt
+		}
+		if want := noise * math.Sqrt(total); !floatsClose(u.Estimate(), want, 1e-12) {
+			t.Errorf("after %f s: Expected uncertainty %f, got %f", total, want, u.Estimate())
+		}
+	}
+
+	u.Reset()
+	if got := u.Estimate(); got != 0 {
+		t.Errorf("Expected zero uncertainty after Reset, got %f", got)
+	}
+	u.Accumulate(0.25)
+	if want := noise * 0.5; !floatsClose(u.Estimate(), want, 1e-12) {
+		t.Errorf("Expected uncertainty %f after Reset and 0.25 s, got %f", want, u.Estimate())
+	}
+}